@@ -3,10 +3,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	kservice "github.com/kardianos/service"
+	"otterserve/internal/audit"
+	"otterserve/internal/auth"
 	"otterserve/internal/config"
 	"otterserve/internal/logger"
 	"otterserve/internal/service"
@@ -17,6 +20,9 @@ const (
 	serviceDisplay = "Otter Serve Service"
 	serviceDesc    = "Lightweight HTTP file server with configurable routing"
 	defaultConfig  = "config.yaml"
+
+	// serviceModeEnv marks processes that run as the system service
+	serviceModeEnv = "OTTERSERVE_SERVICE"
 )
 
 var (
@@ -32,6 +38,8 @@ func main() {
 		help       = flag.Bool("help", false, "Show help information")
 		showVer    = flag.Bool("version", false, "Show version information")
 		configPath = flag.String("config", defaultConfig, "Path to configuration file")
+		totpEnroll = flag.String("totp-enroll", "", "Enroll a local user for TOTP two-factor authentication")
+		auditCheck = flag.String("audit-verify", "", "Verify the hash chain of an audit log file")
 	)
 
 	flag.Parse()
@@ -63,6 +71,28 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Handle TOTP enrollment
+	if *totpEnroll != "" {
+		if err := enrollTOTP(absConfigPath, *totpEnroll, os.Stdout); err != nil {
+			log.Error("TOTP enrollment failed", logger.Fields{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle audit log verification
+	if *auditCheck != "" {
+		if err := verifyAuditLog(*auditCheck, os.Stdout); err != nil {
+			log.Error("Audit log verification failed", logger.Fields{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Handle service installation
 	if *install {
 		if err := installService(absConfigPath, log); err != nil {
@@ -85,9 +115,12 @@ func main() {
 		return
 	}
 
-	// Default: decide based on environment: service mode vs interactive console
-	if !kservice.Interactive() {
-		// Running under the Windows Service Control Manager
+	// Default: decide based on environment: service mode vs interactive console.
+	// A process started by an upgrade has the old one as parent and would look
+	// interactive, so service mode is passed down in the environment.
+	if !kservice.Interactive() || os.Getenv(serviceModeEnv) == "1" {
+		// Running under the Windows Service Control Manager or systemd
+		os.Setenv(serviceModeEnv, "1")
 		svcManager, err := service.NewServiceManager(
 			serviceName,
 			serviceDisplay,
@@ -215,6 +248,60 @@ func uninstallService(configPath string, log logger.Logger) error {
 	return nil
 }
 
+// enrollTOTP creates a TOTP secret for a local user and prints the
+// provisioning URI and recovery codes
+func enrollTOTP(configPath, username string, out io.Writer) error {
+	configManager := config.NewConfigManager()
+	cfg, err := configManager.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if !cfg.Auth.TOTP.Enabled || cfg.Auth.TOTP.SecretsFile == "" {
+		return fmt.Errorf("totp is not enabled in %s", configPath)
+	}
+	if username != cfg.Auth.Username {
+		return fmt.Errorf("%s is not a local user", username)
+	}
+
+	issuer := cfg.Auth.TOTP.Issuer
+	if issuer == "" {
+		issuer = serviceDisplay
+	}
+
+	store, err := auth.NewTOTPStore(cfg.Auth.TOTP.SecretsFile)
+	if err != nil {
+		return err
+	}
+	uri, codes, err := store.Enroll(username, issuer)
+	if err != nil {
+		return fmt.Errorf("failed to enroll %s: %w", username, err)
+	}
+
+	fmt.Fprintf(out, "Enrolled %s for two-factor authentication.\n\n", username)
+	fmt.Fprintln(out, "Add this URI to an authenticator app (or render it as a QR code):")
+	fmt.Fprintf(out, "  %s\n\n", uri)
+	fmt.Fprintln(out, "Recovery codes (each works once; they are not shown again):")
+	for _, code := range codes {
+		fmt.Fprintf(out, "  %s\n", code)
+	}
+	return nil
+}
+
+// verifyAuditLog checks the hash chain of an audit log file and the files
+// it was rotated to
+func verifyAuditLog(path string, out io.Writer) error {
+	count, anchor, err := audit.VerifyLog(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w (%d entries verified)", path, err, count)
+	}
+	fmt.Fprintf(out, "Audit log %s is intact: %d entries verified.\n", path, count)
+	if anchor != "" {
+		fmt.Fprintf(out, "The first entry follows entry %s, which is no longer present; it was in a rotated file that has been removed.\n", anchor)
+	}
+	return nil
+}
+
 // runConsole runs the application in console mode
 func runConsole(configPath string, log logger.Logger) error {
 	log.Info("Starting application in console mode", logger.Fields{
@@ -233,22 +320,28 @@ func runConsole(configPath string, log logger.Logger) error {
 	}
 
 	log.Info("Configuration loaded and validated successfully", logger.Fields{
-		"host":   cfg.Server.Host,
-		"port":   cfg.Server.Port,
-		"routes": len(cfg.Routes),
-		"auth":   cfg.Auth.Enabled,
+		"host":      cfg.Server.Host,
+		"port":      cfg.Server.Port,
+		"listeners": len(cfg.Server.ListenerConfigs()),
+		"routes":    len(cfg.Routes),
+		"auth":      cfg.Auth.Enabled,
 	})
 
 	runner := service.NewConsoleRunner(configPath, log)
 
 	fmt.Printf("Starting %s in console mode...\n", serviceDisplay)
 	fmt.Printf("Configuration: %s\n", configPath)
-	fmt.Printf("Server will listen on: %s:%d\n", cfg.Server.Host, cfg.Server.Port)
+	for _, lc := range cfg.Server.ListenerConfigs() {
+		fmt.Printf("Server will listen on: %s (%s)\n", lc.Address, lc.NetworkName())
+	}
 	if cfg.Auth.Enabled {
 		fmt.Println("Authentication: Enabled")
 	} else {
 		fmt.Println("Authentication: Disabled")
 	}
+	if cfg.Admin.Enabled {
+		fmt.Printf("Admin API: %s (%s)\n", cfg.Admin.Address, cfg.Admin.Listener().NetworkName())
+	}
 	fmt.Printf("Routes configured: %d\n", len(cfg.Routes))
 	for _, route := range cfg.Routes {
 		fmt.Printf("  %s -> %s\n", route.Path, route.Directory)
@@ -267,6 +360,8 @@ func showHelp() {
 	fmt.Println("  -install           Install the service")
 	fmt.Println("  -uninstall         Uninstall the service")
 	fmt.Println("  -config <path>     Path to configuration file (default: config.yaml)")
+	fmt.Println("  -totp-enroll <user> Enroll a local user for TOTP two-factor authentication")
+	fmt.Println("  -audit-verify <file> Verify the hash chain of an audit log file")
 	fmt.Println("  -version           Show version information")
 	fmt.Println("  -help              Show this help message")
 	fmt.Println()
@@ -277,4 +372,6 @@ func showHelp() {
 	fmt.Printf("  %s -config /path/to/config   # Run with custom config file\n", os.Args[0])
 	fmt.Printf("  %s -install                  # Install as system service\n", os.Args[0])
 	fmt.Printf("  %s -uninstall                # Uninstall system service\n", os.Args[0])
+	fmt.Printf("  %s -totp-enroll admin        # Print a TOTP provisioning URI for admin\n", os.Args[0])
+	fmt.Printf("  %s -audit-verify audit.log   # Check an audit log for tampering\n", os.Args[0])
 }
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 
+	"otterserve/internal/audit"
+	"otterserve/internal/config"
 	"otterserve/internal/logger"
 )
 
@@ -108,6 +110,71 @@ func TestRunConsole_InvalidConfig(t *testing.T) {
 	t.Log("runConsole function exists and can handle nonexistent config files")
 }
 
+func TestEnrollTOTP(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.yaml")
+	secretsFile := filepath.Join(tempDir, "totp.json")
+
+	configContent := `server:
+  host: "localhost"
+  port: 0
+auth:
+  enabled: true
+  username: "admin"
+  password: "secret"
+  totp:
+    enabled: true
+    issuer: "Example Files"
+    secrets_file: "` + secretsFile + `"
+routes:
+  - path: "/static"
+    directory: "` + tempDir + `"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := enrollTOTP(configFile, "admin", &out); err != nil {
+		t.Fatalf("enrollTOTP failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "otpauth://totp/Example%20Files:admin?") {
+		t.Errorf("Expected provisioning URI in output, got: %s", out.String())
+	}
+	if _, err := os.Stat(secretsFile); err != nil {
+		t.Errorf("Expected secrets file to be written: %v", err)
+	}
+
+	if err := enrollTOTP(configFile, "mallory", &out); err == nil {
+		t.Error("Expected error for unknown user")
+	}
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+	auditor, err := audit.NewLogger(config.AuditConfig{Enabled: true, File: auditFile, HashChain: true})
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	auditor.Record(audit.Event{Type: audit.EventLogin, Outcome: audit.OutcomeSuccess, User: "admin"})
+	auditor.Record(audit.Event{Type: audit.EventLogout, Outcome: audit.OutcomeSuccess, User: "admin"})
+	auditor.Close()
+
+	var out bytes.Buffer
+	if err := verifyAuditLog(auditFile, &out); err != nil {
+		t.Fatalf("verifyAuditLog failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "2 entries verified") {
+		t.Errorf("Expected entry count in output, got: %s", out.String())
+	}
+
+	data, _ := os.ReadFile(auditFile)
+	os.WriteFile(auditFile, bytes.Replace(data, []byte("logout"), []byte("login"), 1), 0600)
+	if err := verifyAuditLog(auditFile, &out); err == nil {
+		t.Error("Expected error for tampered audit log")
+	}
+}
+
 func TestInstallService_InvalidPath(t *testing.T) {
 	log := logger.NewLogger(logger.InfoLevel, nil)
 
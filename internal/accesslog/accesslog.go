@@ -0,0 +1,102 @@
+package accesslog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+	"otterserve/internal/rotate"
+)
+
+// Entry describes a completed request
+type Entry struct {
+	Time       time.Time // when the request was received
+	ClientIP   string
+	RemoteAddr string
+	User       string
+	Method     string
+	URI        string // request target as sent by the client
+	Path       string
+	Protocol   string
+	Host       string
+	Status     int
+	Bytes      int64
+	Duration   time.Duration
+	Referer    string
+	UserAgent  string
+	RequestID  string
+	TraceID    string
+	Route      string
+}
+
+// Logger writes one line per request in the configured format
+type Logger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	format formatter
+	buf    []byte
+
+	// redactor strips sensitive query parameters from logged URLs
+	redactor *logger.Redactor
+}
+
+// New opens the access log from configuration, writing to standard output
+// when no file is set. Sensitive query parameters are removed from the
+// request target and referer with redactor, unless it is nil.
+func New(cfg config.AccessLogConfig, redactor *logger.Redactor) (*Logger, error) {
+	if cfg.File == "" {
+		l, err := NewWriterLogger(os.Stdout, cfg.FormatName(), cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+		l.redactor = redactor
+		return l, nil
+	}
+
+	format, err := compile(cfg.FormatName(), cfg.Template)
+	if err != nil {
+		return nil, err
+	}
+	file, err := rotate.Open(cfg.File, 0644, cfg.Rotation.Options())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	return &Logger{w: file, closer: file, format: format, redactor: redactor}, nil
+}
+
+// NewWriterLogger creates a logger writing to w in the given format, which
+// is one of the config.AccessLog* formats
+func NewWriterLogger(w io.Writer, format, template string) (*Logger, error) {
+	f, err := compile(format, template)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{w: w, format: f}, nil
+}
+
+// Log writes an entry. A nil logger discards entries.
+func (l *Logger) Log(e Entry) {
+	if l == nil {
+		return
+	}
+
+	e.URI, e.Referer = l.redactor.URL(e.URI), l.redactor.URL(e.Referer)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.format(l.buf[:0], &e), '\n')
+	l.w.Write(l.buf)
+}
+
+// Close closes the underlying file
+func (l *Logger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
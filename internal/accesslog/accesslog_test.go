@@ -0,0 +1,95 @@
+package accesslog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+)
+
+func TestLoggerWritesLines(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewWriterLogger(&buf, config.AccessLogTemplate, "{method} {path} {status}")
+	if err != nil {
+		t.Fatalf("NewWriterLogger failed: %v", err)
+	}
+	l.Log(Entry{Method: "GET", Path: "/a", Status: 200})
+	l.Log(Entry{Method: "PUT", Path: "/b", Status: 403})
+
+	if got, want := buf.String(), "GET /a 200\nPUT /b 403\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestNewOpensFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	l, err := New(config.AccessLogConfig{
+		Enabled:  true,
+		File:     path,
+		Format:   config.AccessLogCommon,
+		Rotation: config.RotationConfig{MaxSize: 1, MaxBackups: 1},
+	}, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	l.Log(testEntry())
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "127.0.0.1 - frank [") {
+		t.Errorf("Expected a Common Log Format line, got %q", data)
+	}
+}
+
+func TestNewRejectsBadTemplate(t *testing.T) {
+	_, err := New(config.AccessLogConfig{
+		Enabled:  true,
+		File:     filepath.Join(t.TempDir(), "access.log"),
+		Format:   config.AccessLogTemplate,
+		Template: "{nope}",
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "{nope}") {
+		t.Errorf("Expected an error naming the placeholder, got %v", err)
+	}
+}
+
+func TestNewStripsSensitiveQueryParameters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	l, err := New(config.AccessLogConfig{
+		Enabled:  true,
+		File:     path,
+		Format:   config.AccessLogTemplate,
+		Template: "{uri} {referer}",
+	}, logger.DefaultRedactor())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	e := testEntry()
+	e.URI = "/files/report.pdf?X-Amz-Signature=abc123&download=1&token=secret"
+	e.Referer = "https://app.example.com/callback?code=4/0Ad&state=xyz"
+	l.Log(e)
+	l.Close()
+
+	data, _ := os.ReadFile(path)
+	want := "/files/report.pdf?download=1 https://app.example.com/callback?state=xyz\n"
+	if string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var l *Logger
+	l.Log(testEntry())
+	if err := l.Close(); err != nil {
+		t.Errorf("Expected closing a nil logger to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"otterserve/internal/config"
+)
+
+// formatter appends an entry, without the line ending, to a buffer
+type formatter func(buf []byte, e *Entry) []byte
+
+// clfTime is the timestamp layout of the Common Log Format
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// compile returns the formatter for a format and, for the template format,
+// its template
+func compile(format, template string) (formatter, error) {
+	switch format {
+	case config.AccessLogCommon:
+		return formatCommon, nil
+	case config.AccessLogCombined:
+		return formatCombined, nil
+	case config.AccessLogJSON:
+		return formatJSON, nil
+	case config.AccessLogTemplate:
+		return parseTemplate(template)
+	}
+	return nil, fmt.Errorf("unknown access log format %s", format)
+}
+
+// formatCommon writes the Common Log Format:
+// host ident user [time] "request" status bytes
+func formatCommon(buf []byte, e *Entry) []byte {
+	buf = appendField(buf, e.ClientIP)
+	buf = append(buf, " - "...)
+	buf = appendField(buf, e.User)
+	buf = append(buf, " ["...)
+	buf = e.Time.AppendFormat(buf, clfTime)
+	buf = append(buf, "] \""...)
+	buf = appendRequestLine(buf, e)
+	buf = append(buf, "\" "...)
+	buf = strconv.AppendInt(buf, int64(e.Status), 10)
+	buf = append(buf, ' ')
+	return appendCLFBytes(buf, e)
+}
+
+// formatCombined writes the Combined Log Format, the Common Log Format
+// followed by the quoted referer and user agent
+func formatCombined(buf []byte, e *Entry) []byte {
+	buf = formatCommon(buf, e)
+	buf = append(buf, " \""...)
+	buf = appendField(buf, e.Referer)
+	buf = append(buf, "\" \""...)
+	buf = appendField(buf, e.UserAgent)
+	return append(buf, '"')
+}
+
+// jsonEntry is an entry in the JSON format
+type jsonEntry struct {
+	Time       time.Time `json:"time"`
+	ClientIP   string    `json:"client_ip"`
+	RemoteAddr string    `json:"remote_addr"`
+	User       string    `json:"user,omitempty"`
+	Method     string    `json:"method"`
+	URI        string    `json:"uri"`
+	Protocol   string    `json:"protocol"`
+	Host       string    `json:"host,omitempty"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMS float64   `json:"duration_ms"`
+	Referer    string    `json:"referer,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	TraceID    string    `json:"trace_id,omitempty"`
+	Route      string    `json:"route,omitempty"`
+}
+
+// formatJSON writes an entry as a JSON object
+func formatJSON(buf []byte, e *Entry) []byte {
+	data, err := json.Marshal(jsonEntry{
+		Time:       e.Time.UTC(),
+		ClientIP:   e.ClientIP,
+		RemoteAddr: e.RemoteAddr,
+		User:       e.User,
+		Method:     e.Method,
+		URI:        e.URI,
+		Protocol:   e.Protocol,
+		Host:       e.Host,
+		Status:     e.Status,
+		Bytes:      e.Bytes,
+		DurationMS: float64(e.Duration.Microseconds()) / 1000,
+		Referer:    e.Referer,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		TraceID:    e.TraceID,
+		Route:      e.Route,
+	})
+	if err != nil {
+		return buf
+	}
+	return append(buf, data...)
+}
+
+// placeholders are the values available to templates
+var placeholders = map[string]formatter{
+	"client_ip":   func(buf []byte, e *Entry) []byte { return appendField(buf, e.ClientIP) },
+	"remote_addr": func(buf []byte, e *Entry) []byte { return appendField(buf, e.RemoteAddr) },
+	"user":        func(buf []byte, e *Entry) []byte { return appendField(buf, e.User) },
+	"time":        func(buf []byte, e *Entry) []byte { return e.Time.AppendFormat(buf, clfTime) },
+	"time_iso": func(buf []byte, e *Entry) []byte {
+		return e.Time.UTC().AppendFormat(buf, "2006-01-02T15:04:05.000Z07:00")
+	},
+	"time_unix": func(buf []byte, e *Entry) []byte { return strconv.AppendInt(buf, e.Time.Unix(), 10) },
+	"method":    func(buf []byte, e *Entry) []byte { return appendField(buf, e.Method) },
+	"uri":       func(buf []byte, e *Entry) []byte { return appendField(buf, e.URI) },
+	"path":      func(buf []byte, e *Entry) []byte { return appendField(buf, e.Path) },
+	"protocol":  func(buf []byte, e *Entry) []byte { return appendField(buf, e.Protocol) },
+	"request":   appendRequestLine,
+	"host":      func(buf []byte, e *Entry) []byte { return appendField(buf, e.Host) },
+	"status":    func(buf []byte, e *Entry) []byte { return strconv.AppendInt(buf, int64(e.Status), 10) },
+	"bytes":     func(buf []byte, e *Entry) []byte { return strconv.AppendInt(buf, e.Bytes, 10) },
+	"bytes_clf": appendCLFBytes,
+	"duration_ms": func(buf []byte, e *Entry) []byte {
+		return strconv.AppendFloat(buf, float64(e.Duration.Microseconds())/1000, 'f', 3, 64)
+	},
+	"duration_s": func(buf []byte, e *Entry) []byte {
+		return strconv.AppendFloat(buf, e.Duration.Seconds(), 'f', 3, 64)
+	},
+	"referer":    func(buf []byte, e *Entry) []byte { return appendField(buf, e.Referer) },
+	"user_agent": func(buf []byte, e *Entry) []byte { return appendField(buf, e.UserAgent) },
+	"request_id": func(buf []byte, e *Entry) []byte { return appendField(buf, e.RequestID) },
+	"trace_id":   func(buf []byte, e *Entry) []byte { return appendField(buf, e.TraceID) },
+	"route":      func(buf []byte, e *Entry) []byte { return appendField(buf, e.Route) },
+}
+
+// placeholderNames returns the names usable in templates, sorted
+func placeholderNames() []string {
+	names := make([]string, 0, len(placeholders))
+	for name := range placeholders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseTemplate compiles a template in which {name} stands for a value of
+// the entry and {{ for a literal brace
+func parseTemplate(template string) (formatter, error) {
+	var parts []formatter
+	literal := func(text string) {
+		if text != "" {
+			parts = append(parts, func(buf []byte, e *Entry) []byte { return append(buf, text...) })
+		}
+	}
+
+	rest := template
+	var text strings.Builder
+	for {
+		i := strings.IndexByte(rest, '{')
+		if i < 0 {
+			text.WriteString(rest)
+			break
+		}
+		text.WriteString(rest[:i])
+		rest = rest[i+1:]
+		if strings.HasPrefix(rest, "{") {
+			text.WriteByte('{')
+			rest = rest[1:]
+			continue
+		}
+		end := strings.IndexByte(rest, '}')
+		if end < 0 {
+			return nil, fmt.Errorf("access log template: unterminated placeholder")
+		}
+		name := rest[:end]
+		value, ok := placeholders[name]
+		if !ok {
+			return nil, fmt.Errorf("access log template: unknown placeholder {%s}, must be one of: %s",
+				name, strings.Join(placeholderNames(), ", "))
+		}
+		literal(text.String())
+		text.Reset()
+		parts = append(parts, value)
+		rest = rest[end+1:]
+	}
+	literal(text.String())
+
+	return func(buf []byte, e *Entry) []byte {
+		for _, part := range parts {
+			buf = part(buf, e)
+		}
+		return buf
+	}, nil
+}
+
+// appendRequestLine writes the request line: method, target and protocol
+func appendRequestLine(buf []byte, e *Entry) []byte {
+	buf = appendField(buf, e.Method)
+	buf = append(buf, ' ')
+	buf = appendField(buf, e.URI)
+	buf = append(buf, ' ')
+	return appendField(buf, e.Protocol)
+}
+
+// appendCLFBytes writes the response size, - when no body was sent
+func appendCLFBytes(buf []byte, e *Entry) []byte {
+	if e.Bytes == 0 {
+		return append(buf, '-')
+	}
+	return strconv.AppendInt(buf, e.Bytes, 10)
+}
+
+// appendField writes a value, - when it is empty. As Apache does, quotes,
+// backslashes and non-printable bytes are escaped so a client cannot forge
+// lines or break the quoting.
+func appendField(buf []byte, s string) []byte {
+	if s == "" {
+		return append(buf, '-')
+	}
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c < 0x20 || c == 0x7f:
+			buf = append(buf, '\\', 'x', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
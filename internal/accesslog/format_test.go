@@ -0,0 +1,121 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/config"
+)
+
+func testEntry() Entry {
+	return Entry{
+		Time:       time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		ClientIP:   "127.0.0.1",
+		RemoteAddr: "127.0.0.1:51234",
+		User:       "frank",
+		Method:     "GET",
+		URI:        "/apache_pb.gif?size=large",
+		Path:       "/apache_pb.gif",
+		Protocol:   "HTTP/1.0",
+		Host:       "example.com",
+		Status:     200,
+		Bytes:      2326,
+		Duration:   1500 * time.Microsecond,
+		Referer:    "http://www.example.com/start.html",
+		UserAgent:  "Mozilla/4.08 [en] (Win98; I ;Nav)",
+		RequestID:  "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+		TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		Route:      "/",
+	}
+}
+
+func format(t *testing.T, name, template string, e Entry) string {
+	t.Helper()
+	f, err := compile(name, template)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	return string(f(nil, &e))
+}
+
+func TestFormatCommon(t *testing.T) {
+	want := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?size=large HTTP/1.0" 200 2326`
+	if got := format(t, config.AccessLogCommon, "", testEntry()); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestFormatCombined(t *testing.T) {
+	want := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?size=large HTTP/1.0" 200 2326 ` +
+		`"http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`
+	if got := format(t, config.AccessLogCombined, "", testEntry()); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestFormatCombinedMissingValues(t *testing.T) {
+	e := testEntry()
+	e.User, e.Referer, e.UserAgent, e.Bytes = "", "", "", 0
+	want := `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?size=large HTTP/1.0" 200 - "-" "-"`
+	if got := format(t, config.AccessLogCombined, "", e); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestFormatEscapesValues(t *testing.T) {
+	e := testEntry()
+	e.UserAgent = "evil\" 200 0\n127.0.0.1 \\"
+	got := format(t, config.AccessLogCombined, "", e)
+	if !strings.HasSuffix(got, `"evil\" 200 0\x0a127.0.0.1 \\"`) {
+		t.Errorf("Expected quotes, backslashes and newlines to be escaped, got %s", got)
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	got := format(t, config.AccessLogJSON, "", testEntry())
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %s: %v", got, err)
+	}
+	want := map[string]interface{}{
+		"time":        "2000-10-10T20:55:36Z",
+		"client_ip":   "127.0.0.1",
+		"user":        "frank",
+		"uri":         "/apache_pb.gif?size=large",
+		"status":      float64(200),
+		"bytes":       float64(2326),
+		"duration_ms": 1.5,
+		"request_id":  "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+		"trace_id":    "4bf92f3577b34da6a3ce929d0e0e4736",
+	}
+	for key, value := range want {
+		if decoded[key] != value {
+			t.Errorf("Expected %s %v, got %v", key, value, decoded[key])
+		}
+	}
+}
+
+func TestFormatTemplate(t *testing.T) {
+	template := `{time_iso} {{{client_ip}} {request} {status} {bytes_clf} {duration_ms}ms id={request_id} ua="{user_agent}"`
+	want := `2000-10-10T20:55:36.000Z {127.0.0.1} GET /apache_pb.gif?size=large HTTP/1.0 200 2326 1.500ms ` +
+		`id=01ARZ3NDEKTSV4RRFFQ69G5FAV ua="Mozilla/4.08 [en] (Win98; I ;Nav)"`
+	if got := format(t, config.AccessLogTemplate, template, testEntry()); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for _, template := range []string{"{method} {colour}", "{method", "{}"} {
+		if _, err := parseTemplate(template); err == nil {
+			t.Errorf("Expected template %q to be rejected", template)
+		}
+	}
+}
+
+func TestCompileUnknownFormat(t *testing.T) {
+	if _, err := compile("w3c", ""); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
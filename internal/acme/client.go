@@ -0,0 +1,427 @@
+package acme
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Object states defined by RFC 8555
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusReady      = "ready"
+	StatusValid      = "valid"
+	StatusInvalid    = "invalid"
+)
+
+// Challenge types supported by the client
+const (
+	ChallengeHTTP01    = "http-01"
+	ChallengeTLSALPN01 = "tls-alpn-01"
+)
+
+const (
+	maxResponseSize = 1 << 20
+	badNonceError   = "urn:ietf:params:acme:error:badNonce"
+)
+
+// pollInterval is the delay between status checks when the server does not
+// send Retry-After
+var pollInterval = 2 * time.Second
+
+// Problem is an ACME error document (RFC 7807)
+type Problem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return fmt.Sprintf("acme: %s: %s", p.Type, p.Detail)
+	}
+	return "acme: " + p.Type
+}
+
+// Identifier names a domain a certificate is requested for
+type Identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Order is a request for a certificate
+type Order struct {
+	URL            string       `json:"-"`
+	Status         string       `json:"status"`
+	Identifiers    []Identifier `json:"identifiers"`
+	Authorizations []string     `json:"authorizations"`
+	Finalize       string       `json:"finalize"`
+	Certificate    string       `json:"certificate,omitempty"`
+	Error          *Problem     `json:"error,omitempty"`
+}
+
+// Authorization is the proof of control the server requires for one domain
+type Authorization struct {
+	Status     string      `json:"status"`
+	Identifier Identifier  `json:"identifier"`
+	Challenges []Challenge `json:"challenges"`
+}
+
+// Challenge is one way of proving control of a domain
+type Challenge struct {
+	Type   string   `json:"type"`
+	URL    string   `json:"url"`
+	Token  string   `json:"token"`
+	Status string   `json:"status"`
+	Error  *Problem `json:"error,omitempty"`
+}
+
+type directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+// Client talks to an ACME certificate authority (RFC 8555) on behalf of
+// one account
+type Client struct {
+	directoryURL string
+	httpClient   *http.Client
+	key          *ecdsa.PrivateKey
+
+	mu     sync.Mutex
+	dir    *directory
+	kid    string
+	nonces []string
+}
+
+// NewClient creates a client for the directory URL using the account key
+func NewClient(directoryURL string, key *ecdsa.PrivateKey, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{
+		directoryURL: directoryURL,
+		httpClient:   httpClient,
+		key:          key,
+	}
+}
+
+// KeyAuthorization returns the response expected for a challenge token
+func (c *Client) KeyAuthorization(token string) string {
+	return token + "." + thumbprint(&c.key.PublicKey)
+}
+
+// Register creates the account, or looks up the existing one for the key,
+// agreeing to the CA's terms of service
+func (c *Client) Register(ctx context.Context, email string) error {
+	dir, err := c.discover(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := struct {
+		TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+		Contact              []string `json:"contact,omitempty"`
+	}{TermsOfServiceAgreed: true}
+	if email != "" {
+		req.Contact = []string{"mailto:" + email}
+	}
+
+	header, _, err := c.post(ctx, dir.NewAccount, req, nil)
+	if err != nil {
+		return fmt.Errorf("failed to register acme account: %w", err)
+	}
+	kid := header.Get("Location")
+	if kid == "" {
+		return fmt.Errorf("acme server returned no account URL")
+	}
+
+	c.mu.Lock()
+	c.kid = kid
+	c.mu.Unlock()
+	return nil
+}
+
+// NewOrder requests a certificate for the domains
+func (c *Client) NewOrder(ctx context.Context, domains []string) (*Order, error) {
+	dir, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var req struct {
+		Identifiers []Identifier `json:"identifiers"`
+	}
+	for _, domain := range domains {
+		req.Identifiers = append(req.Identifiers, Identifier{Type: "dns", Value: domain})
+	}
+
+	order := &Order{}
+	header, _, err := c.post(ctx, dir.NewOrder, req, order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create acme order: %w", err)
+	}
+	order.URL = header.Get("Location")
+	return order, nil
+}
+
+// Authorization fetches an authorization
+func (c *Client) Authorization(ctx context.Context, url string) (*Authorization, error) {
+	authz := &Authorization{}
+	if _, _, err := c.post(ctx, url, nil, authz); err != nil {
+		return nil, err
+	}
+	return authz, nil
+}
+
+// Accept tells the server that the challenge response is in place
+func (c *Client) Accept(ctx context.Context, ch Challenge) error {
+	_, _, err := c.post(ctx, ch.URL, struct{}{}, nil)
+	return err
+}
+
+// WaitAuthorization polls an authorization until it is valid or invalid
+func (c *Client) WaitAuthorization(ctx context.Context, url string) (*Authorization, error) {
+	for {
+		authz := &Authorization{}
+		header, _, err := c.post(ctx, url, nil, authz)
+		if err != nil {
+			return nil, err
+		}
+		if authz.Status != StatusPending && authz.Status != StatusProcessing {
+			return authz, nil
+		}
+		if err := sleep(ctx, retryAfter(header)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Finalize submits the certificate signing request (DER) and waits until the
+// certificate has been issued
+func (c *Client) Finalize(ctx context.Context, order *Order, csr []byte) (*Order, error) {
+	req := struct {
+		CSR string `json:"csr"`
+	}{CSR: b64.EncodeToString(csr)}
+
+	updated := &Order{}
+	header, _, err := c.post(ctx, order.Finalize, req, updated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize acme order: %w", err)
+	}
+
+	for {
+		switch updated.Status {
+		case StatusValid:
+			updated.URL = order.URL
+			return updated, nil
+		case StatusInvalid:
+			if updated.Error != nil {
+				return nil, updated.Error
+			}
+			return nil, fmt.Errorf("acme order %s is invalid", order.URL)
+		}
+
+		if err := sleep(ctx, retryAfter(header)); err != nil {
+			return nil, err
+		}
+		updated = &Order{}
+		if header, _, err = c.post(ctx, order.URL, nil, updated); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Certificate downloads the PEM certificate chain of a valid order
+func (c *Client) Certificate(ctx context.Context, url string) ([]byte, error) {
+	_, body, err := c.post(ctx, url, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download certificate: %w", err)
+	}
+	return body, nil
+}
+
+// discover fetches the directory once
+func (c *Client) discover(ctx context.Context) (*directory, error) {
+	c.mu.Lock()
+	dir := c.dir
+	c.mu.Unlock()
+	if dir != nil {
+		return dir, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.directoryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch acme directory: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch acme directory: %s", resp.Status)
+	}
+
+	dir = &directory{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(dir); err != nil {
+		return nil, fmt.Errorf("failed to parse acme directory: %w", err)
+	}
+	if dir.NewNonce == "" || dir.NewAccount == "" || dir.NewOrder == "" {
+		return nil, fmt.Errorf("acme directory %s is incomplete", c.directoryURL)
+	}
+
+	c.mu.Lock()
+	c.dir = dir
+	c.mu.Unlock()
+	return dir, nil
+}
+
+// nonce returns a fresh anti-replay nonce, fetching one if none is left
+// over from earlier responses
+func (c *Client) nonce(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	if n := len(c.nonces); n > 0 {
+		nonce := c.nonces[n-1]
+		c.nonces = c.nonces[:n-1]
+		c.mu.Unlock()
+		return nonce, nil
+	}
+	c.mu.Unlock()
+
+	dir, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, dir.NewNonce, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch acme nonce: %w", err)
+	}
+	resp.Body.Close()
+
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", fmt.Errorf("acme server returned no nonce")
+	}
+	return nonce, nil
+}
+
+func (c *Client) saveNonce(header http.Header) {
+	if nonce := header.Get("Replay-Nonce"); nonce != "" {
+		c.mu.Lock()
+		c.nonces = append(c.nonces, nonce)
+		c.mu.Unlock()
+	}
+}
+
+// post sends a signed request and decodes a JSON response into out. A nil
+// payload sends a POST-as-GET. A rejected nonce is retried once, as RFC 8555
+// allows the server to refuse any nonce.
+func (c *Client) post(ctx context.Context, url string, payload, out interface{}) (http.Header, []byte, error) {
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		header, body, err := c.postOnce(ctx, url, data)
+		if problem, ok := err.(*Problem); ok && problem.Type == badNonceError && attempt == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if out != nil {
+			if err := json.Unmarshal(body, out); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse acme response from %s: %w", url, err)
+			}
+		}
+		return header, body, nil
+	}
+}
+
+func (c *Client) postOnce(ctx context.Context, url string, payload []byte) (http.Header, []byte, error) {
+	nonce, err := c.nonce(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Account creation is signed with the bare key, everything else with the
+	// account URL
+	header := jwsHeader{Nonce: nonce, URL: url}
+	c.mu.Lock()
+	if c.dir == nil || url != c.dir.NewAccount {
+		header.KID = c.kid
+	}
+	c.mu.Unlock()
+	if header.KID == "" {
+		key := newJWK(&c.key.PublicKey)
+		header.JWK = &key
+	}
+
+	body, err := signJWS(c.key, header, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/jose+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	c.saveNonce(resp.Header)
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode >= 400 {
+		problem := &Problem{}
+		if err := json.Unmarshal(respBody, problem); err != nil || problem.Type == "" {
+			return nil, nil, fmt.Errorf("acme request to %s failed: %s", url, resp.Status)
+		}
+		return nil, nil, problem
+	}
+	return resp.Header, respBody, nil
+}
+
+// retryAfter returns the polling delay requested by the server
+func retryAfter(header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header.Get("Retry-After"))); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return pollInterval
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,152 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+var b64 = base64.RawURLEncoding
+
+// jwk is the JSON Web Key of a P-256 account key. The field order matches
+// the lexicographic member order RFC 7638 requires for thumbprints.
+type jwk struct {
+	Crv string `json:"crv"`
+	Kty string `json:"kty"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newJWK(pub *ecdsa.PublicKey) jwk {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	return jwk{
+		Crv: pub.Curve.Params().Name,
+		Kty: "EC",
+		X:   b64.EncodeToString(pub.X.FillBytes(make([]byte, size))),
+		Y:   b64.EncodeToString(pub.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+// thumbprint is the RFC 7638 SHA-256 thumbprint of the key
+func thumbprint(pub *ecdsa.PublicKey) string {
+	data, _ := json.Marshal(newJWK(pub))
+	sum := sha256.Sum256(data)
+	return b64.EncodeToString(sum[:])
+}
+
+// jwsHeader is the protected header of an ACME request. Exactly one of JWK
+// (new accounts) and KID (everything else) is set.
+type jwsHeader struct {
+	Alg   string `json:"alg"`
+	Nonce string `json:"nonce"`
+	URL   string `json:"url"`
+	JWK   *jwk   `json:"jwk,omitempty"`
+	KID   string `json:"kid,omitempty"`
+}
+
+// jwsMessage is the flattened JSON serialization of a JWS
+type jwsMessage struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// signJWS signs the payload with ES256. A nil payload produces the empty
+// payload of a POST-as-GET request.
+func signJWS(key *ecdsa.PrivateKey, header jwsHeader, payload []byte) ([]byte, error) {
+	header.Alg = "ES256"
+	protected, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := jwsMessage{
+		Protected: b64.EncodeToString(protected),
+		Payload:   b64.EncodeToString(payload),
+	}
+	digest := sha256.Sum256([]byte(msg.Protected + "." + msg.Payload))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// JWS wants the fixed-size concatenation r || s, not ASN.1
+	size := (key.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+	msg.Signature = b64.EncodeToString(sig)
+
+	return json.Marshal(msg)
+}
+
+// newKey generates a P-256 key, used for accounts and certificates alike
+func newKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// encodeKey returns the PEM encoding of a private key
+func encodeKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// loadOrCreateKey reads a P-256 key from path, generating and saving a new
+// one when the file does not exist
+func loadOrCreateKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		key, err := newKey()
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := encodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFileAtomic(path, encoded); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read acme key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in acme key %s", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse acme key %s: %w", path, err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("acme key %s is not an ECDSA key", path)
+	}
+	return key, nil
+}
+
+// writeFileAtomic writes a private file through a temporary file and rename
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
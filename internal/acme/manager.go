@@ -0,0 +1,491 @@
+package acme
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+)
+
+// ALPNProto is the protocol a CA offers when validating tls-alpn-01
+const ALPNProto = "acme-tls/1"
+
+// DefaultDirectoryURL is the Let's Encrypt production directory
+const DefaultDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+
+const (
+	httpChallengePath  = "/.well-known/acme-challenge/"
+	defaultRenewBefore = 30 * 24 * time.Hour
+	checkInterval      = 12 * time.Hour
+	obtainTimeout      = 10 * time.Minute
+	minRetry           = time.Minute
+	maxRetry           = 6 * time.Hour
+)
+
+// idPeACMEIdentifier marks the validation certificate of tls-alpn-01 (RFC 8737)
+var idPeACMEIdentifier = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+// Status describes the managed certificate and the last renewal attempt
+type Status struct {
+	Domains       []string
+	NotAfter      time.Time
+	LastRenewal   time.Time
+	LastError     string
+	LastErrorTime time.Time
+	NextAttempt   time.Time
+}
+
+// Manager obtains a certificate for the configured domains, keeps it in the
+// data directory and renews it in the background
+type Manager struct {
+	client      *Client
+	email       string
+	domains     []string
+	challenges  []string
+	renewBefore time.Duration
+	certFile    string
+	keyFile     string
+	logger      logger.Logger
+	now         func() time.Time
+
+	mu     sync.RWMutex
+	cert   *tls.Certificate
+	status Status
+
+	challengeMu sync.Mutex
+	httpTokens  map[string]string
+	alpnCerts   map[string]*tls.Certificate
+}
+
+// NewManager prepares the data directory, loads or creates the account key
+// and loads a previously obtained certificate
+func NewManager(cfg config.ACMEConfig, log logger.Logger) (*Manager, error) {
+	if log == nil {
+		log = logger.NewLogger(logger.ErrorLevel, nil)
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create acme data_dir: %w", err)
+	}
+
+	httpClient, err := newHTTPClient(cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	accountKey, err := loadOrCreateKey(filepath.Join(cfg.DataDir, "account.key"))
+	if err != nil {
+		return nil, err
+	}
+
+	directoryURL := cfg.DirectoryURL
+	if directoryURL == "" {
+		directoryURL = DefaultDirectoryURL
+	}
+	challenges := cfg.Challenges
+	if len(challenges) == 0 {
+		challenges = []string{ChallengeTLSALPN01}
+	}
+	renewBefore := cfg.RenewBefore
+	if renewBefore == 0 {
+		renewBefore = defaultRenewBefore
+	}
+
+	name := strings.ToLower(cfg.Domains[0])
+	m := &Manager{
+		client:      NewClient(directoryURL, accountKey, httpClient),
+		email:       cfg.Email,
+		domains:     cfg.Domains,
+		challenges:  challenges,
+		renewBefore: renewBefore,
+		certFile:    filepath.Join(cfg.DataDir, name+".crt"),
+		keyFile:     filepath.Join(cfg.DataDir, name+".key"),
+		logger:      log,
+		now:         time.Now,
+		status:      Status{Domains: cfg.Domains},
+		httpTokens:  make(map[string]string),
+		alpnCerts:   make(map[string]*tls.Certificate),
+	}
+	m.loadCertificate()
+	return m, nil
+}
+
+// OffersTLSALPN reports whether tls-alpn-01 is used, which requires the
+// acme-tls/1 protocol to be offered by the TLS listener
+func (m *Manager) OffersTLSALPN() bool {
+	for _, ch := range m.challenges {
+		if ch == ChallengeTLSALPN01 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCertificate serves the managed certificate, or the validation
+// certificate when the CA checks a tls-alpn-01 challenge
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == ALPNProto {
+		m.challengeMu.Lock()
+		cert := m.alpnCerts[strings.ToLower(hello.ServerName)]
+		m.challengeMu.Unlock()
+		if cert == nil {
+			return nil, fmt.Errorf("no tls-alpn-01 challenge pending for %q", hello.ServerName)
+		}
+		return cert, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.cert == nil {
+		return nil, fmt.Errorf("no certificate obtained yet for %s", strings.Join(m.domains, ", "))
+	}
+	return m.cert, nil
+}
+
+// HTTPHandler answers http-01 challenges and passes other requests to next
+func (m *Manager) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, httpChallengePath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m.challengeMu.Lock()
+		keyAuth, ok := m.httpTokens[strings.TrimPrefix(r.URL.Path, httpChallengePath)]
+		m.challengeMu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(keyAuth))
+	})
+}
+
+// Status returns the state of the managed certificate
+func (m *Manager) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Run obtains the certificate if needed and renews it until the context is
+// cancelled. Failures are logged, recorded in the status and retried with
+// increasing delays.
+func (m *Manager) Run(ctx context.Context) {
+	retry := minRetry
+	for {
+		wait := m.untilRenewal()
+		if wait <= 0 {
+			if err := m.Obtain(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				wait = retry
+				if retry *= 2; retry > maxRetry {
+					retry = maxRetry
+				}
+				m.recordFailure(err, wait)
+			} else {
+				retry = minRetry
+				wait = m.untilRenewal()
+			}
+		}
+		if wait > checkInterval {
+			wait = checkInterval
+		}
+
+		m.mu.Lock()
+		m.status.NextAttempt = m.now().Add(wait)
+		m.mu.Unlock()
+
+		if sleep(ctx, wait) != nil {
+			return
+		}
+	}
+}
+
+// Obtain requests a new certificate from the CA
+func (m *Manager) Obtain(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, obtainTimeout)
+	defer cancel()
+
+	if err := m.client.Register(ctx, m.email); err != nil {
+		return err
+	}
+	order, err := m.client.NewOrder(ctx, m.domains)
+	if err != nil {
+		return err
+	}
+	for _, url := range order.Authorizations {
+		if err := m.authorize(ctx, url); err != nil {
+			return err
+		}
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: m.domains[0]},
+		DNSNames: m.domains,
+	}, key)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	order, err = m.client.Finalize(ctx, order, csr)
+	if err != nil {
+		return err
+	}
+	chain, err := m.client.Certificate(ctx, order.Certificate)
+	if err != nil {
+		return err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return err
+	}
+
+	cert, err := parseCertificate(chain, keyPEM)
+	if err != nil {
+		return fmt.Errorf("acme server returned an unusable certificate: %w", err)
+	}
+	if err := writeFileAtomic(m.keyFile, keyPEM); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(m.certFile, chain); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.cert = cert
+	m.status.NotAfter = cert.Leaf.NotAfter
+	m.status.LastRenewal = m.now()
+	m.status.LastError = ""
+	m.status.LastErrorTime = time.Time{}
+	m.mu.Unlock()
+
+	m.logger.Info("Obtained TLS certificate", logger.Fields{
+		"domains":   m.domains,
+		"not_after": cert.Leaf.NotAfter.Format(time.RFC3339),
+	})
+	return nil
+}
+
+// authorize completes one authorization with the first supported challenge
+func (m *Manager) authorize(ctx context.Context, url string) error {
+	authz, err := m.client.Authorization(ctx, url)
+	if err != nil {
+		return err
+	}
+	if authz.Status == StatusValid {
+		return nil
+	}
+	domain := strings.ToLower(authz.Identifier.Value)
+
+	ch, ok := m.pickChallenge(authz)
+	if !ok {
+		return fmt.Errorf("acme server offered no supported challenge for %s", domain)
+	}
+
+	keyAuth := m.client.KeyAuthorization(ch.Token)
+	m.challengeMu.Lock()
+	switch ch.Type {
+	case ChallengeHTTP01:
+		m.httpTokens[ch.Token] = keyAuth
+		defer m.clearChallenge(func() { delete(m.httpTokens, ch.Token) })
+	case ChallengeTLSALPN01:
+		cert, err := tlsALPNCertificate(domain, keyAuth)
+		if err != nil {
+			m.challengeMu.Unlock()
+			return err
+		}
+		m.alpnCerts[domain] = cert
+		defer m.clearChallenge(func() { delete(m.alpnCerts, domain) })
+	}
+	m.challengeMu.Unlock()
+
+	if err := m.client.Accept(ctx, ch); err != nil {
+		return fmt.Errorf("failed to accept %s challenge for %s: %w", ch.Type, domain, err)
+	}
+	authz, err = m.client.WaitAuthorization(ctx, url)
+	if err != nil {
+		return err
+	}
+	if authz.Status != StatusValid {
+		for _, c := range authz.Challenges {
+			if c.Error != nil {
+				return fmt.Errorf("%s challenge for %s failed: %w", ch.Type, domain, c.Error)
+			}
+		}
+		return fmt.Errorf("authorization for %s is %s", domain, authz.Status)
+	}
+	return nil
+}
+
+func (m *Manager) clearChallenge(clear func()) {
+	m.challengeMu.Lock()
+	defer m.challengeMu.Unlock()
+	clear()
+}
+
+// pickChallenge returns the offered challenge ranked first in the configuration
+func (m *Manager) pickChallenge(authz *Authorization) (Challenge, bool) {
+	for _, want := range m.challenges {
+		for _, ch := range authz.Challenges {
+			if ch.Type == want {
+				return ch, true
+			}
+		}
+	}
+	return Challenge{}, false
+}
+
+// untilRenewal returns the time left until the certificate should be
+// renewed; certificates shorter-lived than renew_before are renewed after
+// two thirds of their lifetime
+func (m *Manager) untilRenewal() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.cert == nil {
+		return 0
+	}
+
+	leaf := m.cert.Leaf
+	renewBefore := m.renewBefore
+	if lifetime := leaf.NotAfter.Sub(leaf.NotBefore); renewBefore >= lifetime {
+		renewBefore = lifetime / 3
+	}
+	return leaf.NotAfter.Add(-renewBefore).Sub(m.now())
+}
+
+func (m *Manager) recordFailure(err error, retry time.Duration) {
+	m.mu.Lock()
+	m.status.LastError = err.Error()
+	m.status.LastErrorTime = m.now()
+	notAfter := m.status.NotAfter
+	m.mu.Unlock()
+
+	fields := logger.Fields{
+		"domains": m.domains,
+		"error":   err.Error(),
+		"retry":   retry.String(),
+	}
+	if !notAfter.IsZero() {
+		fields["not_after"] = notAfter.Format(time.RFC3339)
+	}
+	m.logger.Error("Failed to obtain TLS certificate", fields)
+}
+
+// loadCertificate restores a stored certificate that still covers all domains
+func (m *Manager) loadCertificate() {
+	chain, err := os.ReadFile(m.certFile)
+	if err != nil {
+		return
+	}
+	keyPEM, err := os.ReadFile(m.keyFile)
+	if err != nil {
+		return
+	}
+	cert, err := parseCertificate(chain, keyPEM)
+	if err != nil {
+		m.logger.Warn("Ignoring unusable stored certificate", logger.Fields{
+			"file":  m.certFile,
+			"error": err.Error(),
+		})
+		return
+	}
+	for _, domain := range m.domains {
+		if cert.Leaf.VerifyHostname(domain) != nil {
+			return
+		}
+	}
+
+	m.cert = cert
+	m.status.NotAfter = cert.Leaf.NotAfter
+}
+
+// parseCertificate parses a PEM chain and key and fills in the leaf
+func parseCertificate(chain, keyPEM []byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(chain, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	cert.Leaf = leaf
+	return &cert, nil
+}
+
+// tlsALPNCertificate creates the self-signed validation certificate for a
+// tls-alpn-01 challenge (RFC 8737)
+func tlsALPNCertificate(domain, keyAuth string) (*tls.Certificate, error) {
+	key, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(keyAuth))
+	value, err := asn1.Marshal(sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano()),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			{Id: idPeACMEIdentifier, Critical: true, Value: value},
+		},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls-alpn-01 certificate: %w", err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// newHTTPClient returns the client used to talk to the CA, trusting the
+// extra CA file if one is configured (e.g. for a local Pebble instance)
+func newHTTPClient(caFile string) (*http.Client, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	if caFile == "" {
+		return client, nil
+	}
+
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read acme ca_file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in acme ca_file %s", caFile)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	client.Transport = transport
+	return client, nil
+}
@@ -0,0 +1,223 @@
+package acme
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+)
+
+func newTestManager(t *testing.T, ca *testCA, challenges ...string) (*Manager, config.ACMEConfig) {
+	cfg := config.ACMEConfig{
+		Enabled:      true,
+		DirectoryURL: ca.URL + "/directory",
+		Email:        "admin@example.com",
+		Domains:      []string{"files.example.com", "www.example.com"},
+		DataDir:      filepath.Join(t.TempDir(), "acme"),
+		Challenges:   challenges,
+		CAFile:       ca.caFile(t),
+	}
+	m, err := NewManager(cfg, nil)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	return m, cfg
+}
+
+// validateHTTP01 fetches the challenge response from the manager's handler
+func validateHTTP01(m *Manager) func(string, Challenge, string) error {
+	return func(domain string, ch Challenge, keyAuth string) error {
+		if ch.Type != ChallengeHTTP01 {
+			return fmt.Errorf("unexpected challenge %s", ch.Type)
+		}
+		req := httptest.NewRequest("GET", "http://"+domain+"/.well-known/acme-challenge/"+ch.Token, nil)
+		rr := httptest.NewRecorder()
+		m.HTTPHandler(nil).ServeHTTP(rr, req)
+		if rr.Code != 200 || rr.Body.String() != keyAuth {
+			return fmt.Errorf("got %d %q", rr.Code, rr.Body.String())
+		}
+		return nil
+	}
+}
+
+// validateTLSALPN01 checks the validation certificate as RFC 8737 requires
+func validateTLSALPN01(m *Manager) func(string, Challenge, string) error {
+	return func(domain string, ch Challenge, keyAuth string) error {
+		if ch.Type != ChallengeTLSALPN01 {
+			return fmt.Errorf("unexpected challenge %s", ch.Type)
+		}
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: domain, SupportedProtos: []string{ALPNProto}})
+		if err != nil {
+			return err
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return err
+		}
+		if len(leaf.DNSNames) != 1 || leaf.DNSNames[0] != domain {
+			return fmt.Errorf("wrong names %v", leaf.DNSNames)
+		}
+		sum := sha256.Sum256([]byte(keyAuth))
+		for _, ext := range leaf.Extensions {
+			if ext.Id.Equal(idPeACMEIdentifier) {
+				var value []byte
+				if _, err := asn1.Unmarshal(ext.Value, &value); err != nil {
+					return err
+				}
+				if !ext.Critical || !bytes.Equal(value, sum[:]) {
+					return errors.New("wrong acmeIdentifier extension")
+				}
+				return nil
+			}
+		}
+		return errors.New("missing acmeIdentifier extension")
+	}
+}
+
+func TestManager_ObtainHTTP01(t *testing.T) {
+	ca := newTestCA(t)
+	m, cfg := newTestManager(t, ca, ChallengeHTTP01)
+	ca.validate = validateHTTP01(m)
+
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "files.example.com"}); err == nil {
+		t.Error("Expected no certificate before the first order")
+	}
+	if err := m.Obtain(context.Background()); err != nil {
+		t.Fatalf("Obtain failed: %v", err)
+	}
+
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.com"})
+	if err != nil {
+		t.Fatalf("GetCertificate failed: %v", err)
+	}
+	if err := cert.Leaf.VerifyHostname("www.example.com"); err != nil {
+		t.Errorf("Expected certificate for all domains: %v", err)
+	}
+	if len(cert.Certificate) != 2 {
+		t.Errorf("Expected leaf and issuer in chain, got %d certificates", len(cert.Certificate))
+	}
+
+	// Challenge responses are removed once validated
+	if len(m.httpTokens) != 0 {
+		t.Errorf("Expected challenge tokens to be cleared, got %d", len(m.httpTokens))
+	}
+
+	status := m.Status()
+	if status.NotAfter.IsZero() || status.LastRenewal.IsZero() || status.LastError != "" {
+		t.Errorf("Unexpected status after issuance: %+v", status)
+	}
+
+	for _, name := range []string{"account.key", "files.example.com.crt", "files.example.com.key"} {
+		info, err := os.Stat(filepath.Join(cfg.DataDir, name))
+		if err != nil {
+			t.Errorf("Expected %s in data directory: %v", name, err)
+		} else if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected %s to be private, got %v", name, info.Mode().Perm())
+		}
+	}
+
+	// A restarted manager uses the stored certificate and account
+	restarted, err := NewManager(cfg, nil)
+	if err != nil {
+		t.Fatalf("Failed to restart manager: %v", err)
+	}
+	stored, err := restarted.GetCertificate(&tls.ClientHelloInfo{ServerName: "files.example.com"})
+	if err != nil || !bytes.Equal(stored.Certificate[0], cert.Certificate[0]) {
+		t.Errorf("Expected the stored certificate after restart, got %v", err)
+	}
+	if restarted.client.KeyAuthorization("t") != m.client.KeyAuthorization("t") {
+		t.Error("Expected the stored account key after restart")
+	}
+}
+
+func TestManager_ObtainTLSALPN01(t *testing.T) {
+	ca := newTestCA(t)
+	m, _ := newTestManager(t, ca)
+	ca.validate = validateTLSALPN01(m)
+
+	if !m.OffersTLSALPN() {
+		t.Fatal("Expected tls-alpn-01 by default")
+	}
+	if err := m.Obtain(context.Background()); err != nil {
+		t.Fatalf("Obtain failed: %v", err)
+	}
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "files.example.com"}); err != nil {
+		t.Errorf("Expected certificate after issuance: %v", err)
+	}
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "files.example.com", SupportedProtos: []string{ALPNProto}}); err == nil {
+		t.Error("Expected no validation certificate once the challenge is done")
+	}
+}
+
+func TestManager_RunRecordsFailures(t *testing.T) {
+	ca := newTestCA(t)
+	var logBuffer strings.Builder
+	m, _ := newTestManager(t, ca, ChallengeHTTP01)
+	m.logger = logger.NewLogger(logger.InfoLevel, &logBuffer)
+	ca.validate = func(string, Challenge, string) error { return errors.New("connection refused") }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Status().NextAttempt.IsZero() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	status := m.Status()
+	if !strings.Contains(status.LastError, "connection refused") || status.LastErrorTime.IsZero() {
+		t.Errorf("Expected the failure in the status, got %+v", status)
+	}
+	if wait := status.NextAttempt.Sub(status.LastErrorTime); wait < minRetry-time.Second || wait > minRetry+time.Second {
+		t.Errorf("Expected a retry after %v, got %v", minRetry, wait)
+	}
+	if !strings.Contains(logBuffer.String(), "Failed to obtain TLS certificate") {
+		t.Errorf("Expected failure in log output, got: %s", logBuffer.String())
+	}
+}
+
+func TestManager_UntilRenewal(t *testing.T) {
+	ca := newTestCA(t)
+	m, _ := newTestManager(t, ca, ChallengeHTTP01)
+	ca.validate = validateHTTP01(m)
+
+	if m.untilRenewal() > 0 {
+		t.Error("Expected immediate issuance without a certificate")
+	}
+	if err := m.Obtain(context.Background()); err != nil {
+		t.Fatalf("Obtain failed: %v", err)
+	}
+
+	// 90-day certificate, renewed 30 days before expiry
+	if wait := m.untilRenewal(); wait < 59*24*time.Hour || wait > 60*24*time.Hour {
+		t.Errorf("Expected renewal in about 60 days, got %v", wait)
+	}
+
+	// Short-lived certificates are renewed after two thirds of their lifetime
+	ca.certTTL = 6 * 24 * time.Hour
+	if err := m.Obtain(context.Background()); err != nil {
+		t.Fatalf("Obtain failed: %v", err)
+	}
+	if wait := m.untilRenewal(); wait < 95*time.Hour || wait > 97*time.Hour {
+		t.Errorf("Expected renewal in about 4 days, got %v", wait)
+	}
+}
@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+	"otterserve/internal/netutil"
+	"otterserve/internal/server"
+)
+
+// prefix is the path every admin endpoint lives under
+const prefix = "/api/v1"
+
+// Server is the running server the admin API operates on
+type Server interface {
+	Config() *config.Config
+	RegisterRoutes(routes []config.RouteConfig) error
+	Connections() []server.ConnectionInfo
+}
+
+// Reloader reloads the configuration file
+type Reloader interface {
+	Reload() error
+}
+
+// API serves the admin endpoints. Requests must carry the configured token
+// as a bearer token and come from an address in the allow list, if any.
+type API struct {
+	server   Server
+	manager  config.ConfigManager
+	reloader Reloader
+	logger   logger.Logger
+	token    [sha256.Size]byte
+	filter   *netutil.IPFilter
+	mu       sync.Mutex // serializes route changes
+
+	metricsPath string
+	metrics     http.Handler
+}
+
+// New creates the admin API for srv. reloader may be nil, in which case the
+// reload endpoint is not available.
+func New(cfg config.AdminConfig, srv Server, manager config.ConfigManager, reloader Reloader, log logger.Logger) (*API, error) {
+	if log == nil {
+		log = logger.NewLogger(logger.ErrorLevel, nil)
+	}
+	filter, err := netutil.NewIPFilter(cfg.Allow, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &API{
+		server:   srv,
+		manager:  manager,
+		reloader: reloader,
+		logger:   log,
+		token:    sha256.Sum256([]byte(cfg.Token)),
+		filter:   filter,
+	}, nil
+}
+
+// ServeMetrics serves handler at path, outside the /api/v1 prefix but with
+// the same authentication
+func (a *API) ServeMetrics(path string, handler http.Handler) {
+	a.metricsPath, a.metrics = path, handler
+}
+
+// ServeHTTP checks the client and passes the request to its endpoint
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ok, reason := a.filter.Check(netutil.RemoteAddr(r)); !ok {
+		a.logger.Warn("Admin request denied", logger.Fields{
+			"remote_addr": r.RemoteAddr,
+			"reason":      reason,
+		})
+		writeError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	// The description of the API is public so that tools can fetch it
+	if r.URL.Path == prefix+"/openapi.json" {
+		a.handleOpenAPI(w, r)
+		return
+	}
+
+	if !a.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="otterserve admin"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid token")
+		return
+	}
+
+	switch path := r.URL.Path; {
+	case a.metrics != nil && path == a.metricsPath:
+		a.metrics.ServeHTTP(w, r)
+	case path == prefix+"/config":
+		a.handleConfig(w, r)
+	case path == prefix+"/routes":
+		a.handleRoutes(w, r)
+	case strings.HasPrefix(path, prefix+"/routes/"):
+		a.handleRoute(w, r, strings.TrimPrefix(path, prefix+"/routes"))
+	case path == prefix+"/log-level":
+		a.handleLogLevel(w, r)
+	case path == prefix+"/connections":
+		a.handleConnections(w, r)
+	case path == prefix+"/reload":
+		a.handleReload(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// authorized reports whether the request carries the admin token. Hashes
+// are compared so the comparison takes the same time whatever the length
+// of the presented token.
+func (a *API) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return false
+	}
+	presented := sha256.Sum256([]byte(strings.TrimSpace(header[7:])))
+	return subtle.ConstantTimeCompare(presented[:], a.token[:]) == 1
+}
+
+// allowMethods answers requests with a method other than those listed and
+// reports whether the request may proceed
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	return false
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeError writes an error response in the form {"error": message}
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
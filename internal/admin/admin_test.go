@@ -0,0 +1,328 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/fileserver"
+	"otterserve/internal/logger"
+	"otterserve/internal/server"
+)
+
+const testToken = "0123456789abcdef0123"
+
+// fakeReloader counts reloads and fails with err
+type fakeReloader struct {
+	calls int
+	err   error
+}
+
+func (f *fakeReloader) Reload() error {
+	f.calls++
+	return f.err
+}
+
+// testEnv is a running server with the admin API attached
+type testEnv struct {
+	t        *testing.T
+	server   *server.HTTPServer
+	log      logger.Logger
+	reloader *fakeReloader
+	dir      string
+	admin    string
+	site     string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		os.MkdirAll(filepath.Join(dir, name), 0755)
+		os.WriteFile(filepath.Join(dir, name, "file.txt"), []byte(name), 0644)
+	}
+
+	cfg := &config.Config{
+		Server:  config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Auth:    config.AuthConfig{Username: "admin", Password: "hunter2"},
+		Routes:  []config.RouteConfig{{Path: "/a", Directory: filepath.Join(dir, "a")}},
+		Logging: config.LoggingConfig{Level: "info"},
+		Admin:   config.AdminConfig{Enabled: true, Address: "127.0.0.1:0", Token: testToken},
+		Tracing: config.TracingConfig{Headers: map[string]string{"Authorization": "Bearer collector-key"}},
+	}
+	log := logger.NewLogger(logger.InfoLevel, io.Discard)
+	srv := server.NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*server.HTTPServer)
+	reloader := &fakeReloader{}
+	api, err := New(cfg.Admin, srv, config.NewConfigManager(), reloader, log)
+	if err != nil {
+		t.Fatalf("Failed to create admin API: %v", err)
+	}
+	srv.SetAdminHandler(api)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Stop(ctx)
+	})
+
+	return &testEnv{
+		t:        t,
+		server:   srv,
+		log:      log,
+		reloader: reloader,
+		dir:      dir,
+		admin:    "http://" + srv.AdminAddr().String() + prefix,
+		site:     "http://" + srv.GetAddr(),
+	}
+}
+
+// do sends an authenticated admin request and returns the status and body
+func (e *testEnv) do(method, path, body string) (int, string) {
+	req, _ := http.NewRequest(method, e.admin+path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		e.t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// get returns the status of a request to the file server
+func (e *testEnv) get(path string) int {
+	resp, err := http.Get(e.site + path)
+	if err != nil {
+		e.t.Fatalf("GET %s failed: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAPI_Authentication(t *testing.T) {
+	env := newTestEnv(t)
+
+	for _, header := range []string{"", "Bearer wrong-token", "Basic " + testToken} {
+		req, _ := http.NewRequest(http.MethodGet, env.admin+"/routes", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: expected 401, got %d", header, resp.StatusCode)
+		}
+		if !strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Bearer") {
+			t.Errorf("Expected a bearer challenge, got %q", resp.Header.Get("WWW-Authenticate"))
+		}
+	}
+
+	// The admin API is not served on the server's listener
+	if code := env.get(prefix + "/routes"); code != http.StatusNotFound {
+		t.Errorf("Expected the admin API to be absent from the server listener, got %d", code)
+	}
+
+	// The description is public and valid JSON
+	resp, err := http.Get(env.admin + "/openapi.json")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var doc map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	resp.Body.Close()
+	if err != nil || doc["openapi"] == nil {
+		t.Errorf("Expected an OpenAPI document, got %v (%v)", doc, err)
+	}
+}
+
+func TestAPI_AllowList(t *testing.T) {
+	api, err := New(config.AdminConfig{Token: testToken, Allow: []string{"10.0.0.0/8"}}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create admin API: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, prefix+"/openapi.json", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 outside the allow list, got %d", rr.Code)
+	}
+}
+
+func TestAPI_Config(t *testing.T) {
+	env := newTestEnv(t)
+
+	code, body := env.do(http.MethodGet, "/config", "")
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", code, body)
+	}
+	if strings.Contains(body, "hunter2") || strings.Contains(body, testToken) || strings.Contains(body, "collector-key") {
+		t.Errorf("Expected secrets to be redacted: %s", body)
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &cfg); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if cfg["auth"].(map[string]interface{})["password"] != "REDACTED" {
+		t.Errorf("Expected the password to read REDACTED, got %v", cfg["auth"])
+	}
+	if cfg["routes"].([]interface{})[0].(map[string]interface{})["path"] != "/a" {
+		t.Errorf("Expected configuration file keys, got %v", cfg["routes"])
+	}
+}
+
+func TestAPI_Routes(t *testing.T) {
+	env := newTestEnv(t)
+	b := `{"path": "/b", "directory": "` + filepath.Join(env.dir, "b") + `"}`
+
+	if code, body := env.do(http.MethodPost, "/routes", b); code != http.StatusCreated {
+		t.Fatalf("Expected 201 adding a route, got %d: %s", code, body)
+	}
+	if code := env.get("/b/file.txt"); code != http.StatusOK {
+		t.Errorf("Expected the new route to be served, got %d", code)
+	}
+	if code, _ := env.do(http.MethodPost, "/routes", b); code != http.StatusConflict {
+		t.Errorf("Expected 409 adding an existing route, got %d", code)
+	}
+	if code, _ := env.do(http.MethodPost, "/routes", `{"path": "/x", "directory": "/does/not/exist"}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a missing directory, got %d", code)
+	}
+	if code, _ := env.do(http.MethodPost, "/routes", `{"path": "/x", "dir": "/tmp"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown field, got %d", code)
+	}
+
+	code, body := env.do(http.MethodGet, "/routes", "")
+	var routes []config.RouteConfig
+	if err := json.Unmarshal([]byte(body), &routes); err != nil || code != http.StatusOK || len(routes) != 2 {
+		t.Fatalf("Expected two routes, got %d %s", code, body)
+	}
+
+	// Point /b at another directory
+	c := `{"path": "/b", "directory": "` + filepath.Join(env.dir, "c") + `"}`
+	if code, body := env.do(http.MethodPut, "/routes/b", c); code != http.StatusOK {
+		t.Fatalf("Expected 200 updating a route, got %d: %s", code, body)
+	}
+	resp, err := http.Get(env.site + "/b/file.txt")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != "c" {
+		t.Errorf("Expected the updated directory to be served, got %q", data)
+	}
+	if code, _ := env.do(http.MethodPut, "/routes/b", `{"path": "/a", "directory": "/tmp"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a path that does not match the URL, got %d", code)
+	}
+
+	if code, _ := env.do(http.MethodDelete, "/routes/b", ""); code != http.StatusNoContent {
+		t.Errorf("Expected 204 removing a route, got %d", code)
+	}
+	if code := env.get("/b/file.txt"); code != http.StatusNotFound {
+		t.Errorf("Expected the removed route to be gone, got %d", code)
+	}
+	if code, _ := env.do(http.MethodDelete, "/routes/b", ""); code != http.StatusNotFound {
+		t.Errorf("Expected 404 removing a missing route, got %d", code)
+	}
+
+	// The last route cannot be removed
+	if code, _ := env.do(http.MethodDelete, "/routes/a", ""); code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 removing the last route, got %d", code)
+	}
+	if code := env.get("/a/file.txt"); code != http.StatusOK {
+		t.Errorf("Expected /a to keep serving, got %d", code)
+	}
+}
+
+func TestAPI_LogLevel(t *testing.T) {
+	env := newTestEnv(t)
+
+	if code, body := env.do(http.MethodPut, "/log-level", `{"level": "debug"}`); code != http.StatusOK || !strings.Contains(body, `"debug"`) {
+		t.Errorf("Expected the level to change, got %d %s", code, body)
+	}
+	if env.log.GetLevel() != logger.DebugLevel {
+		t.Errorf("Expected debug level, got %v", env.log.GetLevel())
+	}
+	if code, _ := env.do(http.MethodPut, "/log-level", `{"level": "loud"}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an unknown level, got %d", code)
+	}
+	if code, _ := env.do(http.MethodPost, "/log-level", `{}`); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", code)
+	}
+}
+
+func TestAPI_Connections(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Keep a connection to the server open
+	client := &http.Client{Transport: &http.Transport{}}
+	defer client.CloseIdleConnections()
+	resp, err := client.Get(env.site + "/a/file.txt")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	code, body := env.do(http.MethodGet, "/connections", "")
+	var result struct {
+		Count       int                     `json:"count"`
+		Connections []server.ConnectionInfo `json:"connections"`
+	}
+	if err := json.Unmarshal([]byte(body), &result); err != nil || code != http.StatusOK {
+		t.Fatalf("Unexpected response %d %s", code, body)
+	}
+	if result.Count != 1 || result.Connections[0].Listener != env.server.GetAddr() {
+		t.Errorf("Expected the open connection to be listed, got %+v", result)
+	}
+}
+
+func TestAPI_Reload(t *testing.T) {
+	env := newTestEnv(t)
+
+	if code, _ := env.do(http.MethodPost, "/reload", ""); code != http.StatusOK || env.reloader.calls != 1 {
+		t.Errorf("Expected a reload, got %d after %d calls", code, env.reloader.calls)
+	}
+	env.reloader.err = errors.New("invalid configuration")
+	if code, body := env.do(http.MethodPost, "/reload", ""); code != http.StatusUnprocessableEntity || !strings.Contains(body, "invalid configuration") {
+		t.Errorf("Expected the reload error, got %d %s", code, body)
+	}
+}
+
+func TestAPI_Metrics(t *testing.T) {
+	api, err := New(config.AdminConfig{Token: testToken}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create admin API: %v", err)
+	}
+	api.ServeMetrics("/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "up 1\n")
+	}))
+
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected metrics to need the token, got %d", rr.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr = httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.String() != "up 1\n" {
+		t.Errorf("Expected metrics, got %d %q", rr.Code, rr.Body.String())
+	}
+}
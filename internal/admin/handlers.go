@@ -0,0 +1,269 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+)
+
+// maxBodySize limits request bodies; admin requests are small
+const maxBodySize = 1 << 20
+
+// handleConfig returns the configuration in effect with secrets removed.
+// It goes through YAML so the keys are those of the configuration file.
+func (a *API) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	data, err := yaml.Marshal(a.server.Config())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	redact(doc)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// redact replaces the values of secret settings in a decoded configuration
+func redact(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && s != "" && secretKey(key) {
+				v[key] = "REDACTED"
+				continue
+			}
+			// Header values, such as those sent to trace collectors, often
+			// carry credentials
+			if headers, ok := value.(map[string]interface{}); ok && key == "headers" {
+				for name := range headers {
+					headers[name] = "REDACTED"
+				}
+				continue
+			}
+			redact(value)
+		}
+	case []interface{}:
+		for _, item := range v {
+			redact(item)
+		}
+	}
+}
+
+// secretKey reports whether a configuration key holds a secret
+func secretKey(key string) bool {
+	return key == "token" || strings.HasSuffix(key, "password") || strings.HasSuffix(key, "secret")
+}
+
+// handleRoutes lists the routes or adds one
+func (a *API) handleRoutes(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, a.server.Config().Routes)
+		return
+	}
+
+	route, ok := decodeRoute(w, r)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	routes := a.server.Config().Routes
+	if findRoute(routes, route.Path) >= 0 {
+		writeError(w, http.StatusConflict, fmt.Sprintf("route %s already exists", route.Path))
+		return
+	}
+	updated := append(append([]config.RouteConfig(nil), routes...), route)
+	if err := a.setRoutes(updated); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	a.logger.Info("Route added through admin API", logger.Fields{
+		"path":        route.Path,
+		"directory":   route.Directory,
+		"remote_addr": r.RemoteAddr,
+	})
+	w.Header().Set("Location", prefix+"/routes/"+strings.Trim(route.Path, "/"))
+	writeJSON(w, http.StatusCreated, route)
+}
+
+// handleRoute shows, replaces or removes the route with the given path
+func (a *API) handleRoute(w http.ResponseWriter, r *http.Request, path string) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPut, http.MethodDelete) {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	routes := a.server.Config().Routes
+	i := findRoute(routes, path)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("route %s does not exist", path))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, routes[i])
+
+	case http.MethodPut:
+		route, ok := decodeRoute(w, r)
+		if !ok {
+			return
+		}
+		if strings.Trim(route.Path, "/") != strings.Trim(path, "/") {
+			writeError(w, http.StatusBadRequest, "route path does not match the URL")
+			return
+		}
+		updated := append([]config.RouteConfig(nil), routes...)
+		updated[i] = route
+		if err := a.setRoutes(updated); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		a.logger.Info("Route updated through admin API", logger.Fields{
+			"path":        route.Path,
+			"directory":   route.Directory,
+			"remote_addr": r.RemoteAddr,
+		})
+		writeJSON(w, http.StatusOK, route)
+
+	case http.MethodDelete:
+		updated := append(append([]config.RouteConfig(nil), routes[:i]...), routes[i+1:]...)
+		if err := a.setRoutes(updated); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		a.logger.Info("Route removed through admin API", logger.Fields{
+			"path":        routes[i].Path,
+			"remote_addr": r.RemoteAddr,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// decodeRoute reads a route from the request body, answering the request
+// if it is not a valid route
+func decodeRoute(w http.ResponseWriter, r *http.Request) (config.RouteConfig, bool) {
+	var route config.RouteConfig
+	if err := decodeBody(w, r, &route); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return route, false
+	}
+	if route.Path == "" {
+		writeError(w, http.StatusBadRequest, "route path cannot be empty")
+		return route, false
+	}
+	if !strings.HasPrefix(route.Path, "/") {
+		route.Path = "/" + route.Path
+	}
+	return route, true
+}
+
+// decodeBody decodes the JSON request body into v, rejecting unknown fields
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// findRoute returns the index of the route with the given path, comparing
+// without leading and trailing slashes, or -1
+func findRoute(routes []config.RouteConfig, path string) int {
+	for i, route := range routes {
+		if strings.Trim(route.Path, "/") == strings.Trim(path, "/") {
+			return i
+		}
+	}
+	return -1
+}
+
+// setRoutes validates the configuration with routes in place of the current
+// ones and makes the server serve them
+func (a *API) setRoutes(routes []config.RouteConfig) error {
+	cfg := *a.server.Config()
+	cfg.Routes = routes
+	if err := a.manager.Validate(&cfg); err != nil {
+		return err
+	}
+	return a.server.RegisterRoutes(routes)
+}
+
+// logLevel is the body of the log level endpoint
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// handleLogLevel shows or changes the log level
+func (a *API) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPut) {
+		return
+	}
+	if r.Method == http.MethodPut {
+		var body logLevel
+		if err := decodeBody(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		level, err := logger.ParseLogLevel(body.Level)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		a.logger.SetLevel(level)
+		a.logger.Info("Log level changed through admin API", logger.Fields{
+			"level":       strings.ToLower(level.String()),
+			"remote_addr": r.RemoteAddr,
+		})
+	}
+	writeJSON(w, http.StatusOK, logLevel{Level: strings.ToLower(a.logger.GetLevel().String())})
+}
+
+// handleConnections lists the open client connections
+func (a *API) handleConnections(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	connections := a.server.Connections()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"count":       len(connections),
+		"connections": connections,
+	})
+}
+
+// handleReload reloads the configuration file
+func (a *API) handleReload(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	if a.reloader == nil {
+		writeError(w, http.StatusNotImplemented, "reloading is not available")
+		return
+	}
+	a.logger.Info("Configuration reload requested through admin API", logger.Fields{
+		"remote_addr": r.RemoteAddr,
+	})
+	if err := a.reloader.Reload(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+}
@@ -0,0 +1,20 @@
+package admin
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPI describes the admin API in OpenAPI 3 format
+//
+//go:embed openapi.json
+var openAPI []byte
+
+// handleOpenAPI serves the description of the API
+func (a *API) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPI)
+}
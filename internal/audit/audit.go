@@ -0,0 +1,330 @@
+package audit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"otterserve/internal/config"
+	"otterserve/internal/netutil"
+	"otterserve/internal/requestid"
+	"otterserve/internal/rotate"
+)
+
+// Event types
+const (
+	EventLogin        = "login"
+	EventLogout       = "logout"
+	EventLockout      = "lockout"
+	EventAccessDenied = "access_denied"
+	EventWrite        = "write"
+)
+
+// Event outcomes
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Event is a single audit log entry
+type Event struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"event"`
+	Outcome    string    `json:"outcome"`
+	User       string    `json:"user,omitempty"`
+	AuthMethod string    `json:"auth_method,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	Route      string    `json:"route,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	Status     int       `json:"status,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	PrevHash   string    `json:"prev_hash,omitempty"`
+	Hash       string    `json:"hash,omitempty"`
+}
+
+// Logger appends events as JSON lines. With hash chaining enabled every
+// entry carries the hash of its predecessor, so removing or editing entries
+// breaks the chain.
+type Logger struct {
+	mu        sync.Mutex
+	w         io.Writer
+	closer    io.Closer
+	hashChain bool
+	prevHash  string
+	now       func() time.Time
+}
+
+// NewLogger opens the audit log file from configuration in append mode,
+// continuing an existing hash chain
+func NewLogger(cfg config.AuditConfig) (*Logger, error) {
+	prevHash := ""
+	if cfg.HashChain {
+		last, err := lastHash(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		prevHash = last
+	}
+
+	file, err := rotate.Open(cfg.File, 0600, cfg.Rotation.Options())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	l := NewWriterLogger(file, cfg.HashChain, prevHash)
+	l.closer = file
+	return l, nil
+}
+
+// NewWriterLogger creates a logger writing to w, continuing the hash chain
+// from prevHash when hash chaining is enabled
+func NewWriterLogger(w io.Writer, hashChain bool, prevHash string) *Logger {
+	return &Logger{
+		w:         w,
+		hashChain: hashChain,
+		prevHash:  prevHash,
+		now:       time.Now,
+	}
+}
+
+// Record appends an event. A nil logger discards events.
+func (l *Logger) Record(e Event) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = l.now()
+	}
+	e.Time = e.Time.UTC()
+	e.PrevHash, e.Hash = "", ""
+
+	if l.hashChain {
+		e.PrevHash = l.prevHash
+		e.Hash = eventHash(e)
+		l.prevHash = e.Hash
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	l.w.Write(append(line, '\n'))
+}
+
+// Close closes the underlying file
+func (l *Logger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// Verify checks the hash chain of an audit log and returns the number of
+// entries verified, or an error naming the first entry that does not match
+func Verify(r io.Reader) (int, error) {
+	count, _, err := verify(r, true)
+	return count, err
+}
+
+// VerifyLog checks the hash chain of the audit log at path together with
+// its rotated files, oldest first. Rotation may have removed the oldest
+// files, so the first remaining entry is trusted to link to an earlier
+// one; the hash it links to is returned, empty if the chain is complete.
+func VerifyLog(path string) (count int, anchor string, err error) {
+	backups, err := rotate.Backups(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to list rotated audit logs: %w", err)
+	}
+	paths := []string{path}
+	for _, backup := range backups {
+		paths = append([]string{backup}, paths...)
+	}
+
+	var readers []io.Reader
+	for _, p := range paths {
+		r, err := openLog(p)
+		if err != nil {
+			return 0, "", err
+		}
+		defer r.Close()
+		readers = append(readers, r)
+	}
+	return verify(io.MultiReader(readers...), false)
+}
+
+// verify checks a hash chain. When anchored, the first entry must start the
+// chain; otherwise it may link to any entry, whose hash is returned.
+func verify(r io.Reader, anchored bool) (int, string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	prev, anchor := "", ""
+	count := 0
+	for scanner.Scan() {
+		count++
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return count - 1, anchor, fmt.Errorf("entry %d: %w", count, err)
+		}
+		if count == 1 && !anchored {
+			prev, anchor = e.PrevHash, e.PrevHash
+		}
+		if e.PrevHash != prev {
+			return count - 1, anchor, fmt.Errorf("entry %d: chain broken, expected previous hash %q", count, prev)
+		}
+		if e.Hash != eventHash(e) {
+			return count - 1, anchor, fmt.Errorf("entry %d: hash mismatch, entry was modified", count)
+		}
+		prev = e.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return count, anchor, err
+	}
+	return count, anchor, nil
+}
+
+// openLog opens an audit log file, decompressing rotated files that were
+// compressed
+func openLog(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, file}, nil
+}
+
+// eventHash is the SHA-256 of the entry serialized without its own hash
+func eventHash(e Event) string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// lastHash returns the hash of the last entry of an existing audit log. If
+// the log was just rotated and has no entries, the chain continues from the
+// newest rotated file.
+func lastHash(path string) (string, error) {
+	paths := []string{path}
+	backups, err := rotate.Backups(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to list rotated audit logs: %w", err)
+	}
+	paths = append(paths, backups...)
+
+	for _, p := range paths {
+		last, err := lastEntry(p)
+		if err != nil {
+			return "", err
+		}
+		if last != nil {
+			var e Event
+			if err := json.Unmarshal(last, &e); err != nil {
+				return "", fmt.Errorf("failed to parse last audit log entry: %w", err)
+			}
+			return e.Hash, nil
+		}
+	}
+	return "", nil
+}
+
+// lastEntry returns the last line of an audit log file, nil if it does not
+// exist or is empty
+func lastEntry(path string) ([]byte, error) {
+	r, err := openLog(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var last []byte
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+	return last, nil
+}
+
+type contextKey struct{}
+
+type requestAudit struct {
+	logger *Logger
+	route  string
+}
+
+// WithLogger returns a copy of the request through which handlers further
+// down the chain can record events for the given route
+func WithLogger(r *http.Request, l *Logger, route string) *http.Request {
+	if l == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, &requestAudit{logger: l, route: route}))
+}
+
+// Record adds request details (client IP, route, method, path and request
+// ID) to the event and records it with the logger attached to the request,
+// if any
+func Record(r *http.Request, e Event) {
+	ra, ok := r.Context().Value(contextKey{}).(*requestAudit)
+	if !ok {
+		return
+	}
+
+	if e.IP == "" {
+		if addr := netutil.ClientIP(r); addr.IsValid() {
+			e.IP = addr.String()
+		}
+	}
+	if e.Route == "" {
+		e.Route = ra.route
+	}
+	if e.Method == "" {
+		e.Method = r.Method
+	}
+	if e.Path == "" {
+		e.Path = r.URL.Path
+	}
+	if e.RequestID == "" {
+		e.RequestID = requestid.FromRequest(r)
+	}
+	ra.logger.Record(e)
+}
@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/config"
+	"otterserve/internal/requestid"
+	"otterserve/internal/rotate"
+)
+
+func TestLogger_HashChain(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, true, "")
+	l.Record(Event{Type: EventLogin, Outcome: OutcomeFailure, User: "alice", Reason: "invalid credentials"})
+	l.Record(Event{Type: EventLogin, Outcome: OutcomeSuccess, User: "alice"})
+	l.Record(Event{Type: EventWrite, Outcome: OutcomeSuccess, User: "alice", Path: "/upload/a.txt", Status: 201})
+
+	count, err := Verify(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Expected intact chain, got: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 entries verified, got %d", count)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var first, second Event
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[1]), &second)
+	if first.PrevHash != "" || first.Hash == "" {
+		t.Errorf("Expected first entry to start the chain, got prev=%q hash=%q", first.PrevHash, first.Hash)
+	}
+	if second.PrevHash != first.Hash {
+		t.Errorf("Expected second entry to link to the first")
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, true, "")
+	l.Record(Event{Type: EventLogin, Outcome: OutcomeFailure, User: "mallory"})
+	l.Record(Event{Type: EventLogin, Outcome: OutcomeSuccess, User: "alice"})
+	l.Record(Event{Type: EventLogout, Outcome: OutcomeSuccess, User: "alice"})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{"modified entry", []string{strings.Replace(lines[0], "mallory", "alice", 1), lines[1], lines[2]}},
+		{"removed entry", []string{lines[0], lines[2]}},
+		{"reordered entries", []string{lines[1], lines[0], lines[2]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(strings.NewReader(strings.Join(tt.lines, "\n"))); err == nil {
+				t.Error("Expected verification to fail")
+			}
+		})
+	}
+}
+
+func TestNewLogger_ContinuesChain(t *testing.T) {
+	cfg := config.AuditConfig{
+		Enabled:   true,
+		File:      filepath.Join(t.TempDir(), "audit.log"),
+		HashChain: true,
+	}
+
+	for i := 0; i < 2; i++ {
+		l, err := NewLogger(cfg)
+		if err != nil {
+			t.Fatalf("Failed to open audit log: %v", err)
+		}
+		l.Record(Event{Type: EventLogin, Outcome: OutcomeSuccess, User: "alice"})
+		l.Close()
+	}
+
+	data, err := os.ReadFile(cfg.File)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	if count, err := Verify(bytes.NewReader(data)); err != nil || count != 2 {
+		t.Errorf("Expected 2 chained entries across reopen, got %d: %v", count, err)
+	}
+
+	info, _ := os.Stat(cfg.File)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected audit log mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestRecord_FromRequest(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, false, "")
+	l.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600)) }
+
+	req := httptest.NewRequest("DELETE", "/files/report.pdf", nil)
+	req.RemoteAddr = "192.0.2.7:4000"
+
+	// Without a logger attached the event is discarded
+	Record(req, Event{Type: EventWrite, Outcome: OutcomeSuccess})
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no output without an attached logger, got: %s", buf.String())
+	}
+
+	req = requestid.With(req, "01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	Record(WithLogger(req, l, "/files/"), Event{Type: EventWrite, Outcome: OutcomeSuccess, User: "bob", Status: 204})
+
+	var e Event
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatalf("Expected a JSON entry, got %q: %v", buf.String(), err)
+	}
+	if e.IP != "192.0.2.7" || e.Route != "/files/" || e.Method != "DELETE" || e.Path != "/files/report.pdf" || e.RequestID != "01ARZ3NDEKTSV4RRFFQ69G5FAV" {
+		t.Errorf("Expected request details in entry, got %+v", e)
+	}
+	if e.Time.Location() != time.UTC || e.Time.Hour() != 10 {
+		t.Errorf("Expected UTC timestamp, got %v", e.Time)
+	}
+	if e.Hash != "" {
+		t.Errorf("Expected no hash without hash chaining, got %q", e.Hash)
+	}
+}
+
+func TestVerifyLog_AcrossRotation(t *testing.T) {
+	cfg := config.AuditConfig{
+		Enabled:   true,
+		File:      filepath.Join(t.TempDir(), "audit.log"),
+		HashChain: true,
+		Rotation:  config.RotationConfig{MaxBackups: 2, Compress: true},
+	}
+	l, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	writer := l.closer.(*rotate.Writer)
+	var hashes []string
+	for i := 0; i < 4; i++ {
+		l.Record(Event{Type: EventLogin, Outcome: OutcomeSuccess, User: "alice"})
+		hashes = append(hashes, l.prevHash)
+		if i < 3 {
+			writer.Rotate()
+		}
+	}
+	l.Close()
+
+	// The oldest file was removed, so the chain starts at the second entry
+	count, anchor, err := VerifyLog(cfg.File)
+	if err != nil {
+		t.Fatalf("Expected an intact chain across rotated files, got: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 entries verified, got %d", count)
+	}
+	if anchor != hashes[0] {
+		t.Errorf("Expected the chain to start after the removed entry %q, got %q", hashes[0], anchor)
+	}
+}
+
+func TestNewLogger_ContinuesChainAfterRotation(t *testing.T) {
+	cfg := config.AuditConfig{
+		Enabled:   true,
+		File:      filepath.Join(t.TempDir(), "audit.log"),
+		HashChain: true,
+		Rotation:  config.RotationConfig{Compress: true},
+	}
+	l, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	l.Record(Event{Type: EventLogin, Outcome: OutcomeSuccess, User: "alice"})
+	l.closer.(*rotate.Writer).Rotate()
+	l.Close()
+
+	// The current file is empty, so the chain continues from the rotated one
+	l, err = NewLogger(cfg)
+	if err != nil {
+		t.Fatalf("Failed to reopen audit log: %v", err)
+	}
+	l.Record(Event{Type: EventLogout, Outcome: OutcomeSuccess, User: "alice"})
+	l.Close()
+
+	count, anchor, err := VerifyLog(cfg.File)
+	if err != nil || count != 2 || anchor != "" {
+		t.Errorf("Expected a complete chain of 2 entries, got %d (anchor %q): %v", count, anchor, err)
+	}
+}
@@ -6,6 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"otterserve/internal/audit"
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+	"otterserve/internal/requestid"
 )
 
 // Authenticator interface defines authentication operations
@@ -20,6 +25,9 @@ type BasicAuthenticator struct {
 	enabled  bool
 	username string
 	password string
+	lockout  *LockoutTracker
+	totp     *TOTPStore
+	totpReq  bool
 }
 
 // NewBasicAuthenticator creates a new basic authenticator
@@ -36,12 +44,51 @@ func (ba *BasicAuthenticator) IsEnabled() bool {
 	return ba.enabled
 }
 
+// SetLockout enables brute-force protection using the given tracker
+func (ba *BasicAuthenticator) SetLockout(lt *LockoutTracker) {
+	ba.lockout = lt
+}
+
+// SetTOTP requires enrolled users to append a one-time code to their
+// password ("password+123456"); when required is set, users without an
+// enrollment cannot log in at all
+func (ba *BasicAuthenticator) SetTOTP(store *TOTPStore, required bool) {
+	ba.totp = store
+	ba.totpReq = required
+}
+
 // Authenticate validates username and password credentials
 func (ba *BasicAuthenticator) Authenticate(username, password string) bool {
 	if !ba.enabled {
 		return true // Authentication disabled, allow all
 	}
 
+	if ba.totp != nil {
+		return ba.authenticateWithOTP(username, password)
+	}
+	return ba.checkPassword(username, password)
+}
+
+// authenticateWithOTP splits the one-time code off the password and checks
+// it only after the password matched, so recovery codes are not consumed by
+// wrong guesses
+func (ba *BasicAuthenticator) authenticateWithOTP(username, password string) bool {
+	if !ba.totp.Enrolled(username) {
+		return !ba.totpReq && ba.checkPassword(username, password)
+	}
+
+	i := strings.LastIndex(password, "+")
+	if i < 0 {
+		return false
+	}
+	if !ba.checkPassword(username, password[:i]) {
+		return false
+	}
+	return ba.totp.Verify(username, password[i+1:])
+}
+
+// checkPassword compares credentials with the configured user
+func (ba *BasicAuthenticator) checkPassword(username, password string) bool {
 	// Use constant-time comparison to prevent timing attacks
 	usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(ba.username)) == 1
 	passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(ba.password)) == 1
@@ -49,6 +96,100 @@ func (ba *BasicAuthenticator) Authenticate(username, password string) bool {
 	return usernameMatch && passwordMatch
 }
 
+// NewAuthenticatorFromConfig creates every configured authentication method.
+// The default method, used by routes that do not select methods explicitly,
+// is the login form, LDAP, OIDC or Basic (in that order of preference) and
+// falls back to client certificates when they are the only method configured.
+func NewAuthenticatorFromConfig(cfg config.AuthConfig, log logger.Logger) (*Manager, error) {
+	methods := make(map[string]Authenticator)
+
+	var lockout *LockoutTracker
+	if cfg.Lockout.Enabled {
+		lt, err := NewLockoutTracker(cfg.Lockout, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create lockout tracker: %w", err)
+		}
+		lockout = lt
+	}
+
+	basic := NewBasicAuthenticator(cfg.Enabled, cfg.Username, cfg.Password)
+	basic.(*BasicAuthenticator).SetLockout(lockout)
+	if cfg.TOTP.Enabled {
+		store, err := NewTOTPStore(cfg.TOTP.SecretsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load totp secrets: %w", err)
+		}
+		basic.(*BasicAuthenticator).SetTOTP(store, cfg.TOTP.Required)
+	}
+	def := basic
+	if cfg.Enabled && cfg.Username != "" {
+		methods[MethodBasic] = basic
+		methods[MethodPassword] = basic
+	}
+
+	if cfg.ClientCert.Enabled {
+		cert, err := NewClientCertAuthenticator(cfg.ClientCert, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client certificate authenticator: %w", err)
+		}
+		methods[MethodClientCert] = cert
+		if !cfg.Enabled {
+			def = cert
+		}
+	}
+
+	if cfg.OIDC.Enabled {
+		oidc, err := NewOIDCAuthenticator(cfg.OIDC, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create oidc authenticator: %w", err)
+		}
+		methods[MethodOIDC] = oidc
+		def = oidc
+	}
+
+	if cfg.LDAP.Enabled {
+		ldap, err := NewLDAPAuthenticator(cfg.LDAP, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ldap authenticator: %w", err)
+		}
+		ldap.SetLockout(lockout)
+		methods[MethodLDAP] = ldap
+		methods[MethodPassword] = ldap
+		def = ldap
+	}
+
+	if cfg.Form.Enabled {
+		verifier, ok := methods[MethodPassword]
+		if !ok {
+			return nil, fmt.Errorf("form login requires basic or ldap credentials")
+		}
+		form, err := NewFormAuthenticator(cfg.Form, verifier, nil, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create form authenticator: %w", err)
+		}
+		form.SetLockout(lockout)
+		// One-time codes only apply to the local user, not to LDAP accounts
+		if verifier == basic && cfg.TOTP.Enabled {
+			form.SetOTPField(true)
+		}
+		methods[MethodForm] = form
+		def = form
+	}
+
+	m := NewManager(def, methods)
+	if lockout != nil && cfg.Lockout.AdminPath != "" {
+		// The lockout list is only served to authenticated administrators
+		if def.IsEnabled() {
+			m.Handle(cfg.Lockout.AdminPath, def.Middleware(RequireGroups(cfg.Lockout.AdminGroups, lockout.AdminHandler())))
+		} else if log != nil {
+			log.Warn("Lockout admin endpoint not mounted: authentication is disabled", logger.Fields{
+				"path": cfg.Lockout.AdminPath,
+			})
+		}
+	}
+	return m, nil
+}
+
 // Middleware returns an HTTP middleware that enforces basic authentication
 func (ba *BasicAuthenticator) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -61,23 +202,65 @@ func (ba *BasicAuthenticator) Middleware(next http.Handler) http.Handler {
 		// Extract credentials from Authorization header
 		username, password, ok := ba.extractCredentials(r)
 		if !ok {
-			ba.sendUnauthorized(w)
+			ba.sendUnauthorized(w, r)
+			return
+		}
+
+		// Refuse to check passwords while the user or client is locked out
+		ip := remoteIP(r)
+		if wait := ba.lockout.Check(username, ip); wait > 0 {
+			auditLogin(r, MethodBasic, username, audit.OutcomeDenied, "locked out")
+			writeTooManyRequests(w, r, wait)
 			return
 		}
 
 		// Validate credentials
 		if !ba.Authenticate(username, password) {
-			ba.sendUnauthorized(w)
+			auditLogin(r, MethodBasic, username, audit.OutcomeFailure, "invalid credentials")
+			if ba.lockout.Failure(username, ip) {
+				auditLockout(r, MethodBasic, username)
+			}
+			ba.sendUnauthorized(w, r)
 			return
 		}
+		ba.lockout.Success(username)
+		auditLogin(r, MethodBasic, username, audit.OutcomeSuccess, "")
 
 		// Authentication successful, proceed to next handler
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, WithIdentity(r, &Identity{Username: username, Method: MethodBasic}))
 	})
 }
 
+// AuthenticateRequest checks Basic Auth credentials without writing a response
+func (ba *BasicAuthenticator) AuthenticateRequest(r *http.Request) (*Identity, bool) {
+	if !ba.enabled {
+		return nil, false
+	}
+	username, password, ok := basicCredentials(r)
+	if !ok || ba.lockout.Check(username, remoteIP(r)) > 0 {
+		return nil, false
+	}
+	// Failures are recorded by Middleware, which answers the request when no
+	// combined method accepts it
+	if !ba.Authenticate(username, password) {
+		return nil, false
+	}
+	ba.lockout.Success(username)
+	return &Identity{Username: username, Method: MethodBasic}, true
+}
+
 // extractCredentials extracts username and password from Basic Auth header
 func (ba *BasicAuthenticator) extractCredentials(r *http.Request) (username, password string, ok bool) {
+	return basicCredentials(r)
+}
+
+// sendUnauthorized sends a 401 Unauthorized response with WWW-Authenticate header
+func (ba *BasicAuthenticator) sendUnauthorized(w http.ResponseWriter, r *http.Request) {
+	writeUnauthorized(w, r)
+}
+
+// basicCredentials extracts username and password from a Basic Auth header
+func basicCredentials(r *http.Request) (username, password string, ok bool) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
 		return "", "", false
@@ -106,12 +289,36 @@ func (ba *BasicAuthenticator) extractCredentials(r *http.Request) (username, pas
 	return parts[0], parts[1], true
 }
 
-// sendUnauthorized sends a 401 Unauthorized response with WWW-Authenticate header
-func (ba *BasicAuthenticator) sendUnauthorized(w http.ResponseWriter) {
+// writeUnauthorized sends a 401 Unauthorized response with a Basic challenge
+func writeUnauthorized(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("WWW-Authenticate", `Basic realm="Otter Serve Service"`)
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusUnauthorized)
-	fmt.Fprint(w, "401 Unauthorized\n")
+	requestid.Error(w, r, "401 Unauthorized", http.StatusUnauthorized)
+}
+
+// RequireGroups returns a middleware that only admits identities belonging to
+// at least one of the given groups. An empty group list admits everyone.
+func RequireGroups(groups []string, next http.Handler) http.Handler {
+	if len(groups) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := IdentityFromContext(r.Context())
+		if !ok || !id.InAnyGroup(groups) {
+			event := audit.Event{
+				Type:    audit.EventAccessDenied,
+				Outcome: audit.OutcomeDenied,
+				Reason:  "not in required groups",
+			}
+			if ok {
+				event.User, event.AuthMethod = id.Username, id.Method
+			}
+			audit.Record(r, event)
+			requestid.Error(w, r, "403 Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 // NoOpAuthenticator is an authenticator that always allows access
@@ -1,11 +1,14 @@
 package auth
 
 import (
+	"bytes"
 	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"otterserve/internal/audit"
 )
 
 func TestNewBasicAuthenticator(t *testing.T) {
@@ -325,4 +328,42 @@ func TestNoOpAuthenticator(t *testing.T) {
 	if rr.Header().Get("X-Handler-Called") != "true" {
 		t.Error("Expected handler to be called")
 	}
-}
\ No newline at end of file
+}
+
+func TestRequireGroups_Audit(t *testing.T) {
+	var buf bytes.Buffer
+	auditor := audit.NewWriterLogger(&buf, false, "")
+	handler := RequireGroups([]string{"admins"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/reports/q1.pdf", nil)
+	req = WithIdentity(req, &Identity{Username: "bob", Method: MethodBasic, Groups: []string{"staff"}})
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, audit.WithLogger(req, auditor, "/reports/"))
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403, got %d", rr.Code)
+	}
+	out := buf.String()
+	for _, want := range []string{`"event":"access_denied"`, `"outcome":"denied"`, `"user":"bob"`, `"route":"/reports/"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %s in audit entry, got: %s", want, out)
+		}
+	}
+}
+
+func TestBasicAuthenticator_FailureMetric(t *testing.T) {
+	failures := authFailures.WithLabelValues(MethodBasic, audit.OutcomeFailure)
+	before := failures.Value()
+
+	authenticator := NewBasicAuthenticator(true, "admin", "secret")
+	handler := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("admin", "wrong")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if failures.Value() != before+1 {
+		t.Errorf("Expected the failure to be counted, got %v after %v", failures.Value(), before)
+	}
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// BER class and form bits used by LDAP (RFC 4511 section 5.1)
+const (
+	berClassApplication = 0x40
+	berClassContext     = 0x80
+	berConstructed      = 0x20
+
+	berTagBoolean     = 0x01
+	berTagInteger     = 0x02
+	berTagOctetString = 0x04
+	berTagEnumerated  = 0x0a
+	berTagSequence    = 0x10
+
+	berMaxLength = 16 << 20
+)
+
+// berPacket is a decoded BER element; constructed elements carry children
+type berPacket struct {
+	tag      byte
+	value    []byte
+	children []*berPacket
+}
+
+// newBERPrimitive creates a primitive element with raw content
+func newBERPrimitive(tag byte, value []byte) *berPacket {
+	return &berPacket{tag: tag, value: value}
+}
+
+// newBERConstructed creates a constructed element from children
+func newBERConstructed(tag byte, children ...*berPacket) *berPacket {
+	return &berPacket{tag: tag | berConstructed, children: children}
+}
+
+// berString encodes an OCTET STRING
+func berString(s string) *berPacket {
+	return newBERPrimitive(berTagOctetString, []byte(s))
+}
+
+// berInt encodes an INTEGER (or ENUMERATED when tag says so)
+func berInt(tag byte, v int64) *berPacket {
+	var out []byte
+	for {
+		out = append([]byte{byte(v)}, out...)
+		v >>= 8
+		if (v == 0 && out[0]&0x80 == 0) || (v == -1 && out[0]&0x80 != 0) {
+			break
+		}
+	}
+	return newBERPrimitive(tag, out)
+}
+
+// berBool encodes a BOOLEAN
+func berBool(b bool) *berPacket {
+	if b {
+		return newBERPrimitive(berTagBoolean, []byte{0xff})
+	}
+	return newBERPrimitive(berTagBoolean, []byte{0x00})
+}
+
+// constructed reports whether the element has the constructed bit set
+func (p *berPacket) constructed() bool {
+	return p.tag&berConstructed != 0
+}
+
+// child returns the i-th child or nil
+func (p *berPacket) child(i int) *berPacket {
+	if i < 0 || i >= len(p.children) {
+		return nil
+	}
+	return p.children[i]
+}
+
+// int decodes the element content as a two's-complement integer
+func (p *berPacket) int() int64 {
+	var v int64
+	for i, b := range p.value {
+		if i == 0 && b&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(b)
+	}
+	return v
+}
+
+// str returns the element content as a string
+func (p *berPacket) str() string {
+	return string(p.value)
+}
+
+// bytes serializes the element using definite-length encoding
+func (p *berPacket) bytes() []byte {
+	content := p.value
+	if p.constructed() {
+		content = nil
+		for _, c := range p.children {
+			content = append(content, c.bytes()...)
+		}
+	}
+
+	out := []byte{p.tag}
+	out = append(out, berLength(len(content))...)
+	return append(out, content...)
+}
+
+// berLength encodes a definite length
+func berLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var digits []byte
+	for n > 0 {
+		digits = append([]byte{byte(n)}, digits...)
+		n >>= 8
+	}
+	return append([]byte{0x80 | byte(len(digits))}, digits...)
+}
+
+// readBERPacket reads one complete element from the stream
+func readBERPacket(r *bufio.Reader) (*berPacket, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tag&0x1f == 0x1f {
+		return nil, fmt.Errorf("ber: multi-byte tags are not supported")
+	}
+
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	length := int(first)
+	if first&0x80 != 0 {
+		count := int(first & 0x7f)
+		if count == 0 || count > 4 {
+			return nil, fmt.Errorf("ber: unsupported length encoding")
+		}
+		length = 0
+		for i := 0; i < count; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > berMaxLength {
+		return nil, fmt.Errorf("ber: element too large (%d bytes)", length)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	return parseBERContent(tag, content)
+}
+
+// parseBERContent builds an element, decoding children of constructed types
+func parseBERContent(tag byte, content []byte) (*berPacket, error) {
+	p := &berPacket{tag: tag, value: content}
+	if !p.constructed() {
+		return p, nil
+	}
+
+	r := bufio.NewReader(bytes.NewReader(content))
+	for {
+		c, err := readBERPacket(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, c)
+	}
+	p.value = nil
+	return p, nil
+}
+
+// unexpectedEOF reports a truncated element; a clean EOF is only valid
+// before the tag byte
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"otterserve/internal/audit"
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+	"otterserve/internal/requestid"
+)
+
+// TLSConfigurer is implemented by authenticators that need to adjust the
+// server TLS configuration, for example to request client certificates
+type TLSConfigurer interface {
+	ConfigureTLS(cfg *tls.Config)
+}
+
+// RequestAuthenticator is implemented by authenticators that can check a
+// request without writing a response, which allows several methods to be
+// combined on one route
+type RequestAuthenticator interface {
+	AuthenticateRequest(r *http.Request) (*Identity, bool)
+}
+
+// ClientCertAuthenticator authenticates TLS client certificates against a CA
+// bundle and an optional certificate revocation list
+type ClientCertAuthenticator struct {
+	roots         *x509.CertPool
+	caCerts       []*x509.Certificate
+	usernameField string
+	crlFile       string
+	logger        logger.Logger
+
+	mu      sync.Mutex
+	crlMod  time.Time
+	revoked map[string]bool
+}
+
+// NewClientCertAuthenticator creates a new client certificate authenticator
+func NewClientCertAuthenticator(cfg config.ClientCertConfig, log logger.Logger) (*ClientCertAuthenticator, error) {
+	data, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client_cert ca_file: %w", err)
+	}
+
+	roots := x509.NewCertPool()
+	var caCerts []*x509.Certificate
+	for _, der := range pemOrDERBlocks(data, "CERTIFICATE") {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client_cert ca_file %s: %w", cfg.CAFile, err)
+		}
+		roots.AddCert(cert)
+		caCerts = append(caCerts, cert)
+	}
+	if len(caCerts) == 0 {
+		return nil, fmt.Errorf("no certificates found in client_cert ca_file %s", cfg.CAFile)
+	}
+
+	field := cfg.UsernameFrom
+	if field == "" {
+		field = "cn"
+	}
+	if log == nil {
+		log = logger.NewLogger(logger.ErrorLevel, nil)
+	}
+
+	ca := &ClientCertAuthenticator{
+		roots:         roots,
+		caCerts:       caCerts,
+		usernameField: field,
+		crlFile:       cfg.CRLFile,
+		logger:        log,
+	}
+
+	if ca.crlFile != "" {
+		if err := ca.reloadCRL(); err != nil {
+			return nil, err
+		}
+	}
+
+	return ca, nil
+}
+
+// IsEnabled returns whether authentication is enabled
+func (ca *ClientCertAuthenticator) IsEnabled() bool {
+	return true
+}
+
+// Authenticate always fails: client certificates do not use passwords
+func (ca *ClientCertAuthenticator) Authenticate(username, password string) bool {
+	return false
+}
+
+// ConfigureTLS asks clients for a certificate signed by the configured CA
+// without making one mandatory, so routes can still fall back to passwords
+func (ca *ClientCertAuthenticator) ConfigureTLS(cfg *tls.Config) {
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	cfg.ClientCAs = ca.roots
+}
+
+// Middleware returns an HTTP middleware that requires a valid client certificate
+func (ca *ClientCertAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := ca.AuthenticateRequest(r)
+		if !ok {
+			auditLogin(r, MethodClientCert, "", audit.OutcomeFailure, "no valid client certificate")
+			requestid.Error(w, r, "401 Unauthorized: valid client certificate required", http.StatusUnauthorized)
+			return
+		}
+		auditLogin(r, MethodClientCert, id.Username, audit.OutcomeSuccess, "")
+		next.ServeHTTP(w, WithIdentity(r, id))
+	})
+}
+
+// AuthenticateRequest verifies the peer certificate presented on the connection
+func (ca *ClientCertAuthenticator) AuthenticateRequest(r *http.Request) (*Identity, bool) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, false
+	}
+
+	leaf := r.TLS.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         ca.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		ca.logger.Warn("Client certificate rejected", logger.Fields{
+			"request_id": requestid.FromRequest(r),
+			"subject":    leaf.Subject.String(),
+			"error":      err.Error(),
+		})
+		return nil, false
+	}
+
+	if ca.isRevoked(chains[0]) {
+		ca.logger.Warn("Client certificate revoked", logger.Fields{
+			"request_id": requestid.FromRequest(r),
+			"subject":    leaf.Subject.String(),
+			"serial":     leaf.SerialNumber.String(),
+		})
+		return nil, false
+	}
+
+	username := certificateUsername(leaf, ca.usernameField)
+	if username == "" {
+		return nil, false
+	}
+
+	return &Identity{Username: username, Method: MethodClientCert}, true
+}
+
+// isRevoked checks every certificate of the chain except the root against the CRL
+func (ca *ClientCertAuthenticator) isRevoked(chain []*x509.Certificate) bool {
+	if ca.crlFile == "" {
+		return false
+	}
+
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	if info, err := os.Stat(ca.crlFile); err == nil && !info.ModTime().Equal(ca.crlMod) {
+		if err := ca.reloadCRLLocked(); err != nil {
+			ca.logger.Error("Failed to reload CRL, keeping previous list", logger.Fields{
+				"file":  ca.crlFile,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	for i := 0; i < len(chain)-1; i++ {
+		if ca.revoked[revocationKey(chain[i])] {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadCRL reads the CRL file, verifying it was signed by a trusted CA
+func (ca *ClientCertAuthenticator) reloadCRL() error {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	return ca.reloadCRLLocked()
+}
+
+func (ca *ClientCertAuthenticator) reloadCRLLocked() error {
+	info, err := os.Stat(ca.crlFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat crl_file: %w", err)
+	}
+
+	data, err := os.ReadFile(ca.crlFile)
+	if err != nil {
+		return fmt.Errorf("failed to read crl_file: %w", err)
+	}
+
+	revoked := make(map[string]bool)
+	for _, der := range pemOrDERBlocks(data, "X509 CRL") {
+		crl, err := x509.ParseRevocationList(der)
+		if err != nil {
+			return fmt.Errorf("failed to parse crl_file %s: %w", ca.crlFile, err)
+		}
+		if !ca.signedByTrustedCA(crl) {
+			return fmt.Errorf("crl_file %s is not signed by a certificate in ca_file", ca.crlFile)
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			revoked[string(crl.RawIssuer)+"/"+entry.SerialNumber.String()] = true
+		}
+	}
+
+	ca.revoked = revoked
+	ca.crlMod = info.ModTime()
+	return nil
+}
+
+// signedByTrustedCA reports whether a CA from the bundle issued the CRL
+func (ca *ClientCertAuthenticator) signedByTrustedCA(crl *x509.RevocationList) bool {
+	for _, cert := range ca.caCerts {
+		if bytes.Equal(cert.RawSubject, crl.RawIssuer) && crl.CheckSignatureFrom(cert) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// pemOrDERBlocks returns the DER contents of all PEM blocks of the given
+// type, or the input itself when it is not PEM encoded
+func pemOrDERBlocks(data []byte, blockType string) [][]byte {
+	var blocks [][]byte
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == blockType {
+			blocks = append(blocks, block.Bytes)
+		}
+	}
+	if len(blocks) == 0 && len(bytes.TrimSpace(data)) > 0 && !bytes.Contains(data, []byte("-----BEGIN")) {
+		blocks = append(blocks, data)
+	}
+	return blocks
+}
+
+// revocationKey identifies a certificate by issuer and serial number
+func revocationKey(cert *x509.Certificate) string {
+	return string(cert.RawIssuer) + "/" + cert.SerialNumber.String()
+}
+
+// certificateUsername extracts the username from the configured certificate field
+func certificateUsername(cert *x509.Certificate, field string) string {
+	switch field {
+	case "dns":
+		if len(cert.DNSNames) > 0 {
+			return cert.DNSNames[0]
+		}
+	case "email":
+		if len(cert.EmailAddresses) > 0 {
+			return cert.EmailAddresses[0]
+		}
+	case "uri":
+		if len(cert.URIs) > 0 {
+			return cert.URIs[0].String()
+		}
+	default:
+		return cert.Subject.CommonName
+	}
+	return ""
+}
@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"otterserve/internal/config"
+)
+
+// testCA issues client certificates and revocation lists for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Client CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse CA certificate: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "client-ca.pem")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+func (c *testCA) issue(t *testing.T, serial int64, cn, email string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if email != "" {
+		template.EmailAddresses = []string{email}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, c.cert, &key.PublicKey, c.key)
+	if err != nil {
+		t.Fatalf("Failed to create client certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (c *testCA) writeCRL(t *testing.T, file string, number int64, serials ...int64) {
+	var entries []x509.RevocationListEntry
+	for _, serial := range serials {
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now().Add(-time.Minute),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(number),
+		ThisUpdate:                time.Now().Add(-time.Minute),
+		NextUpdate:                time.Now().Add(time.Hour),
+		RevokedCertificateEntries: entries,
+	}, c.cert, c.key)
+	if err != nil {
+		t.Fatalf("Failed to create CRL: %v", err)
+	}
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0644); err != nil {
+		t.Fatalf("Failed to write CRL file: %v", err)
+	}
+}
+
+// newClientCertServer starts a TLS server guarded by the authenticator that
+// echoes the authenticated identity
+func newClientCertServer(t *testing.T, a Authenticator) *httptest.Server {
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := IdentityFromContext(r.Context())
+		fmt.Fprintf(w, "%s:%s", id.Method, id.Username)
+	}))
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.TLS = &tls.Config{}
+	if configurer, ok := a.(TLSConfigurer); ok {
+		configurer.ConfigureTLS(srv.TLS)
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func clientWithCertificate(srv *httptest.Server, cert *tls.Certificate) *http.Client {
+	transport := srv.Client().Transport.(*http.Transport).Clone()
+	if cert != nil {
+		transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+	}
+	return &http.Client{Transport: transport}
+}
+
+func getWithClient(t *testing.T, client *http.Client, req *http.Request) (int, string) {
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body := make([]byte, 512)
+	n, _ := resp.Body.Read(body)
+	return resp.StatusCode, string(body[:n])
+}
+
+func TestClientCertAuthenticator(t *testing.T) {
+	ca := newTestCA(t)
+	cca, err := NewClientCertAuthenticator(config.ClientCertConfig{Enabled: true, CAFile: ca.file}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	srv := newClientCertServer(t, cca)
+
+	cert := ca.issue(t, 10, "alice", "")
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	status, body := getWithClient(t, clientWithCertificate(srv, &cert), req)
+	if status != http.StatusOK || body != "client_cert:alice" {
+		t.Errorf("Expected 200 client_cert:alice, got %d %q", status, body)
+	}
+
+	req, _ = http.NewRequest("GET", srv.URL, nil)
+	if status, _ := getWithClient(t, clientWithCertificate(srv, nil), req); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without certificate, got %d", status)
+	}
+}
+
+func TestClientCertAuthenticator_UntrustedCA(t *testing.T) {
+	ca := newTestCA(t)
+	other := newTestCA(t)
+	cca, err := NewClientCertAuthenticator(config.ClientCertConfig{Enabled: true, CAFile: ca.file}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	// Check verification directly; the TLS handshake would reject this earlier
+	cert := other.issue(t, 10, "mallory", "")
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	req := httptest.NewRequest("GET", "/", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+	if _, ok := cca.AuthenticateRequest(req); ok {
+		t.Error("Expected certificate from untrusted CA to be rejected")
+	}
+}
+
+func TestClientCertAuthenticator_CRL(t *testing.T) {
+	ca := newTestCA(t)
+	crlFile := filepath.Join(t.TempDir(), "client.crl")
+	ca.writeCRL(t, crlFile, 1)
+
+	cca, err := NewClientCertAuthenticator(config.ClientCertConfig{Enabled: true, CAFile: ca.file, CRLFile: crlFile}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	srv := newClientCertServer(t, cca)
+
+	cert := ca.issue(t, 42, "bob", "")
+	client := clientWithCertificate(srv, &cert)
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	if status, _ := getWithClient(t, client, req); status != http.StatusOK {
+		t.Fatalf("Expected 200 before revocation, got %d", status)
+	}
+
+	// Revoke the certificate; the CRL is reloaded once its mtime changes
+	ca.writeCRL(t, crlFile, 2, 42)
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(crlFile, future, future); err != nil {
+		t.Fatalf("Failed to touch CRL: %v", err)
+	}
+
+	req, _ = http.NewRequest("GET", srv.URL, nil)
+	if status, _ := getWithClient(t, client, req); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 after revocation, got %d", status)
+	}
+}
+
+func TestClientCertAuthenticator_CRLFromOtherCA(t *testing.T) {
+	ca := newTestCA(t)
+	other := newTestCA(t)
+	crlFile := filepath.Join(t.TempDir(), "client.crl")
+	other.writeCRL(t, crlFile, 1)
+
+	_, err := NewClientCertAuthenticator(config.ClientCertConfig{Enabled: true, CAFile: ca.file, CRLFile: crlFile}, nil)
+	if err == nil {
+		t.Error("Expected CRL signed by an unknown CA to be rejected")
+	}
+}
+
+func TestClientCertAuthenticator_UsernameFrom(t *testing.T) {
+	ca := newTestCA(t)
+	cca, err := NewClientCertAuthenticator(config.ClientCertConfig{Enabled: true, CAFile: ca.file, UsernameFrom: "email"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	for _, tt := range []struct {
+		email    string
+		expected string
+		ok       bool
+	}{
+		{"carol@example.com", "carol@example.com", true},
+		{"", "", false},
+	} {
+		cert := ca.issue(t, 7, "carol", tt.email)
+		leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+		req := httptest.NewRequest("GET", "/", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+
+		id, ok := cca.AuthenticateRequest(req)
+		if ok != tt.ok {
+			t.Errorf("email %q: expected ok=%v, got %v", tt.email, tt.ok, ok)
+		}
+		if ok && id.Username != tt.expected {
+			t.Errorf("Expected username %q, got %q", tt.expected, id.Username)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// cookieCodec seals values into authenticated, encrypted cookie payloads
+type cookieCodec struct {
+	aead cipher.AEAD
+}
+
+// newCookieCodec derives an AES-256-GCM key from the configured secret
+func newCookieCodec(secret string) (*cookieCodec, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("cookie secret cannot be empty")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &cookieCodec{aead: aead}, nil
+}
+
+// Encode serializes v as JSON and seals it, binding it to the cookie name
+func (c *cookieCodec) Encode(name string, v interface{}) (string, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cookie value: %w", err)
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decode opens a sealed cookie value and unmarshals it into v
+func (c *cookieCodec) Decode(name, value string, v interface{}) error {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("invalid cookie encoding: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return fmt.Errorf("cookie value too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(name))
+	if err != nil {
+		return fmt.Errorf("cookie authentication failed")
+	}
+
+	return json.Unmarshal(plaintext, v)
+}
+
+// randomToken returns a URL-safe random string with n bytes of entropy
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,463 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"otterserve/internal/audit"
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+	"otterserve/internal/requestid"
+)
+
+// Form login defaults used when the configuration leaves a value unset
+const (
+	defaultFormLoginPath  = "/login"
+	defaultFormLogoutPath = "/logout"
+	defaultFormCookieName = "otterserve_login"
+	defaultFormSessionTTL = 8 * time.Hour
+	defaultFormTitle      = "Otter Serve"
+
+	// csrfHeader and csrfField carry the CSRF token on state-changing requests
+	csrfHeader = "X-CSRF-Token"
+	csrfField  = "csrf_token"
+)
+
+// defaultLoginTemplate is used unless a custom template file is configured
+const defaultLoginTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; background: #f4f5f7; display: flex; justify-content: center; padding-top: 10vh; }
+form { background: #fff; padding: 2em; border-radius: 6px; box-shadow: 0 1px 4px rgba(0,0,0,.15); min-width: 18em; }
+label { display: block; margin-top: 1em; }
+input[type=text], input[type=password] { width: 100%; box-sizing: border-box; padding: .4em; }
+button { margin-top: 1.5em; width: 100%; padding: .5em; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+{{if .Logout}}
+<form method="post" action="{{.Action}}">
+<h1>{{.Title}}</h1>
+<p>Signed in as {{.Username}}.</p>
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<button type="submit">Sign out</button>
+</form>
+{{else}}
+<form method="post" action="{{.Action}}">
+<h1>{{.Title}}</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<input type="hidden" name="return_to" value="{{.ReturnTo}}">
+<label for="username">Username</label>
+<input type="text" id="username" name="username" autocomplete="username" value="{{.Username}}" required autofocus>
+<label for="password">Password</label>
+<input type="password" id="password" name="password" autocomplete="current-password" required>
+{{if .OTP}}<label for="otp">Authentication code</label>
+<input type="text" id="otp" name="otp" autocomplete="one-time-code" inputmode="numeric">
+{{end}}<button type="submit">Sign in</button>
+</form>
+{{end}}
+</body>
+</html>
+`
+
+// loginPage holds the values available to the login page template
+type loginPage struct {
+	Title     string
+	Action    string
+	Error     string
+	Username  string
+	ReturnTo  string
+	CSRFToken string
+	OTP       bool
+	Logout    bool
+}
+
+// identityVerifier is implemented by password authenticators that can
+// describe the user beyond a yes/no answer, such as LDAP group membership
+type identityVerifier interface {
+	authenticateIdentity(username, password string) (*Identity, bool)
+}
+
+// FormAuthenticator authenticates browsers with an HTML login form and a
+// server-side session referenced by an HttpOnly cookie
+type FormAuthenticator struct {
+	verifier     Authenticator
+	sessions     SessionStore
+	template     *template.Template
+	title        string
+	loginPath    string
+	logoutPath   string
+	cookieName   string
+	ttl          time.Duration
+	secureCookie bool
+	otpField     bool
+	lockout      *LockoutTracker
+	logger       logger.Logger
+}
+
+// NewFormAuthenticator creates a form login that checks credentials with the
+// given password authenticator
+func NewFormAuthenticator(cfg config.FormConfig, verifier Authenticator, sessions SessionStore, log logger.Logger) (*FormAuthenticator, error) {
+	tmplText := defaultLoginTemplate
+	if cfg.Template != "" {
+		data, err := os.ReadFile(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read login template: %w", err)
+		}
+		tmplText = string(data)
+	}
+	tmpl, err := template.New("login").Parse(tmplText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse login template: %w", err)
+	}
+
+	if sessions == nil {
+		sessions = NewMemorySessionStore()
+	}
+	if log == nil {
+		log = logger.NewLogger(logger.ErrorLevel, nil)
+	}
+
+	fa := &FormAuthenticator{
+		verifier:     verifier,
+		sessions:     sessions,
+		template:     tmpl,
+		title:        cfg.Title,
+		loginPath:    cfg.LoginPath,
+		logoutPath:   cfg.LogoutPath,
+		cookieName:   cfg.CookieName,
+		ttl:          cfg.SessionTTL,
+		secureCookie: cfg.SecureCookie,
+		logger:       log,
+	}
+	if fa.title == "" {
+		fa.title = defaultFormTitle
+	}
+	if fa.loginPath == "" {
+		fa.loginPath = defaultFormLoginPath
+	}
+	if fa.logoutPath == "" {
+		fa.logoutPath = defaultFormLogoutPath
+	}
+	if fa.cookieName == "" {
+		fa.cookieName = defaultFormCookieName
+	}
+	if fa.ttl == 0 {
+		fa.ttl = defaultFormSessionTTL
+	}
+
+	return fa, nil
+}
+
+// SetLockout enables brute-force protection using the given tracker
+func (fa *FormAuthenticator) SetLockout(lt *LockoutTracker) {
+	fa.lockout = lt
+}
+
+// SetOTPField shows a one-time code field, which is passed to the password
+// authenticator using the "password+code" convention
+func (fa *FormAuthenticator) SetOTPField(enabled bool) {
+	fa.otpField = enabled
+}
+
+// IsEnabled returns whether authentication is enabled
+func (fa *FormAuthenticator) IsEnabled() bool {
+	return true
+}
+
+// Authenticate validates credentials with the underlying password authenticator
+func (fa *FormAuthenticator) Authenticate(username, password string) bool {
+	return fa.verifier.Authenticate(username, password)
+}
+
+// Middleware returns an HTTP middleware that requires a login session and
+// sends browsers without one to the login page
+func (fa *FormAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, ok := fa.session(r)
+		if ok {
+			if !isSafeMethod(r.Method) && !fa.validCSRF(r, session) {
+				requestid.Error(w, r, "403 Forbidden: missing or invalid CSRF token", http.StatusForbidden)
+				return
+			}
+			id := session.Identity
+			next.ServeHTTP(w, WithIdentity(r, &id))
+			return
+		}
+
+		// Only safe requests can be replayed after the login page
+		if !isSafeMethod(r.Method) {
+			requestid.Error(w, r, "401 Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		target := appendQuery(fa.loginPath, url.Values{"return_to": {r.URL.RequestURI()}})
+		http.Redirect(w, r, target, http.StatusSeeOther)
+	})
+}
+
+// AuthenticateRequest validates the session cookie and, for state-changing
+// requests, the CSRF token
+func (fa *FormAuthenticator) AuthenticateRequest(r *http.Request) (*Identity, bool) {
+	session, ok := fa.session(r)
+	if !ok || (!isSafeMethod(r.Method) && !fa.validCSRF(r, session)) {
+		return nil, false
+	}
+	id := session.Identity
+	return &id, true
+}
+
+// Handlers returns the login and logout endpoints
+func (fa *FormAuthenticator) Handlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		fa.loginPath:  http.HandlerFunc(fa.handleLogin),
+		fa.logoutPath: http.HandlerFunc(fa.handleLogout),
+	}
+}
+
+// handleLogin shows the login form and processes submitted credentials
+func (fa *FormAuthenticator) handleLogin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		fa.renderLogin(w, r, http.StatusOK, "", "", safeReturnTo(r.URL.Query().Get("return_to")))
+	case http.MethodPost:
+		fa.processLogin(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		requestid.Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// processLogin verifies the login form and starts a session
+func (fa *FormAuthenticator) processLogin(w http.ResponseWriter, r *http.Request) {
+	username := r.PostFormValue("username")
+	password := r.PostFormValue("password")
+	if otp := r.PostFormValue("otp"); fa.otpField && otp != "" {
+		password += "+" + otp
+	}
+	returnTo := safeReturnTo(r.PostFormValue("return_to"))
+
+	// Login CSRF: the form token must match the cookie set with the form
+	cookie, err := r.Cookie(fa.loginCSRFCookie())
+	if err != nil || !tokensEqual(cookie.Value, r.PostFormValue(csrfField)) {
+		fa.renderLogin(w, r, http.StatusForbidden, "Your login form expired, please try again.", username, returnTo)
+		return
+	}
+
+	ip := remoteIP(r)
+	if wait := fa.lockout.Check(username, ip); wait > 0 {
+		auditLogin(r, MethodForm, username, audit.OutcomeDenied, "locked out")
+		w.Header().Set("Retry-After", retryAfter(wait))
+		fa.renderLogin(w, r, http.StatusTooManyRequests, "Too many failed login attempts, please try again later.", username, returnTo)
+		return
+	}
+
+	id, ok := fa.verify(username, password)
+	if !ok {
+		auditLogin(r, MethodForm, username, audit.OutcomeFailure, "invalid credentials")
+		if fa.lockout.Failure(username, ip) {
+			auditLockout(r, MethodForm, username)
+		}
+		fa.logger.Warn("Form login failed", logger.Fields{
+			"request_id": requestid.FromRequest(r),
+			"username":   username,
+			"client_ip":  ip,
+		})
+		fa.renderLogin(w, r, http.StatusUnauthorized, "Invalid username or password.", username, returnTo)
+		return
+	}
+	fa.lockout.Success(username)
+	auditLogin(r, MethodForm, id.Username, audit.OutcomeSuccess, "")
+
+	session, err := fa.sessions.Create(*id, fa.ttl)
+	if err != nil {
+		fa.logger.Error("Failed to create login session", logger.Fields{
+			"request_id": requestid.FromRequest(r),
+			"error":      err.Error(),
+		})
+		requestid.Error(w, r, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fa.setCookie(w, r, &http.Cookie{
+		Name:     fa.cookieName,
+		Value:    session.ID,
+		Path:     "/",
+		Expires:  session.Expires,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	// The CSRF token is readable by scripts so they can echo it in a header
+	fa.setCookie(w, r, &http.Cookie{
+		Name:     fa.cookieName + "_csrf",
+		Value:    session.CSRFToken,
+		Path:     "/",
+		Expires:  session.Expires,
+		SameSite: http.SameSiteStrictMode,
+	})
+	fa.clearCookie(w, r, fa.loginCSRFCookie(), fa.loginPath)
+
+	fa.logger.Info("Form login succeeded", logger.Fields{
+		"request_id": requestid.FromRequest(r),
+		"username":   id.Username,
+		"client_ip":  ip,
+	})
+	http.Redirect(w, r, returnTo, http.StatusSeeOther)
+}
+
+// handleLogout shows a sign-out button (GET) and ends the session (POST)
+func (fa *FormAuthenticator) handleLogout(w http.ResponseWriter, r *http.Request) {
+	session, ok := fa.session(r)
+	if !ok {
+		http.Redirect(w, r, fa.loginPath, http.StatusSeeOther)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		fa.render(w, http.StatusOK, loginPage{
+			Title:     fa.title,
+			Action:    fa.logoutPath,
+			Username:  session.Identity.Username,
+			CSRFToken: session.CSRFToken,
+			Logout:    true,
+		})
+	case http.MethodPost:
+		if !fa.validCSRF(r, session) {
+			requestid.Error(w, r, "missing or invalid CSRF token", http.StatusForbidden)
+			return
+		}
+		fa.sessions.Delete(session.ID)
+		audit.Record(r, audit.Event{
+			Type:       audit.EventLogout,
+			Outcome:    audit.OutcomeSuccess,
+			User:       session.Identity.Username,
+			AuthMethod: MethodForm,
+		})
+		fa.clearCookie(w, r, fa.cookieName, "/")
+		fa.clearCookie(w, r, fa.cookieName+"_csrf", "/")
+		fa.logger.Info("Form logout", logger.Fields{
+			"request_id": requestid.FromRequest(r),
+			"username":   session.Identity.Username,
+		})
+		http.Redirect(w, r, fa.loginPath, http.StatusSeeOther)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		requestid.Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify checks credentials, keeping group information when available
+func (fa *FormAuthenticator) verify(username, password string) (*Identity, bool) {
+	if username == "" || password == "" {
+		return nil, false
+	}
+
+	if iv, ok := fa.verifier.(identityVerifier); ok {
+		id, ok := iv.authenticateIdentity(username, password)
+		if !ok {
+			return nil, false
+		}
+		return &Identity{Username: id.Username, Method: MethodForm, Groups: id.Groups}, true
+	}
+
+	if !fa.verifier.Authenticate(username, password) {
+		return nil, false
+	}
+	return &Identity{Username: username, Method: MethodForm}, true
+}
+
+// renderLogin shows the login form with a fresh login CSRF token
+func (fa *FormAuthenticator) renderLogin(w http.ResponseWriter, r *http.Request, status int, message, username, returnTo string) {
+	token, err := randomToken(32)
+	if err != nil {
+		requestid.Error(w, r, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	fa.setCookie(w, r, &http.Cookie{
+		Name:     fa.loginCSRFCookie(),
+		Value:    token,
+		Path:     fa.loginPath,
+		MaxAge:   int((30 * time.Minute).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	fa.render(w, status, loginPage{
+		Title:     fa.title,
+		Action:    fa.loginPath,
+		Error:     message,
+		Username:  username,
+		ReturnTo:  returnTo,
+		CSRFToken: token,
+		OTP:       fa.otpField,
+	})
+}
+
+func (fa *FormAuthenticator) render(w http.ResponseWriter, status int, page loginPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+	if err := fa.template.Execute(w, page); err != nil {
+		fa.logger.Error("Failed to render login page", logger.Fields{
+			"error": err.Error(),
+		})
+	}
+}
+
+// session returns the valid session referenced by the request cookie
+func (fa *FormAuthenticator) session(r *http.Request) (*Session, bool) {
+	cookie, err := r.Cookie(fa.cookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, false
+	}
+	return fa.sessions.Get(cookie.Value)
+}
+
+// validCSRF checks the CSRF token sent in a header or form field
+func (fa *FormAuthenticator) validCSRF(r *http.Request, session *Session) bool {
+	token := r.Header.Get(csrfHeader)
+	if token == "" {
+		token = r.PostFormValue(csrfField)
+	}
+	return tokensEqual(token, session.CSRFToken)
+}
+
+func (fa *FormAuthenticator) loginCSRFCookie() string {
+	return fa.cookieName + "_form"
+}
+
+func (fa *FormAuthenticator) setCookie(w http.ResponseWriter, r *http.Request, c *http.Cookie) {
+	c.Secure = fa.secureCookie || r.TLS != nil
+	http.SetCookie(w, c)
+}
+
+func (fa *FormAuthenticator) clearCookie(w http.ResponseWriter, r *http.Request, name, path string) {
+	fa.setCookie(w, r, &http.Cookie{Name: name, Value: "", Path: path, MaxAge: -1})
+}
+
+// isSafeMethod reports whether the HTTP method does not change state
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// tokensEqual compares two non-empty tokens in constant time
+func tokensEqual(a, b string) bool {
+	return a != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
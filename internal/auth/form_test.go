@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"otterserve/internal/config"
+)
+
+var csrfInput = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// newFormTestServer serves a protected route and the form endpoints
+func newFormTestServer(t *testing.T, fa *FormAuthenticator) (*httptest.Server, *http.Client) {
+	mux := http.NewServeMux()
+	for path, h := range fa.Handlers() {
+		mux.Handle(path, h)
+	}
+	mux.Handle("/files/", fa.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := IdentityFromContext(r.Context())
+		io.WriteString(w, r.Method+" as "+id.Username)
+	})))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	jar, _ := cookiejar.New(nil)
+	return srv, &http.Client{Jar: jar}
+}
+
+func newTestFormAuthenticator(t *testing.T, cfg config.FormConfig) *FormAuthenticator {
+	fa, err := NewFormAuthenticator(cfg, NewBasicAuthenticator(true, "admin", "secret"), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create form authenticator: %v", err)
+	}
+	return fa
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	return string(body)
+}
+
+// formToken extracts the CSRF token from a rendered page
+func formToken(t *testing.T, page string) string {
+	m := csrfInput.FindStringSubmatch(page)
+	if m == nil {
+		t.Fatalf("No CSRF token in page: %s", page)
+	}
+	return m[1]
+}
+
+func TestFormAuthenticator_LoginFlow(t *testing.T) {
+	fa := newTestFormAuthenticator(t, config.FormConfig{Enabled: true, Title: "Team Files"})
+	srv, client := newFormTestServer(t, fa)
+
+	// Unauthenticated browsers are sent to the login page
+	resp, err := client.Get(srv.URL + "/files/report.txt")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	page := readBody(t, resp)
+	if resp.Request.URL.Path != "/login" || !strings.Contains(page, "Team Files") {
+		t.Fatalf("Expected login page, got %s: %s", resp.Request.URL, page)
+	}
+	if !strings.Contains(page, `value="/files/report.txt"`) {
+		t.Error("Expected return_to to be carried in the form")
+	}
+
+	// Wrong password re-renders the form
+	resp, _ = client.PostForm(srv.URL+"/login", url.Values{
+		"username": {"admin"}, "password": {"wrong"}, "csrf_token": {formToken(t, page)}, "return_to": {"/files/report.txt"},
+	})
+	page = readBody(t, resp)
+	if resp.StatusCode != http.StatusUnauthorized || !strings.Contains(page, "Invalid username or password") {
+		t.Fatalf("Expected 401 with error, got %d", resp.StatusCode)
+	}
+
+	resp, _ = client.PostForm(srv.URL+"/login", url.Values{
+		"username": {"admin"}, "password": {"secret"}, "csrf_token": {formToken(t, page)}, "return_to": {"/files/report.txt"},
+	})
+	if body := readBody(t, resp); body != "GET as admin" {
+		t.Fatalf("Expected redirect back to the file, got %q", body)
+	}
+
+	var sessionCookie, csrfCookie *http.Cookie
+	for _, c := range client.Jar.Cookies(resp.Request.URL) {
+		switch c.Name {
+		case "otterserve_login":
+			sessionCookie = c
+		case "otterserve_login_csrf":
+			csrfCookie = c
+		}
+	}
+	if sessionCookie == nil || csrfCookie == nil {
+		t.Fatal("Expected session and CSRF cookies")
+	}
+
+	// State-changing requests need the CSRF token
+	req, _ := http.NewRequest("POST", srv.URL+"/files/upload", nil)
+	resp, _ = client.Do(req)
+	readBody(t, resp)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 without CSRF token, got %d", resp.StatusCode)
+	}
+	req, _ = http.NewRequest("POST", srv.URL+"/files/upload", nil)
+	req.Header.Set("X-CSRF-Token", csrfCookie.Value)
+	resp, _ = client.Do(req)
+	if body := readBody(t, resp); body != "POST as admin" {
+		t.Errorf("Expected POST with CSRF token to pass, got %d %q", resp.StatusCode, body)
+	}
+
+	// Logging out ends the server-side session
+	resp, _ = client.Get(srv.URL + "/logout")
+	page = readBody(t, resp)
+	resp, _ = client.PostForm(srv.URL+"/logout", url.Values{"csrf_token": {formToken(t, page)}})
+	readBody(t, resp)
+	if resp.Request.URL.Path != "/login" {
+		t.Errorf("Expected redirect to login after logout, got %s", resp.Request.URL)
+	}
+	if _, ok := fa.sessions.Get(sessionCookie.Value); ok {
+		t.Error("Expected session to be deleted on logout")
+	}
+}
+
+func TestFormAuthenticator_LoginCSRF(t *testing.T) {
+	fa := newTestFormAuthenticator(t, config.FormConfig{Enabled: true})
+	srv, client := newFormTestServer(t, fa)
+
+	// A cross-site form post carries no login token cookie
+	resp, err := client.PostForm(srv.URL+"/login", url.Values{
+		"username": {"admin"}, "password": {"secret"}, "csrf_token": {"forged"},
+	})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	readBody(t, resp)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for login without form token, got %d", resp.StatusCode)
+	}
+}
+
+func TestFormAuthenticator_NonGETWithoutSession(t *testing.T) {
+	fa := newTestFormAuthenticator(t, config.FormConfig{Enabled: true})
+	handler := fa.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("PUT", "/files/a", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", rr.Code)
+	}
+}
+
+func TestFormAuthenticator_CustomTemplate(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "login.html")
+	os.WriteFile(tmpl, []byte(`<p class="brand">{{.Title}}</p><input name="csrf_token" value="{{.CSRFToken}}">`), 0644)
+
+	fa := newTestFormAuthenticator(t, config.FormConfig{Enabled: true, Title: "Acme <Files>", Template: tmpl})
+	rr := httptest.NewRecorder()
+	fa.handleLogin(rr, httptest.NewRequest("GET", "/login", nil))
+
+	if !strings.Contains(rr.Body.String(), `<p class="brand">Acme &lt;Files&gt;</p>`) {
+		t.Errorf("Expected custom template with escaped title, got %s", rr.Body.String())
+	}
+}
+
+func TestFormAuthenticator_OTPField(t *testing.T) {
+	store, secret, _, now := enrollTestUser(t, "admin")
+	basic := NewBasicAuthenticator(true, "admin", "secret").(*BasicAuthenticator)
+	basic.SetTOTP(store, false)
+
+	fa, err := NewFormAuthenticator(config.FormConfig{Enabled: true}, basic, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create form authenticator: %v", err)
+	}
+	fa.SetOTPField(true)
+	srv, client := newFormTestServer(t, fa)
+
+	resp, err := client.Get(srv.URL + "/login")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	page := readBody(t, resp)
+	if !strings.Contains(page, `name="otp"`) {
+		t.Fatal("Expected one-time code field on login page")
+	}
+
+	resp, _ = client.PostForm(srv.URL+"/login", url.Values{
+		"username": {"admin"}, "password": {"secret"}, "csrf_token": {formToken(t, page)},
+	})
+	page = readBody(t, resp)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without code, got %d", resp.StatusCode)
+	}
+
+	resp, _ = client.PostForm(srv.URL+"/login", url.Values{
+		"username": {"admin"}, "password": {"secret"}, "otp": {totpCode(secret, now.Unix()/totpPeriod)},
+		"csrf_token": {formToken(t, page)}, "return_to": {"/files/a"},
+	})
+	if body := readBody(t, resp); body != "GET as admin" {
+		t.Errorf("Expected login with code to succeed, got %d %q", resp.StatusCode, body)
+	}
+}
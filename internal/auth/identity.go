@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"otterserve/internal/audit"
+	"otterserve/internal/metrics"
+)
+
+// Identity describes an authenticated principal
+type Identity struct {
+	Username string
+	Method   string
+	Groups   []string
+}
+
+// HandlerProvider is implemented by authenticators that need their own
+// endpoints (login callbacks, logout, ...) mounted on the server mux
+type HandlerProvider interface {
+	Handlers() map[string]http.Handler
+}
+
+// InAnyGroup reports whether the identity belongs to one of the groups
+func (id *Identity) InAnyGroup(groups []string) bool {
+	for _, want := range groups {
+		for _, have := range id.Groups {
+			if want == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+type identitySlotKey struct{}
+
+// identitySlot lets outer middleware observe the identity established by
+// inner middleware, since request contexts only flow inwards
+type identitySlot struct {
+	identity *Identity
+}
+
+// WithIdentity returns a copy of the request carrying the given identity
+func WithIdentity(r *http.Request, id *Identity) *http.Request {
+	if slot, ok := r.Context().Value(identitySlotKey{}).(*identitySlot); ok {
+		slot.identity = id
+	}
+	return r.WithContext(context.WithValue(r.Context(), identityKey{}, id))
+}
+
+// TrackIdentity prepares a request so that the identity established further
+// down the handler chain can be read back through the returned function. A
+// request that is already tracked keeps its slot, so several middleware can
+// observe the same identity.
+func TrackIdentity(r *http.Request) (*http.Request, func() *Identity) {
+	if slot, ok := r.Context().Value(identitySlotKey{}).(*identitySlot); ok {
+		return r, func() *Identity { return slot.identity }
+	}
+	slot := &identitySlot{}
+	r = r.WithContext(context.WithValue(r.Context(), identitySlotKey{}, slot))
+	return r, func() *Identity { return slot.identity }
+}
+
+// IdentityFromContext returns the identity stored in the context, if any
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok && id != nil
+}
+
+// authFailures counts failed and locked-out authentication attempts
+var authFailures = metrics.Default.NewCounterVec("otterserve_auth_failures_total",
+	"Failed authentication attempts, by method and outcome (failure or denied when locked out).", "method", "outcome")
+
+// auditLogin records the outcome of an authentication attempt
+func auditLogin(r *http.Request, method, username, outcome, reason string) {
+	if outcome != audit.OutcomeSuccess {
+		authFailures.WithLabelValues(method, outcome).Inc()
+	}
+	audit.Record(r, audit.Event{
+		Type:       audit.EventLogin,
+		Outcome:    outcome,
+		User:       username,
+		AuthMethod: method,
+		Reason:     reason,
+	})
+}
+
+// auditLockout records that repeated failures locked out a user or client
+func auditLockout(r *http.Request, method, username string) {
+	audit.Record(r, audit.Event{
+		Type:       audit.EventLockout,
+		Outcome:    audit.OutcomeDenied,
+		User:       username,
+		AuthMethod: method,
+		Reason:     "too many failed login attempts",
+	})
+}
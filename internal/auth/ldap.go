@@ -0,0 +1,626 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"otterserve/internal/audit"
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+)
+
+const (
+	defaultLDAPUserFilter = "(uid=%s)"
+	defaultLDAPGroupAttr  = "memberOf"
+	defaultLDAPCacheTTL   = 5 * time.Minute
+	defaultLDAPTimeout    = 10 * time.Second
+
+	ldapResultSuccess = 0
+	ldapStartTLSOID   = "1.3.6.1.4.1.1466.20037"
+
+	ldapTagBindRequest      = berClassApplication | 0
+	ldapTagBindResponse     = berClassApplication | 1
+	ldapTagUnbindRequest    = berClassApplication | 2
+	ldapTagSearchRequest    = berClassApplication | 3
+	ldapTagSearchEntry      = berClassApplication | 4
+	ldapTagSearchDone       = berClassApplication | 5
+	ldapTagExtendedRequest  = berClassApplication | 23
+	ldapTagExtendedResponse = berClassApplication | 24
+)
+
+// ldapEntry is a search result entry
+type ldapEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// ldapCacheEntry remembers a successful authentication
+type ldapCacheEntry struct {
+	identity *Identity
+	expires  time.Time
+}
+
+// LDAPAuthenticator authenticates Basic Auth credentials against an LDAP or
+// Active Directory server using search-then-bind
+type LDAPAuthenticator struct {
+	cfg       config.LDAPConfig
+	tlsConfig *tls.Config
+	address   string
+	useTLS    bool
+	cacheTTL  time.Duration
+	timeout   time.Duration
+	logger    logger.Logger
+	lockout   *LockoutTracker
+
+	mu    sync.Mutex
+	cache map[string]ldapCacheEntry
+}
+
+// NewLDAPAuthenticator creates a new LDAP authenticator
+func NewLDAPAuthenticator(cfg config.LDAPConfig, log logger.Logger) (*LDAPAuthenticator, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap url %q: %w", cfg.URL, err)
+	}
+
+	var useTLS bool
+	defaultPort := "389"
+	switch u.Scheme {
+	case "ldaps":
+		useTLS = true
+		defaultPort = "636"
+	case "ldap":
+		if !cfg.StartTLS {
+			return nil, fmt.Errorf("ldap url %q requires start_tls; use ldaps:// or enable start_tls", cfg.URL)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported ldap url scheme %q", u.Scheme)
+	}
+
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ldap ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ldap ca_file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = defaultLDAPUserFilter
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = defaultLDAPGroupAttr
+	}
+	if _, err := parseLDAPFilter(strings.ReplaceAll(cfg.UserFilter, "%s", "x")); err != nil {
+		return nil, fmt.Errorf("invalid ldap user_filter: %w", err)
+	}
+
+	cacheTTL := cfg.CacheTTL
+	if cacheTTL == 0 {
+		cacheTTL = defaultLDAPCacheTTL
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultLDAPTimeout
+	}
+	if log == nil {
+		log = logger.NewLogger(logger.ErrorLevel, nil)
+	}
+
+	return &LDAPAuthenticator{
+		cfg:       cfg,
+		tlsConfig: tlsConfig,
+		address:   net.JoinHostPort(host, port),
+		useTLS:    useTLS,
+		cacheTTL:  cacheTTL,
+		timeout:   timeout,
+		logger:    log,
+		cache:     make(map[string]ldapCacheEntry),
+	}, nil
+}
+
+// IsEnabled returns whether authentication is enabled
+func (la *LDAPAuthenticator) IsEnabled() bool {
+	return true
+}
+
+// Authenticate validates username and password against the directory
+func (la *LDAPAuthenticator) Authenticate(username, password string) bool {
+	_, ok := la.authenticateIdentity(username, password)
+	return ok
+}
+
+// Middleware returns an HTTP middleware that enforces Basic Auth against LDAP
+func (la *LDAPAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := basicCredentials(r)
+		if !ok {
+			writeUnauthorized(w, r)
+			return
+		}
+
+		ip := remoteIP(r)
+		if wait := la.lockout.Check(username, ip); wait > 0 {
+			auditLogin(r, MethodLDAP, username, audit.OutcomeDenied, "locked out")
+			writeTooManyRequests(w, r, wait)
+			return
+		}
+
+		id, ok := la.authenticateIdentity(username, password)
+		if !ok {
+			auditLogin(r, MethodLDAP, username, audit.OutcomeFailure, "invalid credentials")
+			if la.lockout.Failure(username, ip) {
+				auditLockout(r, MethodLDAP, username)
+			}
+			writeUnauthorized(w, r)
+			return
+		}
+		la.lockout.Success(username)
+		auditLogin(r, MethodLDAP, id.Username, audit.OutcomeSuccess, "")
+
+		next.ServeHTTP(w, WithIdentity(r, id))
+	})
+}
+
+// SetLockout enables brute-force protection using the given tracker
+func (la *LDAPAuthenticator) SetLockout(lt *LockoutTracker) {
+	la.lockout = lt
+}
+
+// AuthenticateRequest checks Basic Auth credentials without writing a response
+func (la *LDAPAuthenticator) AuthenticateRequest(r *http.Request) (*Identity, bool) {
+	username, password, ok := basicCredentials(r)
+	if !ok || la.lockout.Check(username, remoteIP(r)) > 0 {
+		return nil, false
+	}
+	id, ok := la.authenticateIdentity(username, password)
+	if ok {
+		la.lockout.Success(username)
+	}
+	return id, ok
+}
+
+// authenticateIdentity performs (or recalls from cache) a search-then-bind
+func (la *LDAPAuthenticator) authenticateIdentity(username, password string) (*Identity, bool) {
+	// An empty password would turn the user bind into an unauthenticated bind
+	if username == "" || password == "" {
+		return nil, false
+	}
+
+	key := ldapCacheKey(username, password)
+	now := time.Now()
+
+	la.mu.Lock()
+	if entry, ok := la.cache[key]; ok {
+		if now.Before(entry.expires) {
+			la.mu.Unlock()
+			return entry.identity, true
+		}
+		delete(la.cache, key)
+	}
+	la.mu.Unlock()
+
+	id, err := la.searchAndBind(username, password)
+	if err != nil {
+		la.logger.Warn("LDAP authentication failed", logger.Fields{
+			"username": username,
+			"error":    err.Error(),
+		})
+		return nil, false
+	}
+
+	la.mu.Lock()
+	la.cache[key] = ldapCacheEntry{identity: id, expires: now.Add(la.cacheTTL)}
+	la.mu.Unlock()
+
+	return id, true
+}
+
+// searchAndBind locates the user entry and verifies the password by binding as it
+func (la *LDAPAuthenticator) searchAndBind(username, password string) (*Identity, error) {
+	conn, err := la.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	if err := conn.bind(la.cfg.BindDN, la.cfg.BindPassword); err != nil {
+		return nil, fmt.Errorf("service bind failed: %w", err)
+	}
+
+	filter := strings.ReplaceAll(la.cfg.UserFilter, "%s", escapeLDAPFilter(username))
+	entries, err := conn.search(la.cfg.BaseDN, filter, []string{la.cfg.GroupAttribute})
+	if err != nil {
+		return nil, fmt.Errorf("user search failed: %w", err)
+	}
+	if len(entries) != 1 {
+		return nil, fmt.Errorf("expected exactly one entry for user, found %d", len(entries))
+	}
+
+	if err := conn.bind(entries[0].DN, password); err != nil {
+		return nil, fmt.Errorf("user bind failed: %w", err)
+	}
+
+	return &Identity{
+		Username: username,
+		Method:   MethodLDAP,
+		Groups:   la.mapGroups(entries[0].attribute(la.cfg.GroupAttribute)),
+	}, nil
+}
+
+// mapGroups translates directory group DNs into otterserve group names
+func (la *LDAPAuthenticator) mapGroups(dns []string) []string {
+	var groups []string
+	seen := make(map[string]bool)
+	for _, dn := range dns {
+		for ldapGroup, group := range la.cfg.GroupMap {
+			if strings.EqualFold(normalizeDN(ldapGroup), normalizeDN(dn)) && !seen[group] {
+				seen[group] = true
+				groups = append(groups, group)
+			}
+		}
+	}
+	return groups
+}
+
+// dial connects to the server and upgrades the connection to TLS
+func (la *LDAPAuthenticator) dial() (*ldapConn, error) {
+	dialer := &net.Dialer{Timeout: la.timeout}
+
+	var raw net.Conn
+	var err error
+	if la.useTLS {
+		raw, err = tls.DialWithDialer(dialer, "tcp", la.address, la.tlsConfig)
+	} else {
+		raw, err = dialer.Dial("tcp", la.address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", la.address, err)
+	}
+
+	conn := newLDAPConn(raw, la.timeout)
+	if !la.useTLS {
+		if err := conn.startTLS(la.tlsConfig); err != nil {
+			conn.close()
+			return nil, fmt.Errorf("starttls failed: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// ldapCacheKey derives a cache key that does not keep the password in memory
+func ldapCacheKey(username, password string) string {
+	sum := sha256.Sum256([]byte(username + "\x00" + password))
+	return fmt.Sprintf("%x", sum)
+}
+
+// normalizeDN removes insignificant whitespace around RDN separators
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return strings.Join(parts, ",")
+}
+
+// attribute returns the values of an attribute, matching names case-insensitively
+func (e *ldapEntry) attribute(name string) []string {
+	for attr, values := range e.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// ldapConn is a minimal synchronous LDAPv3 client connection
+type ldapConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	nextID  int64
+}
+
+func newLDAPConn(conn net.Conn, timeout time.Duration) *ldapConn {
+	return &ldapConn{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+}
+
+// close sends an unbind request and closes the connection
+func (c *ldapConn) close() {
+	c.nextID++
+	msg := newBERConstructed(berTagSequence,
+		berInt(berTagInteger, c.nextID),
+		newBERPrimitive(ldapTagUnbindRequest, nil),
+	)
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	c.conn.Write(msg.bytes())
+	c.conn.Close()
+}
+
+// roundTrip sends a request and collects responses until one with doneTag
+func (c *ldapConn) roundTrip(op *berPacket, doneTag byte) ([]*berPacket, error) {
+	c.nextID++
+	id := c.nextID
+	msg := newBERConstructed(berTagSequence, berInt(berTagInteger, id), op)
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(msg.bytes()); err != nil {
+		return nil, err
+	}
+
+	var ops []*berPacket
+	for {
+		resp, err := readBERPacket(c.reader)
+		if err != nil {
+			return nil, err
+		}
+		if resp.child(0) == nil || resp.child(1) == nil || resp.child(0).int() != id {
+			return nil, fmt.Errorf("unexpected ldap response")
+		}
+		respOp := resp.child(1)
+		ops = append(ops, respOp)
+		if respOp.tag&^berConstructed == doneTag {
+			return ops, nil
+		}
+	}
+}
+
+// bind performs a simple bind
+func (c *ldapConn) bind(dn, password string) error {
+	op := newBERConstructed(ldapTagBindRequest,
+		berInt(berTagInteger, 3),
+		berString(dn),
+		newBERPrimitive(berClassContext|0, []byte(password)),
+	)
+	ops, err := c.roundTrip(op, ldapTagBindResponse)
+	if err != nil {
+		return err
+	}
+	return ldapResultError(ops[len(ops)-1])
+}
+
+// startTLS negotiates TLS on the existing connection (RFC 4511 section 4.14)
+func (c *ldapConn) startTLS(cfg *tls.Config) error {
+	op := newBERConstructed(ldapTagExtendedRequest,
+		newBERPrimitive(berClassContext|0, []byte(ldapStartTLSOID)),
+	)
+	ops, err := c.roundTrip(op, ldapTagExtendedResponse)
+	if err != nil {
+		return err
+	}
+	if err := ldapResultError(ops[len(ops)-1]); err != nil {
+		return err
+	}
+
+	tlsConn := tls.Client(c.conn, cfg)
+	tlsConn.SetDeadline(time.Now().Add(c.timeout))
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	c.conn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// search runs a subtree search and returns the matching entries
+func (c *ldapConn) search(baseDN, filter string, attributes []string) ([]ldapEntry, error) {
+	f, err := parseLDAPFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := newBERConstructed(berTagSequence)
+	for _, a := range attributes {
+		attrs.children = append(attrs.children, berString(a))
+	}
+
+	op := newBERConstructed(ldapTagSearchRequest,
+		berString(baseDN),
+		berInt(berTagEnumerated, 2), // wholeSubtree
+		berInt(berTagEnumerated, 0), // neverDerefAliases
+		berInt(berTagInteger, 2),    // sizeLimit: we only need to detect ambiguity
+		berInt(berTagInteger, int64(c.timeout/time.Second)),
+		berBool(false),
+		f,
+		attrs,
+	)
+
+	ops, err := c.roundTrip(op, ldapTagSearchDone)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ldapEntry
+	for _, respOp := range ops {
+		switch respOp.tag &^ berConstructed {
+		case ldapTagSearchEntry:
+			entries = append(entries, parseLDAPEntry(respOp))
+		case ldapTagSearchDone:
+			// sizeLimitExceeded (4) still tells us the result is ambiguous
+			if code := respOp.child(0); code != nil && code.int() == 4 {
+				continue
+			}
+			if err := ldapResultError(respOp); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return entries, nil
+}
+
+// parseLDAPEntry decodes a SearchResultEntry
+func parseLDAPEntry(op *berPacket) ldapEntry {
+	entry := ldapEntry{Attributes: make(map[string][]string)}
+	if dn := op.child(0); dn != nil {
+		entry.DN = dn.str()
+	}
+	if attrs := op.child(1); attrs != nil {
+		for _, attr := range attrs.children {
+			name := attr.child(0)
+			vals := attr.child(1)
+			if name == nil || vals == nil {
+				continue
+			}
+			for _, v := range vals.children {
+				entry.Attributes[name.str()] = append(entry.Attributes[name.str()], v.str())
+			}
+		}
+	}
+	return entry
+}
+
+// ldapResultError converts an LDAPResult into an error
+func ldapResultError(op *berPacket) error {
+	code := op.child(0)
+	if code == nil {
+		return fmt.Errorf("malformed ldap result")
+	}
+	if code.int() == ldapResultSuccess {
+		return nil
+	}
+	msg := ""
+	if diag := op.child(2); diag != nil {
+		msg = diag.str()
+	}
+	return fmt.Errorf("ldap result code %d: %s", code.int(), msg)
+}
+
+// escapeLDAPFilter escapes a value for use in a search filter (RFC 4515)
+func escapeLDAPFilter(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// parseLDAPFilter compiles the subset of RFC 4515 filters used for user
+// lookups: and, or, not, equality and presence
+func parseLDAPFilter(filter string) (*berPacket, error) {
+	f, rest, err := parseLDAPFilterAt(strings.TrimSpace(filter))
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected trailing data in filter: %q", rest)
+	}
+	return f, nil
+}
+
+func parseLDAPFilterAt(s string) (*berPacket, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("filter must start with '('")
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, "", fmt.Errorf("unterminated filter")
+	}
+
+	switch s[0] {
+	case '&', '|':
+		tag := byte(berClassContext | 0)
+		if s[0] == '|' {
+			tag = berClassContext | 1
+		}
+		set := newBERConstructed(tag)
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseLDAPFilterAt(s)
+			if err != nil {
+				return nil, "", err
+			}
+			set.children = append(set.children, child)
+			s = rest
+		}
+		if !strings.HasPrefix(s, ")") || len(set.children) == 0 {
+			return nil, "", fmt.Errorf("malformed filter set")
+		}
+		return set, s[1:], nil
+	case '!':
+		child, rest, err := parseLDAPFilterAt(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("malformed not filter")
+		}
+		return newBERConstructed(berClassContext|2, child), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("unterminated filter")
+	}
+	item := s[:end]
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, "", fmt.Errorf("unsupported filter item %q", item)
+	}
+	attr, value := item[:eq], item[eq+1:]
+
+	if value == "*" {
+		return newBERPrimitive(berClassContext|7, []byte(attr)), s[end+1:], nil
+	}
+	if strings.Contains(value, "*") {
+		return nil, "", fmt.Errorf("substring filters are not supported")
+	}
+
+	unescaped, err := unescapeLDAPFilter(value)
+	if err != nil {
+		return nil, "", err
+	}
+	return newBERConstructed(berClassContext|3, berString(attr), berString(unescaped)), s[end+1:], nil
+}
+
+// unescapeLDAPFilter decodes \xx escapes in a filter assertion value
+func unescapeLDAPFilter(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("truncated escape in filter")
+		}
+		c, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in filter")
+		}
+		b.Write(c)
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,423 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"otterserve/internal/config"
+)
+
+// testLDAPEntry is a directory entry served by the in-process LDAP stand-in
+type testLDAPEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// testLDAPServer is a tiny LDAP server that understands bind, search,
+// StartTLS and unbind, enough to exercise the search-then-bind flow
+type testLDAPServer struct {
+	t        *testing.T
+	listener net.Listener
+	tls      *tls.Config
+	ldaps    bool
+	entries  []testLDAPEntry
+	bindDN   string
+	bindPass string
+	binds    int64
+	wg       sync.WaitGroup
+}
+
+func newTestLDAPServer(t *testing.T, ldaps bool) (*testLDAPServer, string) {
+	cert, caFile := newTestCertificate(t)
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	var listener net.Listener
+	var err error
+	if ldaps {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	srv := &testLDAPServer{
+		t:        t,
+		listener: listener,
+		tls:      tlsConfig,
+		ldaps:    ldaps,
+		bindDN:   "cn=svc,dc=example,dc=com",
+		bindPass: "svc-secret",
+		entries: []testLDAPEntry{
+			{
+				dn:       "uid=alice,ou=people,dc=example,dc=com",
+				password: "alice-pw",
+				attrs: map[string][]string{
+					"uid":      {"alice"},
+					"memberOf": {"cn=Exports, ou=groups,dc=example,dc=com", "cn=unmapped,ou=groups,dc=example,dc=com"},
+				},
+			},
+			{
+				dn:       "uid=bob,ou=people,dc=example,dc=com",
+				password: "bob-pw",
+				attrs:    map[string][]string{"uid": {"bob"}},
+			},
+		},
+	}
+
+	srv.wg.Add(1)
+	go srv.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		srv.wg.Wait()
+	})
+
+	return srv, caFile
+}
+
+func (s *testLDAPServer) url() string {
+	if s.ldaps {
+		return "ldaps://" + s.listener.Addr().String()
+	}
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testLDAPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *testLDAPServer) handle(conn net.Conn) {
+	defer func() { conn.Close() }()
+	reader := bufio.NewReader(conn)
+
+	reply := func(id int64, op *berPacket) {
+		conn.Write(newBERConstructed(berTagSequence, berInt(berTagInteger, id), op).bytes())
+	}
+	result := func(tag byte, code int64, msg string) *berPacket {
+		return newBERConstructed(tag, berInt(berTagEnumerated, code), berString(""), berString(msg))
+	}
+
+	for {
+		msg, err := readBERPacket(reader)
+		if err != nil {
+			return
+		}
+		id := msg.child(0).int()
+		op := msg.child(1)
+
+		switch op.tag &^ berConstructed {
+		case ldapTagBindRequest:
+			atomic.AddInt64(&s.binds, 1)
+			dn, password := op.child(1).str(), op.child(2).str()
+			code := int64(49) // invalidCredentials
+			if dn == s.bindDN && password == s.bindPass {
+				code = 0
+			}
+			for _, e := range s.entries {
+				if e.dn == dn && e.password == password {
+					code = 0
+				}
+			}
+			reply(id, result(ldapTagBindResponse, code, ""))
+		case ldapTagSearchRequest:
+			for _, e := range s.entries {
+				if matchTestFilter(op.child(6), e) {
+					attrs := newBERConstructed(berTagSequence)
+					for name, values := range e.attrs {
+						vals := newBERConstructed(berTagSequence | 0x01)
+						for _, v := range values {
+							vals.children = append(vals.children, berString(v))
+						}
+						attrs.children = append(attrs.children, newBERConstructed(berTagSequence, berString(name), vals))
+					}
+					reply(id, newBERConstructed(ldapTagSearchEntry, berString(e.dn), attrs))
+				}
+			}
+			reply(id, result(ldapTagSearchDone, 0, ""))
+		case ldapTagExtendedRequest:
+			reply(id, result(ldapTagExtendedResponse, 0, ""))
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			reader = bufio.NewReader(conn)
+		case ldapTagUnbindRequest:
+			return
+		}
+	}
+}
+
+// matchTestFilter evaluates the filter subset produced by parseLDAPFilter
+func matchTestFilter(f *berPacket, e testLDAPEntry) bool {
+	switch f.tag &^ berConstructed {
+	case berClassContext | 0:
+		for _, c := range f.children {
+			if !matchTestFilter(c, e) {
+				return false
+			}
+		}
+		return true
+	case berClassContext | 1:
+		for _, c := range f.children {
+			if matchTestFilter(c, e) {
+				return true
+			}
+		}
+		return false
+	case berClassContext | 2:
+		return !matchTestFilter(f.child(0), e)
+	case berClassContext | 3:
+		for _, v := range e.attrs[f.child(0).str()] {
+			if strings.EqualFold(v, f.child(1).str()) {
+				return true
+			}
+		}
+		return false
+	case berClassContext | 7:
+		return len(e.attrs[f.str()]) > 0
+	}
+	return false
+}
+
+// newTestCertificate creates a self-signed certificate for 127.0.0.1 and
+// writes it to a CA file
+func newTestCertificate(t *testing.T) (tls.Certificate, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ldap.test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
+
+func newTestLDAPAuthenticator(t *testing.T, srv *testLDAPServer, caFile, filter string) *LDAPAuthenticator {
+	la, err := NewLDAPAuthenticator(config.LDAPConfig{
+		Enabled:      true,
+		URL:          srv.url(),
+		StartTLS:     !srv.ldaps,
+		CAFile:       caFile,
+		BindDN:       srv.bindDN,
+		BindPassword: srv.bindPass,
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   filter,
+		GroupMap: map[string]string{
+			"cn=exports,ou=groups,dc=example,dc=com": "exports",
+		},
+		CacheTTL: time.Minute,
+		Timeout:  5 * time.Second,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create LDAP authenticator: %v", err)
+	}
+	return la
+}
+
+func TestLDAPAuthenticator_LDAPS(t *testing.T) {
+	srv, caFile := newTestLDAPServer(t, true)
+	la := newTestLDAPAuthenticator(t, srv, caFile, "(&(uid=*)(uid=%s))")
+
+	id, ok := la.authenticateIdentity("alice", "alice-pw")
+	if !ok {
+		t.Fatal("Expected alice to authenticate")
+	}
+	if id.Username != "alice" || id.Method != "ldap" {
+		t.Errorf("Unexpected identity: %+v", id)
+	}
+	if len(id.Groups) != 1 || id.Groups[0] != "exports" {
+		t.Errorf("Expected groups [exports], got %v", id.Groups)
+	}
+
+	if la.Authenticate("alice", "wrong") {
+		t.Error("Expected wrong password to fail")
+	}
+	if la.Authenticate("mallory", "alice-pw") {
+		t.Error("Expected unknown user to fail")
+	}
+	if la.Authenticate("alice", "") {
+		t.Error("Expected empty password to fail")
+	}
+}
+
+func TestLDAPAuthenticator_StartTLS(t *testing.T) {
+	srv, caFile := newTestLDAPServer(t, false)
+	la := newTestLDAPAuthenticator(t, srv, caFile, "(|(uid=%s)(mail=%s))")
+
+	if !la.Authenticate("bob", "bob-pw") {
+		t.Error("Expected bob to authenticate over StartTLS")
+	}
+}
+
+func TestLDAPAuthenticator_Cache(t *testing.T) {
+	srv, caFile := newTestLDAPServer(t, true)
+	la := newTestLDAPAuthenticator(t, srv, caFile, "(uid=%s)")
+
+	if !la.Authenticate("alice", "alice-pw") {
+		t.Fatal("Expected alice to authenticate")
+	}
+	binds := atomic.LoadInt64(&srv.binds)
+
+	if !la.Authenticate("alice", "alice-pw") {
+		t.Fatal("Expected cached authentication to succeed")
+	}
+	if got := atomic.LoadInt64(&srv.binds); got != binds {
+		t.Errorf("Expected cached authentication not to contact the server, binds went %d -> %d", binds, got)
+	}
+
+	// A different password must not be served from the cache
+	if la.Authenticate("alice", "other") {
+		t.Error("Expected different password to fail")
+	}
+
+	// Expired entries are revalidated
+	la.mu.Lock()
+	for k, e := range la.cache {
+		e.expires = time.Now().Add(-time.Second)
+		la.cache[k] = e
+	}
+	la.mu.Unlock()
+	la.Authenticate("alice", "alice-pw")
+	if got := atomic.LoadInt64(&srv.binds); got <= binds+2 {
+		t.Errorf("Expected expired cache entry to trigger a new bind, binds=%d", got)
+	}
+}
+
+func TestLDAPAuthenticator_FilterInjection(t *testing.T) {
+	srv, caFile := newTestLDAPServer(t, true)
+	la := newTestLDAPAuthenticator(t, srv, caFile, "(uid=%s)")
+
+	if la.Authenticate("*", "alice-pw") {
+		t.Error("Expected wildcard username to be escaped")
+	}
+	if la.Authenticate("alice)(uid=*", "alice-pw") {
+		t.Error("Expected filter metacharacters to be escaped")
+	}
+}
+
+func TestLDAPAuthenticator_Middleware(t *testing.T) {
+	srv, caFile := newTestLDAPServer(t, true)
+	la := newTestLDAPAuthenticator(t, srv, caFile, "(uid=%s)")
+
+	handler := la.Middleware(RequireGroups([]string{"exports"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	tests := []struct {
+		name     string
+		user     string
+		pass     string
+		expected int
+	}{
+		{"member of mapped group", "alice", "alice-pw", http.StatusOK},
+		{"authenticated without group", "bob", "bob-pw", http.StatusForbidden},
+		{"bad password", "alice", "nope", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/exports/", nil)
+			req.SetBasicAuth(tt.user, tt.pass)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestNewLDAPAuthenticator_RequiresTLS(t *testing.T) {
+	_, err := NewLDAPAuthenticator(config.LDAPConfig{URL: "ldap://127.0.0.1:389", BaseDN: "dc=x"}, nil)
+	if err == nil {
+		t.Error("Expected plain ldap:// without start_tls to be rejected")
+	}
+}
+
+func TestParseLDAPFilter(t *testing.T) {
+	valid := []string{
+		"(uid=alice)",
+		"(&(objectClass=person)(uid=alice))",
+		"(|(uid=alice)(mail=alice@example.com))",
+		"(!(disabled=TRUE))",
+		"(uid=a\\2ab)",
+		"(mail=*)",
+	}
+	for _, f := range valid {
+		if _, err := parseLDAPFilter(f); err != nil {
+			t.Errorf("Expected %q to parse, got %v", f, err)
+		}
+	}
+
+	invalid := []string{"uid=alice", "(uid=alice", "(&)", "(uid=al*ce)", "(uid=a\\2)", "(uid=a)x"}
+	for _, f := range invalid {
+		if _, err := parseLDAPFilter(f); err == nil {
+			t.Errorf("Expected %q to be rejected", f)
+		}
+	}
+}
+
+func TestEscapeLDAPFilter(t *testing.T) {
+	if got := escapeLDAPFilter(`a*b(c)\d`); got != `a\2ab\28c\29\5cd` {
+		t.Errorf("Unexpected escaping: %s", got)
+	}
+}
+
+func TestBERIntRoundTrip(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 255, 256, 65535, -1, -129} {
+		p := berInt(berTagInteger, v)
+		if got := p.int(); got != v {
+			t.Errorf("Round trip of %d returned %d", v, got)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+	"otterserve/internal/netutil"
+	"otterserve/internal/requestid"
+)
+
+// Lockout defaults used when the configuration leaves a value unset
+const (
+	defaultLockoutMaxAttempts      = 5
+	defaultLockoutMaxAttemptsPerIP = 20
+	defaultLockoutWindow           = 15 * time.Minute
+	defaultLockoutDuration         = time.Minute
+	defaultLockoutMaxDuration      = time.Hour
+
+	// maxLockoutEntries bounds memory when attackers cycle through usernames:
+	// beyond it, the unlocked entries with the oldest failures are dropped
+	maxLockoutEntries = 10000
+)
+
+// LockoutEntry describes the failed login attempts of a username or client IP
+type LockoutEntry struct {
+	Kind        string    `json:"kind"`
+	Value       string    `json:"value"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+}
+
+// LockoutTracker counts failed password logins per username and per client
+// IP and locks them out with exponential backoff once a threshold is reached.
+// A nil tracker is valid and never locks anything out.
+type LockoutTracker struct {
+	maxAttempts      int
+	maxAttemptsPerIP int
+	window           time.Duration
+	duration         time.Duration
+	maxDuration      time.Duration
+	stateFile        string
+	logger           logger.Logger
+	now              func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*LockoutEntry
+}
+
+// NewLockoutTracker creates a lockout tracker, restoring state from the
+// persistence file when one is configured
+func NewLockoutTracker(cfg config.LockoutConfig, log logger.Logger) (*LockoutTracker, error) {
+	if log == nil {
+		log = logger.NewLogger(logger.ErrorLevel, nil)
+	}
+
+	lt := &LockoutTracker{
+		maxAttempts:      cfg.MaxAttempts,
+		maxAttemptsPerIP: cfg.MaxAttemptsPerIP,
+		window:           cfg.Window,
+		duration:         cfg.Duration,
+		maxDuration:      cfg.MaxDuration,
+		stateFile:        cfg.StateFile,
+		logger:           log,
+		now:              time.Now,
+		entries:          make(map[string]*LockoutEntry),
+	}
+	if lt.maxAttempts == 0 {
+		lt.maxAttempts = defaultLockoutMaxAttempts
+	}
+	if lt.maxAttemptsPerIP == 0 {
+		lt.maxAttemptsPerIP = defaultLockoutMaxAttemptsPerIP
+	}
+	if lt.window == 0 {
+		lt.window = defaultLockoutWindow
+	}
+	if lt.duration == 0 {
+		lt.duration = defaultLockoutDuration
+	}
+	if lt.maxDuration == 0 {
+		lt.maxDuration = defaultLockoutMaxDuration
+	}
+	if lt.maxDuration < lt.duration {
+		lt.maxDuration = lt.duration
+	}
+
+	if lt.stateFile != "" {
+		if err := lt.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return lt, nil
+}
+
+// Check returns how long the username or client IP remains locked out
+func (lt *LockoutTracker) Check(username, ip string) time.Duration {
+	if lt == nil {
+		return 0
+	}
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	now := lt.now()
+	var wait time.Duration
+	for _, key := range []string{lockoutKey("user", username), lockoutKey("ip", ip)} {
+		if e, ok := lt.entries[key]; ok && e.LockedUntil.After(now) {
+			if d := e.LockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// Failure records a failed login for the username and client IP and reports
+// whether it started a lockout
+func (lt *LockoutTracker) Failure(username, ip string) bool {
+	if lt == nil {
+		return false
+	}
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	now := lt.now()
+	if len(lt.entries) >= maxLockoutEntries {
+		lt.pruneLocked(now)
+	}
+	if len(lt.entries) >= maxLockoutEntries {
+		lt.evictLocked(now)
+	}
+
+	changed := lt.recordLocked("user", username, lt.maxAttempts, now)
+	if ip != "" && lt.recordLocked("ip", ip, lt.maxAttemptsPerIP, now) {
+		changed = true
+	}
+	if changed {
+		lt.saveLocked()
+	}
+	return changed
+}
+
+// Success clears the failure count of a username after a successful login.
+// Client IP counters are left alone so one valid account cannot be used to
+// reset them.
+func (lt *LockoutTracker) Success(username string) {
+	if lt == nil {
+		return
+	}
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	key := lockoutKey("user", username)
+	if e, ok := lt.entries[key]; ok {
+		delete(lt.entries, key)
+		if !e.LockedUntil.IsZero() {
+			lt.saveLocked()
+		}
+	}
+}
+
+// Unlock removes the lockout and failure count for a username or client IP
+func (lt *LockoutTracker) Unlock(kind, value string) bool {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	key := lockoutKey(kind, value)
+	if _, ok := lt.entries[key]; !ok {
+		return false
+	}
+	delete(lt.entries, key)
+	lt.saveLocked()
+
+	lt.logger.Info("Login lockout cleared", logger.Fields{
+		"kind":  kind,
+		"value": value,
+	})
+	return true
+}
+
+// Locked returns the entries that are currently locked out
+func (lt *LockoutTracker) Locked() []LockoutEntry {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	now := lt.now()
+	locked := []LockoutEntry{}
+	for _, e := range lt.entries {
+		if e.LockedUntil.After(now) {
+			locked = append(locked, *e)
+		}
+	}
+	sort.Slice(locked, func(i, j int) bool {
+		if locked[i].Kind != locked[j].Kind {
+			return locked[i].Kind < locked[j].Kind
+		}
+		return locked[i].Value < locked[j].Value
+	})
+	return locked
+}
+
+// AdminHandler lists current lockouts (GET) and clears one (DELETE with a
+// user or ip query parameter)
+func (lt *LockoutTracker) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"lockouts": lt.Locked()})
+		case http.MethodDelete:
+			kind, value := "user", r.URL.Query().Get("user")
+			if value == "" {
+				kind, value = "ip", r.URL.Query().Get("ip")
+			}
+			if value == "" {
+				requestid.Error(w, r, "user or ip query parameter required", http.StatusBadRequest)
+				return
+			}
+			if !lt.Unlock(kind, value) {
+				requestid.Error(w, r, "no lockout found", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			requestid.Error(w, r, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// recordLocked counts a failure and starts a lockout once the threshold is
+// reached; every further failure doubles the lockout duration. It reports
+// whether a new lockout started.
+func (lt *LockoutTracker) recordLocked(kind, value string, threshold int, now time.Time) bool {
+	key := lockoutKey(kind, value)
+	e, ok := lt.entries[key]
+	if !ok && len(lt.entries) >= maxLockoutEntries {
+		// Every entry is locked out; new names are not tracked until one expires
+		return false
+	}
+	if !ok || (now.Sub(e.LastFailure) > lt.window && !e.LockedUntil.After(now)) {
+		e = &LockoutEntry{Kind: kind, Value: value}
+		lt.entries[key] = e
+	}
+
+	e.Failures++
+	e.LastFailure = now
+	if e.Failures < threshold {
+		return false
+	}
+
+	d := lt.duration
+	for i := threshold; i < e.Failures && d < lt.maxDuration; i++ {
+		d *= 2
+	}
+	if d > lt.maxDuration {
+		d = lt.maxDuration
+	}
+	e.LockedUntil = now.Add(d)
+
+	lt.logger.Warn("Login locked out after repeated failures", logger.Fields{
+		"kind":         kind,
+		"value":        value,
+		"failures":     e.Failures,
+		"locked_until": e.LockedUntil.Format(time.RFC3339),
+	})
+	return true
+}
+
+// pruneLocked drops entries whose failures have expired
+func (lt *LockoutTracker) pruneLocked(now time.Time) {
+	for key, e := range lt.entries {
+		if now.Sub(e.LastFailure) > lt.window && !e.LockedUntil.After(now) {
+			delete(lt.entries, key)
+		}
+	}
+}
+
+// evictLocked drops the unlocked entries with the oldest failures until a
+// tenth of the entries are free. Lockouts are kept.
+func (lt *LockoutTracker) evictLocked(now time.Time) {
+	var unlocked []string
+	for key, e := range lt.entries {
+		if !e.LockedUntil.After(now) {
+			unlocked = append(unlocked, key)
+		}
+	}
+	sort.Slice(unlocked, func(i, j int) bool {
+		return lt.entries[unlocked[i]].LastFailure.Before(lt.entries[unlocked[j]].LastFailure)
+	})
+	excess := len(lt.entries) - maxLockoutEntries*9/10
+	for i := 0; i < excess && i < len(unlocked); i++ {
+		delete(lt.entries, unlocked[i])
+	}
+}
+
+// load restores lockout state from the persistence file
+func (lt *LockoutTracker) load() error {
+	data, err := os.ReadFile(lt.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read lockout state file: %w", err)
+	}
+
+	var state struct {
+		Entries []LockoutEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse lockout state file %s: %w", lt.stateFile, err)
+	}
+
+	for i := range state.Entries {
+		e := state.Entries[i]
+		lt.entries[lockoutKey(e.Kind, e.Value)] = &e
+	}
+	lt.pruneLocked(lt.now())
+	return nil
+}
+
+// saveLocked writes the current state to the persistence file. Failures are
+// logged rather than returned so that logins keep working.
+func (lt *LockoutTracker) saveLocked() {
+	if lt.stateFile == "" {
+		return
+	}
+
+	lt.pruneLocked(lt.now())
+	entries := make([]LockoutEntry, 0, len(lt.entries))
+	for _, e := range lt.entries {
+		entries = append(entries, *e)
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{"entries": entries}, "", "  ")
+	if err == nil {
+		tmp := lt.stateFile + ".tmp"
+		if err = os.WriteFile(tmp, data, 0600); err == nil {
+			err = os.Rename(tmp, lt.stateFile)
+		}
+	}
+	if err != nil {
+		lt.logger.Error("Failed to save lockout state", logger.Fields{
+			"file":  lt.stateFile,
+			"error": err.Error(),
+		})
+	}
+}
+
+func lockoutKey(kind, value string) string {
+	return kind + ":" + value
+}
+
+// remoteIP returns the client address used for per-IP lockouts
+func remoteIP(r *http.Request) string {
+	addr := netutil.ClientIP(r)
+	if !addr.IsValid() {
+		return ""
+	}
+	return addr.String()
+}
+
+// writeTooManyRequests tells the client to retry once the lockout expires
+func writeTooManyRequests(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	w.Header().Set("Retry-After", retryAfter(wait))
+	requestid.Error(w, r, "429 Too Many Requests: too many failed login attempts", http.StatusTooManyRequests)
+}
+
+// retryAfter formats a wait as whole seconds, rounded up, for Retry-After
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int((wait + time.Second - 1) / time.Second))
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/audit"
+	"otterserve/internal/config"
+)
+
+// newTestLockout returns a tracker driven by a manual clock
+func newTestLockout(t *testing.T, cfg config.LockoutConfig) (*LockoutTracker, *time.Time) {
+	lt, err := NewLockoutTracker(cfg, nil)
+	if err != nil {
+		t.Fatalf("Failed to create lockout tracker: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	lt.now = func() time.Time { return now }
+	return lt, &now
+}
+
+func TestLockoutTracker_Backoff(t *testing.T) {
+	lt, now := newTestLockout(t, config.LockoutConfig{
+		Enabled:     true,
+		MaxAttempts: 3,
+		Duration:    time.Minute,
+		MaxDuration: 5 * time.Minute,
+	})
+
+	for i := 0; i < 2; i++ {
+		lt.Failure("alice", "192.0.2.1")
+	}
+	if wait := lt.Check("alice", "192.0.2.9"); wait != 0 {
+		t.Fatalf("Expected no lockout below threshold, got %v", wait)
+	}
+
+	lt.Failure("alice", "192.0.2.1")
+	if wait := lt.Check("alice", "192.0.2.9"); wait != time.Minute {
+		t.Errorf("Expected 1m lockout, got %v", wait)
+	}
+
+	// Each further failure doubles the lockout, up to the maximum
+	expected := []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+	for _, want := range expected {
+		*now = now.Add(10 * time.Minute)
+		lt.Failure("alice", "192.0.2.1")
+		if wait := lt.Check("alice", ""); wait != want {
+			t.Errorf("Expected %v lockout, got %v", want, wait)
+		}
+	}
+
+	lt.Success("alice")
+	if wait := lt.Check("alice", ""); wait != 0 {
+		t.Errorf("Expected success to clear lockout, got %v", wait)
+	}
+}
+
+func TestLockoutTracker_PerIP(t *testing.T) {
+	lt, _ := newTestLockout(t, config.LockoutConfig{Enabled: true, MaxAttempts: 100, MaxAttemptsPerIP: 3})
+
+	// Spraying different usernames from one address locks out the address
+	for _, user := range []string{"a", "b", "c"} {
+		lt.Failure(user, "198.51.100.7")
+	}
+	if wait := lt.Check("d", "198.51.100.7"); wait == 0 {
+		t.Error("Expected client IP to be locked out")
+	}
+	if wait := lt.Check("d", "198.51.100.8"); wait != 0 {
+		t.Errorf("Expected other clients to be unaffected, got %v", wait)
+	}
+
+	// A successful login does not reset the client IP counter
+	lt.Success("d")
+	if wait := lt.Check("d", "198.51.100.7"); wait == 0 {
+		t.Error("Expected client IP lockout to survive a successful login")
+	}
+}
+
+func TestLockoutTracker_WindowExpiry(t *testing.T) {
+	lt, now := newTestLockout(t, config.LockoutConfig{Enabled: true, MaxAttempts: 2, Window: time.Minute})
+
+	lt.Failure("bob", "")
+	*now = now.Add(2 * time.Minute)
+	lt.Failure("bob", "")
+	if wait := lt.Check("bob", ""); wait != 0 {
+		t.Errorf("Expected failures outside the window to be forgotten, got %v", wait)
+	}
+}
+
+func TestLockoutTracker_EntryLimit(t *testing.T) {
+	lt, now := newTestLockout(t, config.LockoutConfig{Enabled: true, MaxAttempts: 2})
+	lt.Failure("mallory", "")
+	lt.Failure("mallory", "")
+
+	// Cycling through usernames within the window stays within the limit
+	for i := 0; i < maxLockoutEntries+1000; i++ {
+		*now = now.Add(time.Millisecond)
+		lt.Failure("user"+strconv.Itoa(i), "")
+	}
+	if n := len(lt.entries); n > maxLockoutEntries {
+		t.Errorf("Expected at most %d entries, got %d", maxLockoutEntries, n)
+	}
+	if wait := lt.Check("mallory", ""); wait == 0 {
+		t.Error("Expected the lockout to be kept")
+	}
+	last := "user" + strconv.Itoa(maxLockoutEntries+999)
+	if _, ok := lt.entries[lockoutKey("user", last)]; !ok {
+		t.Error("Expected the latest failure to be tracked")
+	}
+}
+
+func TestLockoutTracker_Persistence(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "lockouts.json")
+	cfg := config.LockoutConfig{Enabled: true, MaxAttempts: 1, Duration: time.Hour, StateFile: stateFile}
+
+	lt, err := NewLockoutTracker(cfg, nil)
+	if err != nil {
+		t.Fatalf("Failed to create lockout tracker: %v", err)
+	}
+	lt.Failure("carol", "203.0.113.5")
+
+	restored, err := NewLockoutTracker(cfg, nil)
+	if err != nil {
+		t.Fatalf("Failed to restore lockout tracker: %v", err)
+	}
+	if wait := restored.Check("carol", ""); wait <= 0 {
+		t.Error("Expected lockout to be restored from the state file")
+	}
+}
+
+func TestBasicAuthenticator_Lockout(t *testing.T) {
+	lt, _ := newTestLockout(t, config.LockoutConfig{Enabled: true, MaxAttempts: 2, Duration: 90 * time.Second})
+	ba := NewBasicAuthenticator(true, "admin", "secret").(*BasicAuthenticator)
+	ba.SetLockout(lt)
+
+	handler := ba.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.SetBasicAuth("admin", password)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		if rr := request("wrong"); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: expected 401, got %d", i+1, rr.Code)
+		}
+	}
+
+	// The correct password is refused while the lockout is in force
+	rr := request("secret")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "90" {
+		t.Errorf("Expected Retry-After 90, got %q", got)
+	}
+}
+
+func TestBasicAuthenticator_LockoutAudit(t *testing.T) {
+	lt, _ := newTestLockout(t, config.LockoutConfig{Enabled: true, MaxAttempts: 2})
+	ba := NewBasicAuthenticator(true, "admin", "secret").(*BasicAuthenticator)
+	ba.SetLockout(lt)
+
+	var buf bytes.Buffer
+	auditor := audit.NewWriterLogger(&buf, false, "")
+	handler := ba.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, password := range []string{"wrong", "wrong", "secret"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.SetBasicAuth("admin", password)
+		handler.ServeHTTP(httptest.NewRecorder(), audit.WithLogger(req, auditor, "/"))
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e audit.Event
+		json.Unmarshal([]byte(line), &e)
+		if e.User != "admin" || e.AuthMethod != MethodBasic {
+			t.Errorf("Expected user and method in entry, got %+v", e)
+		}
+		got = append(got, e.Type+"/"+e.Outcome)
+	}
+	expected := "login/failure login/failure lockout/denied login/denied"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected events %q, got %q", expected, strings.Join(got, " "))
+	}
+}
+
+func TestLockoutTracker_AdminHandler(t *testing.T) {
+	lt, _ := newTestLockout(t, config.LockoutConfig{Enabled: true, MaxAttempts: 1, MaxAttemptsPerIP: 1})
+	lt.Failure("dave", "192.0.2.44")
+	handler := lt.AdminHandler()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/admin/lockouts", nil))
+	var body struct {
+		Lockouts []LockoutEntry `json:"lockouts"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Lockouts) != 2 || body.Lockouts[0].Kind != "ip" || body.Lockouts[1].Value != "dave" {
+		t.Errorf("Unexpected lockouts: %+v", body.Lockouts)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("DELETE", "/admin/lockouts?user=dave", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rr.Code)
+	}
+	if wait := lt.Check("dave", ""); wait != 0 {
+		t.Errorf("Expected dave to be unlocked, got %v", wait)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("DELETE", "/admin/lockouts?user=dave", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown lockout, got %d", rr.Code)
+	}
+}
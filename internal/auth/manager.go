@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"otterserve/internal/audit"
+)
+
+// Authentication method names usable in route configuration
+const (
+	MethodBasic      = "basic"
+	MethodLDAP       = "ldap"
+	MethodOIDC       = "oidc"
+	MethodClientCert = "client_cert"
+	MethodPassword   = "password"
+	MethodForm       = "form"
+)
+
+// RouteAuthenticator is implemented by authenticators that can select a
+// different set of methods for individual routes
+type RouteAuthenticator interface {
+	ForRoute(methods []string) (Authenticator, error)
+}
+
+// Manager holds every configured authentication method and a default used by
+// routes that do not select methods explicitly
+type Manager struct {
+	def      Authenticator
+	methods  map[string]Authenticator
+	handlers map[string]http.Handler
+}
+
+// NewManager creates a manager with a default authenticator and named methods
+func NewManager(def Authenticator, methods map[string]Authenticator) *Manager {
+	if methods == nil {
+		methods = make(map[string]Authenticator)
+	}
+	return &Manager{def: def, methods: methods, handlers: make(map[string]http.Handler)}
+}
+
+// Handle mounts an additional endpoint alongside those of the methods
+func (m *Manager) Handle(path string, h http.Handler) {
+	m.handlers[path] = h
+}
+
+// Default returns the authenticator used by routes without explicit methods
+func (m *Manager) Default() Authenticator {
+	return m.def
+}
+
+// IsEnabled returns whether the default authenticator is enabled
+func (m *Manager) IsEnabled() bool {
+	return m.def.IsEnabled()
+}
+
+// Authenticate validates credentials with the default authenticator
+func (m *Manager) Authenticate(username, password string) bool {
+	return m.def.Authenticate(username, password)
+}
+
+// Middleware returns the default authenticator's middleware
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return m.def.Middleware(next)
+}
+
+// ForRoute returns an authenticator accepting any of the named methods
+func (m *Manager) ForRoute(methods []string) (Authenticator, error) {
+	if len(methods) == 0 {
+		return m.def, nil
+	}
+
+	var selected []Authenticator
+	for _, name := range methods {
+		a, ok := m.methods[name]
+		if !ok {
+			return nil, fmt.Errorf("authentication method %q is not configured", name)
+		}
+		selected = append(selected, a)
+	}
+
+	if len(selected) == 1 {
+		return selected[0], nil
+	}
+	return NewAnyAuthenticator(selected...), nil
+}
+
+// Handlers merges the endpoints of all configured methods
+func (m *Manager) Handlers() map[string]http.Handler {
+	handlers := make(map[string]http.Handler)
+	for _, a := range m.uniqueMethods() {
+		if provider, ok := a.(HandlerProvider); ok {
+			for path, h := range provider.Handlers() {
+				handlers[path] = h
+			}
+		}
+	}
+	for path, h := range m.handlers {
+		handlers[path] = h
+	}
+	return handlers
+}
+
+// ConfigureTLS lets every configured method adjust the TLS configuration
+func (m *Manager) ConfigureTLS(cfg *tls.Config) {
+	for _, a := range m.uniqueMethods() {
+		if configurer, ok := a.(TLSConfigurer); ok {
+			configurer.ConfigureTLS(cfg)
+		}
+	}
+}
+
+// uniqueMethods returns each configured authenticator once, in a stable order
+func (m *Manager) uniqueMethods() []Authenticator {
+	names := make([]string, 0, len(m.methods))
+	for name := range m.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	candidates := []Authenticator{m.def}
+	for _, name := range names {
+		candidates = append(candidates, m.methods[name])
+	}
+
+	seen := make(map[Authenticator]bool)
+	var out []Authenticator
+	for _, a := range candidates {
+		if !seen[a] {
+			seen[a] = true
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// AnyAuthenticator admits a request when any of its authenticators accepts it
+type AnyAuthenticator struct {
+	authenticators []Authenticator
+}
+
+// NewAnyAuthenticator combines several authenticators with "any of" semantics
+func NewAnyAuthenticator(authenticators ...Authenticator) Authenticator {
+	return &AnyAuthenticator{authenticators: authenticators}
+}
+
+// IsEnabled returns true when any combined authenticator is enabled
+func (aa *AnyAuthenticator) IsEnabled() bool {
+	for _, a := range aa.authenticators {
+		if a.IsEnabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate validates credentials against each password-capable authenticator
+func (aa *AnyAuthenticator) Authenticate(username, password string) bool {
+	for _, a := range aa.authenticators {
+		if a.Authenticate(username, password) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware tries every authenticator that can check requests silently and
+// falls back to the challenge of the first interactive one
+func (aa *AnyAuthenticator) Middleware(next http.Handler) http.Handler {
+	challenger := aa.challenger().Middleware(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, a := range aa.authenticators {
+			if ra, ok := a.(RequestAuthenticator); ok {
+				if id, ok := ra.AuthenticateRequest(r); ok {
+					auditLogin(r, id.Method, id.Username, audit.OutcomeSuccess, "")
+					next.ServeHTTP(w, WithIdentity(r, id))
+					return
+				}
+			}
+		}
+		challenger.ServeHTTP(w, r)
+	})
+}
+
+// challenger picks the authenticator whose rejection response is shown to
+// clients; client certificates cannot be prompted for, so they come last
+func (aa *AnyAuthenticator) challenger() Authenticator {
+	for _, a := range aa.authenticators {
+		if _, isCert := a.(*ClientCertAuthenticator); !isCert {
+			return a
+		}
+	}
+	return aa.authenticators[0]
+}
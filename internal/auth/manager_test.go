@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"otterserve/internal/config"
+)
+
+func TestManager_ForRoute(t *testing.T) {
+	ca := newTestCA(t)
+	m, err := NewAuthenticatorFromConfig(config.AuthConfig{
+		Enabled:    true,
+		Username:   "admin",
+		Password:   "secret",
+		ClientCert: config.ClientCertConfig{Enabled: true, CAFile: ca.file},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create authenticators: %v", err)
+	}
+
+	if _, ok := m.Default().(*BasicAuthenticator); !ok {
+		t.Errorf("Expected basic auth as default, got %T", m.Default())
+	}
+	if _, err := m.ForRoute([]string{"oidc"}); err == nil {
+		t.Error("Expected error for method that is not configured")
+	}
+
+	either, err := m.ForRoute([]string{MethodClientCert, MethodPassword})
+	if err != nil {
+		t.Fatalf("ForRoute failed: %v", err)
+	}
+	certOnly, err := m.ForRoute([]string{MethodClientCert})
+	if err != nil {
+		t.Fatalf("ForRoute failed: %v", err)
+	}
+
+	// ConfigureTLS must be applied for the certificate to be requested
+	eitherSrv := newClientCertServer(t, &tlsAuthenticator{either, m})
+	certSrv := newClientCertServer(t, &tlsAuthenticator{certOnly, m})
+	cert := ca.issue(t, 3, "dave", "")
+
+	tests := []struct {
+		name     string
+		srvURL   string
+		client   *http.Client
+		password bool
+		status   int
+		body     string
+	}{
+		{"either with certificate", eitherSrv.URL, clientWithCertificate(eitherSrv, &cert), false, http.StatusOK, "client_cert:dave"},
+		{"either with password", eitherSrv.URL, clientWithCertificate(eitherSrv, nil), true, http.StatusOK, "basic:admin"},
+		{"either with nothing", eitherSrv.URL, clientWithCertificate(eitherSrv, nil), false, http.StatusUnauthorized, ""},
+		{"certificate only with password", certSrv.URL, clientWithCertificate(certSrv, nil), true, http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.srvURL, nil)
+			if tt.password {
+				req.SetBasicAuth("admin", "secret")
+			}
+			status, body := getWithClient(t, tt.client, req)
+			if status != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, status)
+			}
+			if tt.body != "" && body != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, body)
+			}
+		})
+	}
+
+	// A failed combined attempt falls back to the Basic Auth challenge
+	req, _ := http.NewRequest("GET", eitherSrv.URL, nil)
+	resp, err := clientWithCertificate(eitherSrv, nil).Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("WWW-Authenticate") == "" {
+		t.Error("Expected WWW-Authenticate challenge from basic auth")
+	}
+}
+
+func TestManager_ClientCertOnlyDefault(t *testing.T) {
+	ca := newTestCA(t)
+	m, err := NewAuthenticatorFromConfig(config.AuthConfig{
+		ClientCert: config.ClientCertConfig{Enabled: true, CAFile: ca.file},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create authenticators: %v", err)
+	}
+	if _, ok := m.Default().(*ClientCertAuthenticator); !ok {
+		t.Errorf("Expected client certificates as default, got %T", m.Default())
+	}
+	if !m.IsEnabled() {
+		t.Error("Expected manager to be enabled")
+	}
+}
+
+// tlsAuthenticator serves a route authenticator while taking the TLS settings
+// from the manager, as the server does
+type tlsAuthenticator struct {
+	Authenticator
+	tls TLSConfigurer
+}
+
+func (ta *tlsAuthenticator) ConfigureTLS(cfg *tls.Config) {
+	ta.tls.ConfigureTLS(cfg)
+}
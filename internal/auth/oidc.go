@@ -0,0 +1,681 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"otterserve/internal/audit"
+	"otterserve/internal/config"
+	"otterserve/internal/requestid"
+)
+
+const (
+	defaultOIDCCookieName = "otterserve_session"
+	defaultOIDCSessionTTL = 8 * time.Hour
+	defaultOIDCLogoutPath = "/auth/logout"
+	oidcStateTTL          = 10 * time.Minute
+	oidcClockSkew         = time.Minute
+)
+
+// oidcProviderMetadata holds the discovery document fields we rely on
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// oidcState is stored in a short-lived cookie between redirect and callback
+type oidcState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	ReturnTo string `json:"r"`
+	Expires  int64  `json:"e"`
+}
+
+// oidcSession is the payload of the session cookie
+type oidcSession struct {
+	Subject  string   `json:"sub"`
+	Username string   `json:"usr"`
+	Groups   []string `json:"grp,omitempty"`
+	Expires  int64    `json:"exp"`
+}
+
+// OIDCAuthenticator implements an OpenID Connect relying party using the
+// authorization code flow with PKCE and encrypted session cookies
+type OIDCAuthenticator struct {
+	cfg          config.OIDCConfig
+	codec        *cookieCodec
+	client       *http.Client
+	callbackPath string
+	cookieName   string
+	stateCookie  string
+	sessionTTL   time.Duration
+	secure       bool
+
+	mu       sync.Mutex
+	metadata *oidcProviderMetadata
+	keys     map[string]crypto.PublicKey
+}
+
+// NewOIDCAuthenticator creates a new OpenID Connect authenticator. Provider
+// discovery is deferred until the first login so that an unreachable issuer
+// does not prevent the server from starting.
+func NewOIDCAuthenticator(cfg config.OIDCConfig, client *http.Client) (*OIDCAuthenticator, error) {
+	codec, err := newCookieCodec(cfg.CookieSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	redirect, err := url.Parse(cfg.RedirectURL)
+	if err != nil || redirect.Path == "" {
+		return nil, fmt.Errorf("invalid oidc redirect_url %q", cfg.RedirectURL)
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.LogoutPath == "" {
+		cfg.LogoutPath = defaultOIDCLogoutPath
+	}
+
+	cookieName := cfg.CookieName
+	if cookieName == "" {
+		cookieName = defaultOIDCCookieName
+	}
+	ttl := cfg.SessionTTL
+	if ttl == 0 {
+		ttl = defaultOIDCSessionTTL
+	}
+
+	return &OIDCAuthenticator{
+		cfg:          cfg,
+		codec:        codec,
+		client:       client,
+		callbackPath: redirect.Path,
+		cookieName:   cookieName,
+		stateCookie:  cookieName + "_state",
+		sessionTTL:   ttl,
+		secure:       redirect.Scheme == "https",
+	}, nil
+}
+
+// IsEnabled returns whether authentication is enabled
+func (oa *OIDCAuthenticator) IsEnabled() bool {
+	return true
+}
+
+// Authenticate always fails: OIDC does not accept passwords
+func (oa *OIDCAuthenticator) Authenticate(username, password string) bool {
+	return false
+}
+
+// Middleware returns an HTTP middleware that requires a valid session cookie
+// and redirects browsers to the identity provider otherwise
+func (oa *OIDCAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := oa.AuthenticateRequest(r); ok {
+			next.ServeHTTP(w, WithIdentity(r, id))
+			return
+		}
+
+		// Only safe requests can be replayed after the login round-trip
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			requestid.Error(w, r, "401 Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		oa.redirectToProvider(w, r)
+	})
+}
+
+// AuthenticateRequest validates the session cookie on the request
+func (oa *OIDCAuthenticator) AuthenticateRequest(r *http.Request) (*Identity, bool) {
+	cookie, err := r.Cookie(oa.cookieName)
+	if err != nil {
+		return nil, false
+	}
+
+	var session oidcSession
+	if err := oa.codec.Decode(oa.cookieName, cookie.Value, &session); err != nil {
+		return nil, false
+	}
+	if time.Now().Unix() >= session.Expires {
+		return nil, false
+	}
+
+	return &Identity{
+		Username: session.Username,
+		Method:   MethodOIDC,
+		Groups:   session.Groups,
+	}, true
+}
+
+// Handlers returns the callback and logout endpoints
+func (oa *OIDCAuthenticator) Handlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		oa.callbackPath:   http.HandlerFunc(oa.handleCallback),
+		oa.cfg.LogoutPath: http.HandlerFunc(oa.handleLogout),
+	}
+}
+
+// redirectToProvider starts the authorization code flow
+func (oa *OIDCAuthenticator) redirectToProvider(w http.ResponseWriter, r *http.Request) {
+	meta, err := oa.provider(r.Context())
+	if err != nil {
+		requestid.Error(w, r, "502 Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	state, err1 := randomToken(24)
+	nonce, err2 := randomToken(24)
+	verifier, err3 := randomToken(32)
+	if err1 != nil || err2 != nil || err3 != nil {
+		requestid.Error(w, r, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	value, err := oa.codec.Encode(oa.stateCookie, oidcState{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		ReturnTo: r.URL.RequestURI(),
+		Expires:  time.Now().Add(oidcStateTTL).Unix(),
+	})
+	if err != nil {
+		requestid.Error(w, r, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oa.stateCookie,
+		Value:    value,
+		Path:     oa.callbackPath,
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   oa.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {oa.cfg.ClientID},
+		"redirect_uri":          {oa.cfg.RedirectURL},
+		"scope":                 {strings.Join(oa.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	http.Redirect(w, r, appendQuery(meta.AuthorizationEndpoint, params), http.StatusFound)
+}
+
+// handleCallback completes the authorization code flow
+func (oa *OIDCAuthenticator) handleCallback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(oa.stateCookie)
+	if err != nil {
+		requestid.Error(w, r, "400 Bad Request: missing login state", http.StatusBadRequest)
+		return
+	}
+
+	var st oidcState
+	if err := oa.codec.Decode(oa.stateCookie, cookie.Value, &st); err != nil || time.Now().Unix() >= st.Expires {
+		requestid.Error(w, r, "400 Bad Request: invalid login state", http.StatusBadRequest)
+		return
+	}
+
+	// The state cookie is single-use
+	http.SetCookie(w, &http.Cookie{Name: oa.stateCookie, Path: oa.callbackPath, MaxAge: -1})
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		auditLogin(r, MethodOIDC, "", audit.OutcomeFailure, "provider error: "+errCode)
+		requestid.Error(w, r, "403 Forbidden: "+errCode, http.StatusForbidden)
+		return
+	}
+	if query.Get("state") != st.State || query.Get("code") == "" {
+		requestid.Error(w, r, "400 Bad Request: state mismatch", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := oa.exchange(r.Context(), query.Get("code"), st.Verifier)
+	if err != nil {
+		auditLogin(r, MethodOIDC, "", audit.OutcomeFailure, "token exchange failed")
+		requestid.Error(w, r, "401 Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != st.Nonce {
+		auditLogin(r, MethodOIDC, "", audit.OutcomeFailure, "nonce mismatch")
+		requestid.Error(w, r, "401 Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	session := oidcSession{
+		Subject:  claimString(claims, "sub"),
+		Username: claimString(claims, oa.cfg.UsernameClaim),
+		Groups:   claimStrings(claims, oa.cfg.GroupsClaim),
+		Expires:  time.Now().Add(oa.sessionTTL).Unix(),
+	}
+	if session.Username == "" {
+		session.Username = claimString(claims, "email")
+	}
+	if session.Username == "" {
+		session.Username = session.Subject
+	}
+
+	value, err := oa.codec.Encode(oa.cookieName, session)
+	if err != nil {
+		requestid.Error(w, r, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oa.cookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(oa.sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   oa.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	auditLogin(r, MethodOIDC, session.Username, audit.OutcomeSuccess, "")
+	http.Redirect(w, r, safeReturnTo(st.ReturnTo), http.StatusFound)
+}
+
+// handleLogout clears the session and, if supported, ends the provider session
+func (oa *OIDCAuthenticator) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if id, ok := oa.AuthenticateRequest(r); ok {
+		audit.Record(r, audit.Event{
+			Type:       audit.EventLogout,
+			Outcome:    audit.OutcomeSuccess,
+			User:       id.Username,
+			AuthMethod: MethodOIDC,
+		})
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oa.cookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   oa.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	target := oa.cfg.PostLogoutRedirectURL
+	if meta, err := oa.provider(r.Context()); err == nil && meta.EndSessionEndpoint != "" {
+		params := url.Values{"client_id": {oa.cfg.ClientID}}
+		if target != "" {
+			params.Set("post_logout_redirect_uri", target)
+		}
+		target = appendQuery(meta.EndSessionEndpoint, params)
+	}
+	if target == "" {
+		target = "/"
+	}
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// exchange redeems the authorization code and verifies the returned ID token
+func (oa *OIDCAuthenticator) exchange(ctx context.Context, code, verifier string) (map[string]interface{}, error) {
+	meta, err := oa.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oa.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if oa.cfg.ClientSecret == "" {
+		form.Set("client_id", oa.cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if oa.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(oa.cfg.ClientID), url.QueryEscape(oa.cfg.ClientSecret))
+	}
+
+	resp, err := oa.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d", resp.StatusCode)
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	return oa.verifyIDToken(ctx, token.IDToken)
+}
+
+// verifyIDToken checks the signature and standard claims of an ID token
+func (oa *OIDCAuthenticator) verifyIDToken(ctx context.Context, raw string) (map[string]interface{}, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed id_token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid id_token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token signature encoding")
+	}
+
+	key, err := oa.signingKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWS(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid id_token claims: %w", err)
+	}
+
+	meta, err := oa.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if claimString(claims, "iss") != meta.Issuer {
+		return nil, fmt.Errorf("id_token issuer mismatch")
+	}
+	if !audienceContains(claims["aud"], oa.cfg.ClientID) {
+		return nil, fmt.Errorf("id_token audience mismatch")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().Add(-oidcClockSkew).Unix() >= int64(exp) {
+		return nil, fmt.Errorf("id_token expired")
+	}
+
+	return claims, nil
+}
+
+// provider returns the cached discovery document, fetching it on first use
+func (oa *OIDCAuthenticator) provider(ctx context.Context) (*oidcProviderMetadata, error) {
+	oa.mu.Lock()
+	defer oa.mu.Unlock()
+
+	if oa.metadata != nil {
+		return oa.metadata, nil
+	}
+
+	discovery := strings.TrimSuffix(oa.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	var meta oidcProviderMetadata
+	if err := oa.getJSON(ctx, discovery, &meta); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery document is incomplete")
+	}
+	// The issuer must be the configured one exactly (OpenID Connect
+	// Discovery 1.0, section 4.3)
+	if meta.Issuer != oa.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery document names issuer %q, expected %q", meta.Issuer, oa.cfg.Issuer)
+	}
+
+	oa.metadata = &meta
+	return oa.metadata, nil
+}
+
+// signingKey returns the provider key with the given ID, refreshing the key
+// set once when the ID is unknown to pick up provider key rotation
+func (oa *OIDCAuthenticator) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	meta, err := oa.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oa.mu.Lock()
+	defer oa.mu.Unlock()
+
+	if key, ok := oa.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := oa.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	oa.keys = make(map[string]crypto.PublicKey)
+	for _, jwk := range set.Keys {
+		if key, err := jwk.publicKey(); err == nil {
+			oa.keys[jwk.Kid] = key
+		}
+	}
+
+	if key, ok := oa.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no signing key found for kid %q", kid)
+}
+
+// lookupKey finds a key by ID, accepting the only key when no ID is given
+func (oa *OIDCAuthenticator) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if key, ok := oa.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(oa.keys) == 1 {
+		for _, key := range oa.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// getJSON fetches a URL and decodes the JSON response
+func (oa *OIDCAuthenticator) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oa.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// jsonWebKey is the subset of RFC 7517 needed for RSA and EC signature keys
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey converts the JWK into a Go public key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	if k.Use != "" && k.Use != "sig" {
+		return nil, fmt.Errorf("key %q is not a signing key", k.Kid)
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// verifyJWS checks a compact JWS signature for the supported algorithms
+func verifyJWS(alg string, key crypto.PublicKey, signingInput, signature []byte) error {
+	digest := sha256.Sum256(signingInput)
+
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type does not match alg %s", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("invalid id_token signature")
+		}
+		return nil
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("key type does not match alg %s", alg)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return fmt.Errorf("invalid id_token signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported id_token alg %q", alg)
+	}
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// audienceContains reports whether the aud claim includes the client ID
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimString returns a string claim or an empty string
+func claimString(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+// claimStrings returns a string-array claim, accepting a single string too
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// appendQuery adds parameters to a URL that may already have a query string
+func appendQuery(base string, params url.Values) string {
+	if strings.Contains(base, "?") {
+		return base + "&" + params.Encode()
+	}
+	return base + "?" + params.Encode()
+}
+
+// safeReturnTo only allows local absolute paths to avoid open redirects
+func safeReturnTo(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
@@ -0,0 +1,370 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"otterserve/internal/config"
+)
+
+// mockIssuer is a minimal OpenID provider used to exercise the relying party
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	issuer string // announced in the discovery document
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+	clientID  string
+	subject   string
+	tamper    bool
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	mi := &mockIssuer{t: t, key: key, clientID: "otterserve", subject: "alice"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", mi.discovery)
+	mux.HandleFunc("/authorize", mi.authorize)
+	mux.HandleFunc("/token", mi.token)
+	mux.HandleFunc("/jwks", mi.jwks)
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "logged out")
+	})
+	mi.server = httptest.NewServer(mux)
+	mi.issuer = mi.server.URL
+	t.Cleanup(mi.server.Close)
+	return mi
+}
+
+func (mi *mockIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 mi.issuer,
+		"authorization_endpoint": mi.server.URL + "/authorize",
+		"token_endpoint":         mi.server.URL + "/token",
+		"jwks_uri":               mi.server.URL + "/jwks",
+		"end_session_endpoint":   mi.server.URL + "/logout",
+	})
+}
+
+func (mi *mockIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "pkce required", http.StatusBadRequest)
+		return
+	}
+
+	mi.mu.Lock()
+	mi.challenge = q.Get("code_challenge")
+	mi.nonce = q.Get("nonce")
+	mi.mu.Unlock()
+
+	redirect := q.Get("redirect_uri") + "?code=test-code&state=" + url.QueryEscape(q.Get("state"))
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (mi *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	verifier := r.PostForm.Get("code_verifier")
+	sum := sha256.Sum256([]byte(verifier))
+
+	mi.mu.Lock()
+	challenge, nonce := mi.challenge, mi.nonce
+	mi.mu.Unlock()
+
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != challenge || r.PostForm.Get("code") != "test-code" {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "opaque",
+		"token_type":   "Bearer",
+		"id_token":     mi.signIDToken(nonce),
+	})
+}
+
+func (mi *mockIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(mi.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(mi.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (mi *mockIssuer) signIDToken(nonce string) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test-key", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":                mi.server.URL,
+		"sub":                mi.subject,
+		"aud":                mi.clientID,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              nonce,
+		"preferred_username": mi.subject,
+		"groups":             []string{"staff"},
+	})
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, mi.key, crypto.SHA256, digest[:])
+	if err != nil {
+		mi.t.Fatalf("Failed to sign token: %v", err)
+	}
+	if mi.tamper {
+		sig[0] ^= 0xff
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// newOIDCTestApp wires an OIDC authenticator in front of a trivial handler
+func newOIDCTestApp(t *testing.T, issuer *mockIssuer, ttl time.Duration) (*httptest.Server, *OIDCAuthenticator) {
+	mux := http.NewServeMux()
+	app := httptest.NewServer(mux)
+	t.Cleanup(app.Close)
+
+	oa, err := NewOIDCAuthenticator(config.OIDCConfig{
+		Enabled:      true,
+		Issuer:       issuer.server.URL,
+		ClientID:     "otterserve",
+		RedirectURL:  app.URL + "/auth/callback",
+		CookieSecret: "test-secret",
+		SessionTTL:   ttl,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	for path, handler := range oa.Handlers() {
+		mux.Handle(path, handler)
+	}
+	mux.Handle("/", oa.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := IdentityFromContext(r.Context())
+		io.WriteString(w, "hello "+id.Username)
+	})))
+
+	return app, oa
+}
+
+func newBrowser(t *testing.T) *http.Client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("Failed to create cookie jar: %v", err)
+	}
+	return &http.Client{Jar: jar}
+}
+
+func TestOIDCAuthenticator_LoginFlow(t *testing.T) {
+	issuer := newMockIssuer(t)
+	app, _ := newOIDCTestApp(t, issuer, time.Hour)
+	browser := newBrowser(t)
+
+	resp, err := browser.Get(app.URL + "/files/report.txt?x=1")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 after login, got %d: %s", resp.StatusCode, body)
+	}
+	if string(body) != "hello alice" {
+		t.Errorf("Expected 'hello alice', got %q", body)
+	}
+	if resp.Request.URL.RequestURI() != "/files/report.txt?x=1" {
+		t.Errorf("Expected to return to original URL, got %s", resp.Request.URL.RequestURI())
+	}
+
+	// Subsequent requests must pass on the session cookie without another redirect
+	browser.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err = browser.Get(app.URL + "/other")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected session to be reused, got %d", resp.StatusCode)
+	}
+}
+
+func TestOIDCAuthenticator_Logout(t *testing.T) {
+	issuer := newMockIssuer(t)
+	app, _ := newOIDCTestApp(t, issuer, time.Hour)
+	browser := newBrowser(t)
+
+	resp, err := browser.Get(app.URL + "/")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = browser.Get(app.URL + "/auth/logout")
+	if err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "logged out" {
+		t.Errorf("Expected to land on provider logout, got %q", body)
+	}
+
+	browser.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err = browser.Get(app.URL + "/")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("Expected redirect to provider after logout, got %d", resp.StatusCode)
+	}
+}
+
+func TestOIDCAuthenticator_SessionExpiry(t *testing.T) {
+	issuer := newMockIssuer(t)
+	_, oa := newOIDCTestApp(t, issuer, time.Hour)
+
+	expired, err := oa.codec.Encode(oa.cookieName, oidcSession{
+		Username: "alice",
+		Expires:  time.Now().Add(-time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode session: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: oa.cookieName, Value: expired})
+	if _, ok := oa.AuthenticateRequest(req); ok {
+		t.Error("Expected expired session to be rejected")
+	}
+
+	valid, _ := oa.codec.Encode(oa.cookieName, oidcSession{
+		Username: "alice",
+		Expires:  time.Now().Add(time.Minute).Unix(),
+	})
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: oa.cookieName, Value: valid})
+	id, ok := oa.AuthenticateRequest(req)
+	if !ok || id.Username != "alice" || id.Method != "oidc" {
+		t.Errorf("Expected valid session for alice, got %+v", id)
+	}
+
+	// Tampered cookies must be rejected
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: oa.cookieName, Value: "A" + valid[1:]})
+	if _, ok := oa.AuthenticateRequest(req); ok {
+		t.Error("Expected tampered session to be rejected")
+	}
+}
+
+func TestOIDCAuthenticator_RejectsBadSignature(t *testing.T) {
+	issuer := newMockIssuer(t)
+	issuer.tamper = true
+	app, _ := newOIDCTestApp(t, issuer, time.Hour)
+	browser := newBrowser(t)
+
+	resp, err := browser.Get(app.URL + "/")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for tampered id_token, got %d", resp.StatusCode)
+	}
+}
+
+func TestOIDCAuthenticator_IssuerMismatch(t *testing.T) {
+	for _, announced := range []string{"", "https://evil.example.com"} {
+		issuer := newMockIssuer(t)
+		issuer.issuer = announced
+		_, oa := newOIDCTestApp(t, issuer, time.Hour)
+		if _, err := oa.provider(context.Background()); err == nil {
+			t.Errorf("Expected discovery to fail for issuer %q", announced)
+		}
+	}
+}
+
+func TestOIDCAuthenticator_NonGetWithoutSession(t *testing.T) {
+	issuer := newMockIssuer(t)
+	app, _ := newOIDCTestApp(t, issuer, time.Hour)
+
+	resp, err := http.Post(app.URL+"/upload", "text/plain", strings.NewReader("data"))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for POST without session, got %d", resp.StatusCode)
+	}
+}
+
+func TestSafeReturnTo(t *testing.T) {
+	tests := map[string]string{
+		"/files/a.txt":        "/files/a.txt",
+		"//evil.example.com":  "/",
+		"https://evil.com/":   "/",
+		"/\\evil.example.com": "/",
+		"":                    "/",
+	}
+	for input, expected := range tests {
+		if got := safeReturnTo(input); got != expected {
+			t.Errorf("safeReturnTo(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestNewAuthenticatorFromConfig(t *testing.T) {
+	a, err := NewAuthenticatorFromConfig(config.AuthConfig{Enabled: true, Username: "u", Password: "p"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := a.Default().(*BasicAuthenticator); !ok {
+		t.Errorf("Expected BasicAuthenticator, got %T", a.Default())
+	}
+
+	a, err = NewAuthenticatorFromConfig(config.AuthConfig{OIDC: config.OIDCConfig{
+		Enabled:      true,
+		Issuer:       "https://issuer.example.com",
+		ClientID:     "otterserve",
+		RedirectURL:  "https://files.example.com/auth/callback",
+		CookieSecret: "secret",
+	}}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := a.Default().(*OIDCAuthenticator); !ok {
+		t.Errorf("Expected OIDCAuthenticator, got %T", a.Default())
+	}
+	if len(a.Handlers()) == 0 {
+		t.Error("Expected OIDC authenticator to provide handlers")
+	}
+}
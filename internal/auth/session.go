@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Session is a server-side login session
+type Session struct {
+	ID        string
+	Identity  Identity
+	CSRFToken string
+	Expires   time.Time
+}
+
+// SessionStore keeps login sessions on the server so that they can be
+// revoked, unlike self-contained cookies
+type SessionStore interface {
+	Create(id Identity, ttl time.Duration) (*Session, error)
+	Get(sessionID string) (*Session, bool)
+	Delete(sessionID string)
+}
+
+// MemorySessionStore is an in-memory SessionStore
+type MemorySessionStore struct {
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemorySessionStore creates an empty in-memory session store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]*Session),
+		now:      time.Now,
+	}
+}
+
+// Create starts a new session with random session and CSRF tokens
+func (ms *MemorySessionStore) Create(id Identity, ttl time.Duration) (*Session, error) {
+	sessionID, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	csrf, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := ms.now()
+	if now.Sub(ms.lastPrune) > time.Minute {
+		for key, s := range ms.sessions {
+			if !now.Before(s.Expires) {
+				delete(ms.sessions, key)
+			}
+		}
+		ms.lastPrune = now
+	}
+
+	session := &Session{ID: sessionID, Identity: id, CSRFToken: csrf, Expires: now.Add(ttl)}
+	ms.sessions[sessionID] = session
+	return session, nil
+}
+
+// Get returns the session if it exists and has not expired
+func (ms *MemorySessionStore) Get(sessionID string) (*Session, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	session, ok := ms.sessions[sessionID]
+	if !ok {
+		return nil, false
+	}
+	if !ms.now().Before(session.Expires) {
+		delete(ms.sessions, sessionID)
+		return nil, false
+	}
+	return session, true
+}
+
+// Delete ends a session
+func (ms *MemorySessionStore) Delete(sessionID string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.sessions, sessionID)
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemorySessionStore(t *testing.T) {
+	store := NewMemorySessionStore()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	session, err := store.Create(Identity{Username: "alice", Method: MethodForm}, time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if session.ID == "" || session.CSRFToken == "" || session.ID == session.CSRFToken {
+		t.Fatalf("Expected distinct random tokens, got %+v", session)
+	}
+
+	got, ok := store.Get(session.ID)
+	if !ok || got.Identity.Username != "alice" {
+		t.Fatalf("Expected session for alice, got %+v, %v", got, ok)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := store.Get(session.ID); ok {
+		t.Error("Expected expired session to be rejected")
+	}
+
+	session, _ = store.Create(Identity{Username: "bob"}, time.Hour)
+	store.Delete(session.ID)
+	if _, ok := store.Get(session.ID); ok {
+		t.Error("Expected deleted session to be gone")
+	}
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// Test vectors from RFC 6238 appendix B (SHA1, last six digits)
+	secret := []byte("12345678901234567890")
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range vectors {
+		if got := totpCode(secret, unix/totpPeriod); got != expected {
+			t.Errorf("totpCode at %d = %s, expected %s", unix, got, expected)
+		}
+	}
+}
+
+// enrollTestUser enrolls a user and returns the store, the decoded secret,
+// the recovery codes and a settable clock
+func enrollTestUser(t *testing.T, username string) (*TOTPStore, []byte, []string, *time.Time) {
+	store, err := NewTOTPStore(filepath.Join(t.TempDir(), "totp.json"))
+	if err != nil {
+		t.Fatalf("Failed to create TOTP store: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+
+	uri, codes, err := store.Enroll(username, "Otter Serve")
+	if err != nil {
+		t.Fatalf("Enroll failed: %v", err)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("Invalid provisioning URI %q: %v", uri, err)
+	}
+	secret, err := totpEncoding.DecodeString(u.Query().Get("secret"))
+	if err != nil {
+		t.Fatalf("Invalid secret in URI: %v", err)
+	}
+	return store, secret, codes, &now
+}
+
+func TestTOTPStore_Enroll(t *testing.T) {
+	store, _, codes, _ := enrollTestUser(t, "admin")
+
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+	}
+	if !store.Enrolled("admin") || store.Enrolled("guest") {
+		t.Error("Expected only admin to be enrolled")
+	}
+
+	// Recovery codes are never stored in clear text
+	for _, stored := range store.users["admin"].RecoveryCodes {
+		for _, code := range codes {
+			if strings.Contains(stored, strings.ReplaceAll(code, "-", "")) {
+				t.Fatal("Recovery code stored in clear text")
+			}
+		}
+	}
+
+	uri := provisioningURI("Otter Serve", "admin", []byte("12345678901234567890"))
+	if !strings.HasPrefix(uri, "otpauth://totp/Otter%20Serve:admin?") || !strings.Contains(uri, "issuer=Otter+Serve") {
+		t.Errorf("Unexpected provisioning URI: %s", uri)
+	}
+}
+
+func TestTOTPStore_Verify(t *testing.T) {
+	store, secret, _, now := enrollTestUser(t, "admin")
+	step := now.Unix() / totpPeriod
+
+	if !store.Verify("admin", totpCode(secret, step)) {
+		t.Fatal("Expected current code to verify")
+	}
+	// Basic Auth clients resend the same code
+	if !store.Verify("admin", totpCode(secret, step)) {
+		t.Error("Expected current code to be reusable within its window")
+	}
+	if !store.Verify("admin", totpCode(secret, step+1)) {
+		t.Error("Expected next code to be accepted for clock skew")
+	}
+	if store.Verify("admin", totpCode(secret, step)) {
+		t.Error("Expected code older than the last accepted one to be refused")
+	}
+	if store.Verify("admin", totpCode(secret, step+5)) {
+		t.Error("Expected code far in the future to be refused")
+	}
+	if store.Verify("guest", totpCode(secret, step)) {
+		t.Error("Expected unknown user to be refused")
+	}
+}
+
+func TestTOTPStore_RecoveryCodes(t *testing.T) {
+	store, _, codes, now := enrollTestUser(t, "admin")
+
+	if !store.Verify("admin", strings.ToUpper(codes[0])) {
+		t.Fatal("Expected recovery code to verify")
+	}
+	// Basic Auth clients resend the same code
+	*now = now.Add(recoveryCodeGrace - time.Second)
+	if !store.Verify("admin", codes[0]) {
+		t.Error("Expected used recovery code to be reusable within the grace period")
+	}
+	*now = now.Add(time.Second)
+	if store.Verify("admin", codes[0]) {
+		t.Error("Expected recovery code to be refused after the grace period")
+	}
+
+	// Consumption is persisted
+	reopened, err := NewTOTPStore(store.path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if reopened.Verify("admin", codes[0]) {
+		t.Error("Expected used recovery code to stay used after reload")
+	}
+	if !reopened.Verify("admin", codes[1]) {
+		t.Error("Expected unused recovery code to verify after reload")
+	}
+}
+
+func TestBasicAuthenticator_TOTP(t *testing.T) {
+	store, secret, codes, now := enrollTestUser(t, "admin")
+	code := totpCode(secret, now.Unix()/totpPeriod)
+
+	ba := NewBasicAuthenticator(true, "admin", "pa+ss").(*BasicAuthenticator)
+	ba.SetTOTP(store, false)
+
+	tests := []struct {
+		name     string
+		password string
+		expected bool
+	}{
+		{"password with code", "pa+ss+" + code, true},
+		{"password without code", "pa+ss", false},
+		{"wrong password with code", "wrong+" + code, false},
+		{"password with wrong code", "pa+ss+000000", false},
+		{"password with recovery code", "pa+ss+" + codes[0], true},
+	}
+	for _, tt := range tests {
+		if got := ba.Authenticate("admin", tt.password); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	// Users without an enrollment only need a password unless TOTP is required
+	other := NewBasicAuthenticator(true, "guest", "pw").(*BasicAuthenticator)
+	other.SetTOTP(store, false)
+	if !other.Authenticate("guest", "pw") {
+		t.Error("Expected unenrolled user to log in with password")
+	}
+	other.SetTOTP(store, true)
+	if other.Authenticate("guest", "pw") {
+		t.Error("Expected unenrolled user to be refused when TOTP is required")
+	}
+}
@@ -3,10 +3,21 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"otterserve/internal/netutil"
+	"otterserve/internal/rotate"
+	"otterserve/internal/tlsutil"
 )
 
 // Config represents the complete application configuration
@@ -15,31 +26,547 @@ type Config struct {
 	Auth    AuthConfig    `yaml:"auth"`
 	Routes  []RouteConfig `yaml:"routes"`
 	Logging LoggingConfig `yaml:"logging"`
+	Admin   AdminConfig   `yaml:"admin,omitempty"`
+	Metrics MetricsConfig `yaml:"metrics,omitempty"`
+	Health  HealthConfig  `yaml:"health,omitempty"`
+	Tracing TracingConfig `yaml:"tracing,omitempty"`
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host            string           `yaml:"host"`
+	Port            int              `yaml:"port"`
+	Allow           []string         `yaml:"allow,omitempty"`
+	Deny            []string         `yaml:"deny,omitempty"`
+	TrustedProxies  []string         `yaml:"trusted_proxies,omitempty"`
+	TLS             TLSConfig        `yaml:"tls,omitempty"`
+	Listeners       []ListenerConfig `yaml:"listeners,omitempty"`
+	PIDFile         string           `yaml:"pid_file,omitempty"`
+	RequestIDHeader string           `yaml:"request_id_header,omitempty"` // default X-Request-ID
+	Reload          ReloadConfig     `yaml:"reload,omitempty"`
+}
+
+// ReloadConfig controls reloading the configuration while running. SIGHUP
+// always triggers a reload; with watch the file is also checked for changes.
+type ReloadConfig struct {
+	Watch    bool          `yaml:"watch"`
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+// ListenerConfig describes one address the server accepts connections on.
+// Network is tcp (the default), tcp6 or unix; for unix the address is the
+// socket path and mode its octal file permissions. Routes restricts the
+// listener to the named route paths; empty exposes every route.
+type ListenerConfig struct {
+	Network string    `yaml:"network,omitempty"`
+	Address string    `yaml:"address"`
+	Mode    string    `yaml:"mode,omitempty"`
+	TLS     TLSConfig `yaml:"tls,omitempty"`
+	Routes  []string  `yaml:"routes,omitempty"`
+}
+
+// ListenerConfigs returns the configured listeners, or a single TCP listener
+// on host and port using the server TLS settings when none are configured
+func (sc ServerConfig) ListenerConfigs() []ListenerConfig {
+	if len(sc.Listeners) > 0 {
+		return sc.Listeners
+	}
+	return []ListenerConfig{{
+		Network: "tcp",
+		Address: net.JoinHostPort(sc.Host, strconv.Itoa(sc.Port)),
+		TLS:     sc.TLS,
+	}}
+}
+
+// RequestIDHeaderName returns the request ID header, X-Request-ID unless
+// configured
+func (sc ServerConfig) RequestIDHeaderName() string {
+	if sc.RequestIDHeader == "" {
+		return "X-Request-ID"
+	}
+	return sc.RequestIDHeader
+}
+
+// NetworkName returns the listener network, defaulting to tcp
+func (lc ListenerConfig) NetworkName() string {
+	if lc.Network == "" {
+		return "tcp"
+	}
+	return lc.Network
+}
+
+// SocketMode returns the file mode for a unix socket, or 0 to leave the
+// permissions the process umask gives it
+func (lc ListenerConfig) SocketMode() (os.FileMode, error) {
+	if lc.Mode == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(lc.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid socket mode %q: expected octal permissions such as 0660", lc.Mode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// ExposesRoute reports whether the listener serves the route with the given
+// path; paths are compared without leading and trailing slashes
+func (lc ListenerConfig) ExposesRoute(path string) bool {
+	if len(lc.Routes) == 0 {
+		return true
+	}
+	for _, p := range lc.Routes {
+		if strings.Trim(p, "/") == strings.Trim(path, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// TLSConfig holds HTTPS settings. Certificate files are reloaded when they
+// change; additional certificates are selected by server name (SNI).
+type TLSConfig struct {
+	Enabled      bool                `yaml:"enabled"`
+	CertFile     string              `yaml:"cert_file"`
+	KeyFile      string              `yaml:"key_file"`
+	Certificates []CertificateConfig `yaml:"certificates,omitempty"`
+	MinVersion   string              `yaml:"min_version,omitempty"`
+	CipherSuites []string            `yaml:"cipher_suites,omitempty"`
+	ALPN         []string            `yaml:"alpn,omitempty"`
+	RedirectPort int                 `yaml:"redirect_port,omitempty"`
+	ACME         ACMEConfig          `yaml:"acme,omitempty"`
+}
+
+// ACMEConfig holds settings for obtaining certificates automatically from an
+// ACME certificate authority such as Let's Encrypt
+type ACMEConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	DirectoryURL string        `yaml:"directory_url,omitempty"`
+	Email        string        `yaml:"email,omitempty"`
+	Domains      []string      `yaml:"domains"`
+	DataDir      string        `yaml:"data_dir"`
+	Challenges   []string      `yaml:"challenges,omitempty"`
+	CAFile       string        `yaml:"ca_file,omitempty"`
+	RenewBefore  time.Duration `yaml:"renew_before,omitempty"`
+}
+
+// CertificateConfig names an additional certificate and key for SNI
+type CertificateConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
 }
 
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
-	Enabled  bool   `yaml:"enabled"`
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+	Enabled    bool             `yaml:"enabled"`
+	Username   string           `yaml:"username"`
+	Password   string           `yaml:"password"`
+	OIDC       OIDCConfig       `yaml:"oidc,omitempty"`
+	LDAP       LDAPConfig       `yaml:"ldap,omitempty"`
+	ClientCert ClientCertConfig `yaml:"client_cert,omitempty"`
+	Lockout    LockoutConfig    `yaml:"lockout,omitempty"`
+	Form       FormConfig       `yaml:"form,omitempty"`
+	TOTP       TOTPConfig       `yaml:"totp,omitempty"`
+}
+
+// OIDCConfig holds OpenID Connect relying-party configuration
+type OIDCConfig struct {
+	Enabled               bool          `yaml:"enabled"`
+	Issuer                string        `yaml:"issuer"`
+	ClientID              string        `yaml:"client_id"`
+	ClientSecret          string        `yaml:"client_secret"`
+	RedirectURL           string        `yaml:"redirect_url"`
+	Scopes                []string      `yaml:"scopes,omitempty"`
+	UsernameClaim         string        `yaml:"username_claim,omitempty"`
+	GroupsClaim           string        `yaml:"groups_claim,omitempty"`
+	CookieName            string        `yaml:"cookie_name,omitempty"`
+	CookieSecret          string        `yaml:"cookie_secret"`
+	SessionTTL            time.Duration `yaml:"session_ttl,omitempty"`
+	LogoutPath            string        `yaml:"logout_path,omitempty"`
+	PostLogoutRedirectURL string        `yaml:"post_logout_redirect_url,omitempty"`
+}
+
+// LDAPConfig holds LDAP / Active Directory authentication configuration
+type LDAPConfig struct {
+	Enabled            bool              `yaml:"enabled"`
+	URL                string            `yaml:"url"`
+	StartTLS           bool              `yaml:"start_tls,omitempty"`
+	CAFile             string            `yaml:"ca_file,omitempty"`
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify,omitempty"`
+	BindDN             string            `yaml:"bind_dn"`
+	BindPassword       string            `yaml:"bind_password"`
+	BaseDN             string            `yaml:"base_dn"`
+	UserFilter         string            `yaml:"user_filter,omitempty"`
+	GroupAttribute     string            `yaml:"group_attribute,omitempty"`
+	GroupMap           map[string]string `yaml:"group_map,omitempty"`
+	CacheTTL           time.Duration     `yaml:"cache_ttl,omitempty"`
+	Timeout            time.Duration     `yaml:"timeout,omitempty"`
+}
+
+// ClientCertConfig holds TLS client certificate authentication configuration
+type ClientCertConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	CAFile       string `yaml:"ca_file"`
+	CRLFile      string `yaml:"crl_file,omitempty"`
+	UsernameFrom string `yaml:"username_from,omitempty"`
 }
 
-// RouteConfig defines a route mapping
+// LockoutConfig holds brute-force protection settings for password logins
+type LockoutConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	MaxAttempts      int           `yaml:"max_attempts,omitempty"`
+	MaxAttemptsPerIP int           `yaml:"max_attempts_per_ip,omitempty"`
+	Window           time.Duration `yaml:"window,omitempty"`
+	Duration         time.Duration `yaml:"duration,omitempty"`
+	MaxDuration      time.Duration `yaml:"max_duration,omitempty"`
+	StateFile        string        `yaml:"state_file,omitempty"`
+	AdminPath        string        `yaml:"admin_path,omitempty"`
+	AdminGroups      []string      `yaml:"admin_groups,omitempty"`
+}
+
+// FormConfig holds HTML login form and session cookie configuration
+type FormConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Title        string        `yaml:"title,omitempty"`
+	Template     string        `yaml:"template,omitempty"`
+	LoginPath    string        `yaml:"login_path,omitempty"`
+	LogoutPath   string        `yaml:"logout_path,omitempty"`
+	CookieName   string        `yaml:"cookie_name,omitempty"`
+	SessionTTL   time.Duration `yaml:"session_ttl,omitempty"`
+	SecureCookie bool          `yaml:"secure_cookie,omitempty"`
+}
+
+// TOTPConfig holds two-factor authentication settings for the local user
+type TOTPConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Issuer      string `yaml:"issuer,omitempty"`
+	SecretsFile string `yaml:"secrets_file"`
+	Required    bool   `yaml:"required,omitempty"`
+}
+
+// RouteConfig defines a route mapping. The JSON form is used by the admin API.
 type RouteConfig struct {
-	Path      string `yaml:"path"`
-	Directory string `yaml:"directory"`
+	Path      string   `yaml:"path" json:"path"`
+	Directory string   `yaml:"directory" json:"directory"`
+	Groups    []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+	Auth      []string `yaml:"auth,omitempty" json:"auth,omitempty"`
+	Allow     []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+	Deny      []string `yaml:"deny,omitempty" json:"deny,omitempty"`
+}
+
+// AdminConfig holds the settings of the admin API. It is served on its own
+// address and every request must carry the bearer token.
+type AdminConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Network string   `yaml:"network,omitempty"` // tcp (default) or unix
+	Address string   `yaml:"address,omitempty"` // default 127.0.0.1:1124
+	Mode    string   `yaml:"mode,omitempty"`    // unix socket permissions
+	Token   string   `yaml:"token"`
+	Allow   []string `yaml:"allow,omitempty"`
+}
+
+// Listener returns the listener settings of the admin API
+func (ac AdminConfig) Listener() ListenerConfig {
+	return ListenerConfig{Network: ac.Network, Address: ac.Address, Mode: ac.Mode}
+}
+
+// MetricsConfig holds the settings of the Prometheus metrics endpoint. With
+// admin set it is served on the admin listener and needs the admin token;
+// otherwise it is served on every server listener.
+type MetricsConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Path    string   `yaml:"path,omitempty"` // default /metrics
+	Admin   bool     `yaml:"admin,omitempty"`
+	Allow   []string `yaml:"allow,omitempty"`
+}
+
+// MetricsPath returns the path metrics are served at
+func (mc MetricsConfig) MetricsPath() string {
+	if mc.Path == "" {
+		return "/metrics"
+	}
+	return mc.Path
+}
+
+// HealthConfig holds the settings of the liveness and readiness endpoints,
+// which are served on every server listener
+type HealthConfig struct {
+	LivenessPath  string        `yaml:"liveness_path,omitempty"`  // default /healthz
+	ReadinessPath string        `yaml:"readiness_path,omitempty"` // default /readyz
+	CertExpiry    time.Duration `yaml:"cert_expiry,omitempty"`    // default 7 days
+	LogRequests   bool          `yaml:"log_requests,omitempty"`
+}
+
+// Liveness returns the path of the liveness endpoint
+func (hc HealthConfig) Liveness() string {
+	if hc.LivenessPath == "" {
+		return "/healthz"
+	}
+	return hc.LivenessPath
+}
+
+// Readiness returns the path of the readiness endpoint
+func (hc HealthConfig) Readiness() string {
+	if hc.ReadinessPath == "" {
+		return "/readyz"
+	}
+	return hc.ReadinessPath
+}
+
+// CertExpiryThreshold returns how long before expiry a certificate makes
+// the server report not ready
+func (hc HealthConfig) CertExpiryThreshold() time.Duration {
+	if hc.CertExpiry == 0 {
+		return 7 * 24 * time.Hour
+	}
+	return hc.CertExpiry
+}
+
+// Tracing exporters
+const (
+	TracingExporterOTLP = "otlp"
+	TracingExporterFile = "file"
+)
+
+// OTLP protocols, named as in the OpenTelemetry environment variables
+const (
+	OTLPProtocolHTTPProtobuf = "http/protobuf"
+	OTLPProtocolHTTPJSON     = "http/json"
+	OTLPProtocolGRPC         = "grpc"
+)
+
+// TracingConfig holds the OpenTelemetry tracing settings
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Exporter    string            `yaml:"exporter,omitempty"` // otlp (default) or file
+	Protocol    string            `yaml:"protocol,omitempty"` // http/protobuf (default), http/json or grpc
+	Endpoint    string            `yaml:"endpoint,omitempty"`
+	Headers     map[string]string `yaml:"headers,omitempty"`
+	File        string            `yaml:"file,omitempty"`
+	ServiceName string            `yaml:"service_name,omitempty"` // default otterserve
+	SampleRatio float64           `yaml:"sample_ratio,omitempty"` // default 1
+}
+
+// ExporterName returns the exporter, otlp unless configured
+func (tc TracingConfig) ExporterName() string {
+	if tc.Exporter == "" {
+		return TracingExporterOTLP
+	}
+	return tc.Exporter
+}
+
+// ProtocolName returns the OTLP protocol, http/protobuf unless configured
+func (tc TracingConfig) ProtocolName() string {
+	if tc.Protocol == "" {
+		return OTLPProtocolHTTPProtobuf
+	}
+	return tc.Protocol
+}
+
+// EndpointURL returns the OTLP endpoint, defaulting to a collector on
+// localhost. HTTP endpoints without a path get the standard /v1/traces.
+func (tc TracingConfig) EndpointURL() string {
+	endpoint := tc.Endpoint
+	if tc.ProtocolName() == OTLPProtocolGRPC {
+		if endpoint == "" {
+			endpoint = "http://localhost:4317"
+		}
+		return endpoint
+	}
+	if endpoint == "" {
+		endpoint = "http://localhost:4318"
+	}
+	if u, err := url.Parse(endpoint); err == nil && strings.Trim(u.Path, "/") == "" {
+		u.Path = "/v1/traces"
+		endpoint = u.String()
+	}
+	return endpoint
+}
+
+// Service returns the service name reported with spans
+func (tc TracingConfig) Service() string {
+	if tc.ServiceName == "" {
+		return "otterserve"
+	}
+	return tc.ServiceName
+}
+
+// Ratio returns the share of new traces that are sampled
+func (tc TracingConfig) Ratio() float64 {
+	if tc.SampleRatio == 0 {
+		return 1
+	}
+	return tc.SampleRatio
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level string `yaml:"level"`
-	File  string `yaml:"file"`
+	Level         string          `yaml:"level"`
+	File          string          `yaml:"file"`
+	Format        string          `yaml:"format,omitempty"`         // text (default), json or logfmt
+	TimeZone      string          `yaml:"time_zone,omitempty"`      // Local (default), UTC or an IANA zone name
+	TimePrecision string          `yaml:"time_precision,omitempty"` // s (default), ms, us or ns
+	Requests      string          `yaml:"requests,omitempty"`       // all (default), completed or none
+	Rotation      RotationConfig  `yaml:"rotation,omitempty"`
+	Audit         AuditConfig     `yaml:"audit,omitempty"`
+	Access        AccessLogConfig `yaml:"access,omitempty"`
+	Sinks         []LogSinkConfig `yaml:"sinks,omitempty"`
+	Async         AsyncLogConfig  `yaml:"async,omitempty"`
+	Redact        RedactConfig    `yaml:"redact,omitempty"`
+}
+
+// Application log formats
+const (
+	LogFormatText   = "text"
+	LogFormatJSON   = "json"
+	LogFormatLogfmt = "logfmt"
+)
+
+// Request lines written to the application log
+const (
+	RequestLinesAll       = "all"       // "Request started" and "Request completed"
+	RequestLinesCompleted = "completed" // "Request completed" only
+	RequestLinesNone      = "none"
+)
+
+// RequestLines returns which request lines go to the application log
+func (lc LoggingConfig) RequestLines() string {
+	if lc.Requests == "" {
+		return RequestLinesAll
+	}
+	return lc.Requests
+}
+
+// Overflow policies of the asynchronous log buffer
+const (
+	LogOverflowDrop  = "drop"
+	LogOverflowBlock = "block"
+)
+
+// AsyncLogConfig makes the application log and file and stdout sinks write
+// in the background, so requests never wait for the disk
+type AsyncLogConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	BufferSize int    `yaml:"buffer_size,omitempty"` // messages held, default 8192
+	Overflow   string `yaml:"overflow,omitempty"`    // drop (default) or block when the buffer is full
+}
+
+// RedactConfig lists secrets masked before they are logged, in addition to
+// the built-in ones such as password, token and authorization
+type RedactConfig struct {
+	Fields      []string `yaml:"fields,omitempty"`       // field names whose values are masked
+	Patterns    []string `yaml:"patterns,omitempty"`     // regular expressions; the first group, or the match, is masked
+	QueryParams []string `yaml:"query_params,omitempty"` // query parameters removed from logged URLs
+}
+
+// Log sink types
+const (
+	LogSinkFile     = "file"
+	LogSinkStdout   = "stdout"
+	LogSinkSyslog   = "syslog"
+	LogSinkJournald = "journald"
+)
+
+// LogSinkConfig describes an additional destination of the application log.
+// Each sink has its own minimum level, so errors can go to syslog while
+// debug messages go to a file.
+type LogSinkConfig struct {
+	Type     string         `yaml:"type"`               // file, stdout, syslog or journald
+	Level    string         `yaml:"level,omitempty"`    // the logging level if empty
+	File     string         `yaml:"file,omitempty"`     // file sinks
+	Format   string         `yaml:"format,omitempty"`   // file and stdout sinks: text (default), json or logfmt
+	Rotation RotationConfig `yaml:"rotation,omitempty"` // file sinks
+	Network  string         `yaml:"network,omitempty"`  // syslog: unix (default), udp or tcp
+	Address  string         `yaml:"address,omitempty"`  // syslog and journald: socket path or host:port
+	Facility string         `yaml:"facility,omitempty"` // syslog: daemon (default), local0 and so on
+	Tag      string         `yaml:"tag,omitempty"`      // syslog and journald identifier, default otterserve
+}
+
+// LevelName returns the minimum level of the sink, defaultLevel unless
+// configured
+func (sc LogSinkConfig) LevelName(defaultLevel string) string {
+	if sc.Level == "" {
+		return defaultLevel
+	}
+	return sc.Level
+}
+
+// NetworkName returns the syslog transport, unix unless configured
+func (sc LogSinkConfig) NetworkName() string {
+	if sc.Network == "" {
+		return "unix"
+	}
+	return sc.Network
+}
+
+// FacilityName returns the syslog facility, daemon unless configured
+func (sc LogSinkConfig) FacilityName() string {
+	if sc.Facility == "" {
+		return "daemon"
+	}
+	return sc.Facility
+}
+
+// TagName returns the identifier sent with syslog and journald messages,
+// otterserve unless configured
+func (sc LogSinkConfig) TagName() string {
+	if sc.Tag == "" {
+		return "otterserve"
+	}
+	return sc.Tag
+}
+
+// AuditConfig holds the audit log configuration. The audit log is a separate
+// append-only JSON stream of authentication and access events.
+type AuditConfig struct {
+	Enabled   bool           `yaml:"enabled"`
+	File      string         `yaml:"file"`
+	HashChain bool           `yaml:"hash_chain,omitempty"`
+	Rotation  RotationConfig `yaml:"rotation,omitempty"`
+}
+
+// Access log formats
+const (
+	AccessLogCommon   = "common"
+	AccessLogCombined = "combined"
+	AccessLogJSON     = "json"
+	AccessLogTemplate = "template"
+)
+
+// AccessLogConfig holds the access log configuration. The access log is a
+// separate stream with one line per request in a standard format.
+type AccessLogConfig struct {
+	Enabled  bool           `yaml:"enabled"`
+	File     string         `yaml:"file,omitempty"`     // standard output if empty
+	Format   string         `yaml:"format,omitempty"`   // combined (default), common, json or template
+	Template string         `yaml:"template,omitempty"` // used by the template format
+	Rotation RotationConfig `yaml:"rotation,omitempty"`
+}
+
+// FormatName returns the access log format, combined unless configured
+func (ac AccessLogConfig) FormatName() string {
+	if ac.Format == "" {
+		return AccessLogCombined
+	}
+	return ac.Format
+}
+
+// RotationConfig controls rotation of a log file. Zero values disable the
+// corresponding limit.
+type RotationConfig struct {
+	MaxSize    int           `yaml:"max_size,omitempty"` // megabytes
+	MaxBackups int           `yaml:"max_backups,omitempty"`
+	MaxAge     time.Duration `yaml:"max_age,omitempty"`
+	Compress   bool          `yaml:"compress,omitempty"`
+}
+
+// Options returns the settings in the form the rotate package takes
+func (rc RotationConfig) Options() rotate.Options {
+	return rotate.Options{
+		MaxSize:    int64(rc.MaxSize) << 20,
+		MaxBackups: rc.MaxBackups,
+		MaxAge:     rc.MaxAge,
+		Compress:   rc.Compress,
+	}
 }
 
 // ConfigManager interface defines configuration management operations
@@ -93,6 +620,9 @@ func (cm *DefaultConfigManager) Save(config *Config, filename string) error {
 	return nil
 }
 
+// defaultAdminAddress is where the admin API listens unless configured
+const defaultAdminAddress = "127.0.0.1:1124"
+
 // GetDefaultConfig returns a configuration with default values
 func GetDefaultConfig() *Config {
 	return &Config{
@@ -148,6 +678,12 @@ func (cm *DefaultConfigManager) LoadOrCreateDefault(filename string) (*Config, e
 	return config, nil
 }
 
+// ApplyDefaults fills in missing values of a configuration loaded with Load,
+// as LoadOrCreateDefault does
+func ApplyDefaults(config *Config) {
+	(&DefaultConfigManager{}).applyDefaults(config)
+}
+
 // applyDefaults fills in any missing configuration values with defaults
 func (cm *DefaultConfigManager) applyDefaults(config *Config) {
 	defaults := GetDefaultConfig()
@@ -167,6 +703,10 @@ func (cm *DefaultConfigManager) applyDefaults(config *Config) {
 		config.Logging.Level = defaults.Logging.Level
 	}
 
+	if config.Admin.Enabled && config.Admin.Address == "" && config.Admin.Listener().NetworkName() != "unix" {
+		config.Admin.Address = defaultAdminAddress
+	}
+
 	// Ensure at least one route exists
 	if len(config.Routes) == 0 {
 		config.Routes = defaults.Routes
@@ -196,9 +736,33 @@ func (cm *DefaultConfigManager) Validate(config *Config) error {
 	if config.Server.Port < 0 || config.Server.Port > 65535 {
 		return fmt.Errorf("server port must be between 0 and 65535, got %d", config.Server.Port)
 	}
+	if config.Server.Reload.Interval < 0 {
+		return fmt.Errorf("server reload interval cannot be negative")
+	}
+	if err := validateIPRules("server", config.Server.Allow, config.Server.Deny); err != nil {
+		return err
+	}
+	if _, err := netutil.ParsePrefixes(config.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("server: invalid trusted proxy: %w", err)
+	}
+	if !validHeaderName(config.Server.RequestIDHeaderName()) {
+		return fmt.Errorf("invalid server request_id_header %q", config.Server.RequestIDHeader)
+	}
+	if len(config.Server.Listeners) > 0 {
+		if config.Server.TLS.Enabled {
+			return fmt.Errorf("server tls cannot be combined with listeners; configure tls per listener")
+		}
+		if err := validateListeners(config.Server.Listeners, config.Routes); err != nil {
+			return err
+		}
+	} else if config.Server.TLS.Enabled {
+		if err := validateTLS(&config.Server.TLS, config.Server.Port); err != nil {
+			return err
+		}
+	}
 
 	// Validate authentication configuration
-	if config.Auth.Enabled {
+	if config.Auth.Enabled && !config.Auth.OIDC.Enabled && !config.Auth.LDAP.Enabled {
 		if config.Auth.Username == "" {
 			return fmt.Errorf("auth username cannot be empty when auth is enabled")
 		}
@@ -207,6 +771,43 @@ func (cm *DefaultConfigManager) Validate(config *Config) error {
 		}
 	}
 
+	if config.Auth.OIDC.Enabled {
+		if err := validateOIDC(&config.Auth.OIDC); err != nil {
+			return err
+		}
+	}
+	if config.Auth.LDAP.Enabled {
+		if err := validateLDAP(&config.Auth.LDAP); err != nil {
+			return err
+		}
+	}
+	if config.Auth.ClientCert.Enabled {
+		if err := validateClientCert(&config.Auth.ClientCert, config.Server.ListenerConfigs()); err != nil {
+			return err
+		}
+	}
+	if config.Auth.Lockout.Enabled {
+		if err := validateLockout(&config.Auth.Lockout); err != nil {
+			return err
+		}
+	}
+	if config.Auth.TOTP.Enabled {
+		if !config.Auth.methodEnabled("basic") {
+			return fmt.Errorf("totp requires basic auth credentials to be enabled")
+		}
+		if config.Auth.TOTP.SecretsFile == "" {
+			return fmt.Errorf("totp secrets_file cannot be empty when totp is enabled")
+		}
+	}
+	if config.Auth.Form.Enabled {
+		if !config.Auth.methodEnabled("password") {
+			return fmt.Errorf("form login requires basic auth credentials or ldap to be enabled")
+		}
+		if err := validateForm(&config.Auth.Form); err != nil {
+			return err
+		}
+	}
+
 	// Validate routes
 	if len(config.Routes) == 0 {
 		return fmt.Errorf("at least one route must be configured")
@@ -224,6 +825,16 @@ func (cm *DefaultConfigManager) Validate(config *Config) error {
 		if _, err := os.Stat(route.Directory); os.IsNotExist(err) {
 			return fmt.Errorf("route %d: directory %s does not exist", i, route.Directory)
 		}
+
+		if err := validateIPRules(fmt.Sprintf("route %d", i), route.Allow, route.Deny); err != nil {
+			return err
+		}
+
+		for _, method := range route.Auth {
+			if !config.Auth.methodEnabled(method) {
+				return fmt.Errorf("route %d: authentication method %q is not enabled", i, method)
+			}
+		}
 	}
 
 	// Validate logging configuration
@@ -236,6 +847,614 @@ func (cm *DefaultConfigManager) Validate(config *Config) error {
 	if !validLevels[config.Logging.Level] {
 		return fmt.Errorf("invalid log level %s, must be one of: debug, info, warn, error", config.Logging.Level)
 	}
+	if err := validateRotation("log", config.Logging.File, config.Logging.Rotation); err != nil {
+		return err
+	}
+	if config.Logging.Audit.Enabled {
+		if config.Logging.Audit.File == "" {
+			return fmt.Errorf("audit log file cannot be empty when the audit log is enabled")
+		}
+		if err := validateRotation("audit log", config.Logging.Audit.File, config.Logging.Audit.Rotation); err != nil {
+			return err
+		}
+	}
+	if err := validateLogFormat(&config.Logging); err != nil {
+		return err
+	}
+	switch config.Logging.RequestLines() {
+	case RequestLinesAll, RequestLinesCompleted, RequestLinesNone:
+	default:
+		return fmt.Errorf("invalid logging requests %s, must be one of: all, completed, none", config.Logging.Requests)
+	}
+	if config.Logging.Access.Enabled {
+		if err := validateAccessLog(&config.Logging.Access); err != nil {
+			return err
+		}
+	}
+	if config.Logging.Async.Enabled {
+		if err := validateAsyncLog(&config.Logging.Async); err != nil {
+			return err
+		}
+	}
+	for _, expr := range config.Logging.Redact.Patterns {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid log redact pattern %s: %w", expr, err)
+		}
+	}
+	for i := range config.Logging.Sinks {
+		if err := validateLogSink(&config.Logging.Sinks[i]); err != nil {
+			return fmt.Errorf("log sink %d: %w", i, err)
+		}
+	}
+
+	if config.Admin.Enabled {
+		if err := validateAdmin(&config.Admin, config.Server.ListenerConfigs()); err != nil {
+			return err
+		}
+	}
+	if config.Metrics.Enabled {
+		if err := validateMetrics(config); err != nil {
+			return err
+		}
+	}
+	if err := validateHealth(config); err != nil {
+		return err
+	}
+	if config.Tracing.Enabled {
+		if err := validateTracing(&config.Tracing); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateLogFormat checks the format and timestamp settings of the
+// application log
+func validateLogFormat(lc *LoggingConfig) error {
+	switch lc.Format {
+	case "", LogFormatText, LogFormatJSON, LogFormatLogfmt:
+	default:
+		return fmt.Errorf("invalid log format %s, must be one of: text, json, logfmt", lc.Format)
+	}
+	if lc.TimeZone != "" {
+		if _, err := time.LoadLocation(lc.TimeZone); err != nil {
+			return fmt.Errorf("invalid log time_zone %s: %w", lc.TimeZone, err)
+		}
+	}
+	switch lc.TimePrecision {
+	case "", "s", "ms", "us", "ns":
+	default:
+		return fmt.Errorf("invalid log time_precision %s, must be one of: s, ms, us, ns", lc.TimePrecision)
+	}
+	return nil
+}
+
+// validateAccessLog checks the access log format and rotation settings.
+// Template placeholders are checked when the access log is opened.
+func validateAccessLog(ac *AccessLogConfig) error {
+	switch ac.FormatName() {
+	case AccessLogCommon, AccessLogCombined, AccessLogJSON:
+		if ac.Template != "" {
+			return fmt.Errorf("access log template requires the template format")
+		}
+	case AccessLogTemplate:
+		if ac.Template == "" {
+			return fmt.Errorf("access log template cannot be empty with the template format")
+		}
+	default:
+		return fmt.Errorf("invalid access log format %s, must be one of: common, combined, json, template", ac.Format)
+	}
+	return validateRotation("access log", ac.File, ac.Rotation)
+}
+
+// validateAsyncLog checks the buffer settings of asynchronous logging
+func validateAsyncLog(ac *AsyncLogConfig) error {
+	if ac.BufferSize < 0 {
+		return fmt.Errorf("log async buffer_size cannot be negative")
+	}
+	switch ac.Overflow {
+	case "", LogOverflowDrop, LogOverflowBlock:
+	default:
+		return fmt.Errorf("invalid log async overflow %s, must be one of: drop, block", ac.Overflow)
+	}
+	return nil
+}
+
+// syslogFacilities are the facility names syslog sinks accept
+var syslogFacilities = map[string]bool{
+	"kern": true, "user": true, "mail": true, "daemon": true, "auth": true,
+	"syslog": true, "lpr": true, "news": true, "uucp": true, "cron": true,
+	"authpriv": true, "ftp": true, "local0": true, "local1": true,
+	"local2": true, "local3": true, "local4": true, "local5": true,
+	"local6": true, "local7": true,
+}
+
+// validateLogSink checks the settings of an additional log destination
+// against its type
+func validateLogSink(sc *LogSinkConfig) error {
+	switch sc.Level {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("invalid level %s, must be one of: debug, info, warn, error", sc.Level)
+	}
+
+	switch sc.Type {
+	case LogSinkFile, LogSinkStdout:
+		if sc.Type == LogSinkFile && sc.File == "" {
+			return fmt.Errorf("file cannot be empty for a file sink")
+		}
+		if sc.Type == LogSinkStdout && sc.File != "" {
+			return fmt.Errorf("file is only used by file sinks")
+		}
+		switch sc.Format {
+		case "", LogFormatText, LogFormatJSON, LogFormatLogfmt:
+		default:
+			return fmt.Errorf("invalid format %s, must be one of: text, json, logfmt", sc.Format)
+		}
+		if sc.Network != "" || sc.Address != "" || sc.Facility != "" || sc.Tag != "" {
+			return fmt.Errorf("network, address, facility and tag are only used by syslog and journald sinks")
+		}
+		return validateRotation("sink", sc.File, sc.Rotation)
+	case LogSinkSyslog, LogSinkJournald:
+	default:
+		return fmt.Errorf("invalid type %s, must be one of: file, stdout, syslog, journald", sc.Type)
+	}
+
+	if sc.File != "" || sc.Format != "" || sc.Rotation != (RotationConfig{}) {
+		return fmt.Errorf("file, format and rotation are only used by file and stdout sinks")
+	}
+	if sc.Type == LogSinkJournald {
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("journald is only available on Linux")
+		}
+		if sc.Network != "" || sc.Facility != "" {
+			return fmt.Errorf("network and facility are only used by syslog sinks")
+		}
+		return nil
+	}
+
+	if !syslogFacilities[sc.FacilityName()] {
+		return fmt.Errorf("invalid syslog facility %s", sc.Facility)
+	}
+	switch sc.NetworkName() {
+	case "unix":
+		if runtime.GOOS == "windows" {
+			return fmt.Errorf("syslog over unix sockets is not available on Windows, use udp or tcp")
+		}
+	case "udp", "tcp":
+		if sc.Address == "" {
+			return fmt.Errorf("syslog over %s requires an address", sc.Network)
+		}
+		if _, _, err := net.SplitHostPort(sc.Address); err != nil {
+			return fmt.Errorf("invalid syslog address %s: %w", sc.Address, err)
+		}
+	default:
+		return fmt.Errorf("invalid syslog network %s, must be one of: unix, udp, tcp", sc.Network)
+	}
+	return nil
+}
+
+// validateRotation checks the rotation settings of a log file
+func validateRotation(name, file string, rc RotationConfig) error {
+	if rc.MaxSize < 0 || rc.MaxBackups < 0 || rc.MaxAge < 0 {
+		return fmt.Errorf("%s rotation settings cannot be negative", name)
+	}
+	if file == "" && rc != (RotationConfig{}) {
+		return fmt.Errorf("%s rotation requires a file", name)
+	}
+	return nil
+}
+
+// validateMetrics checks the metrics endpoint settings
+func validateMetrics(config *Config) error {
+	mc := config.Metrics
+	if !strings.HasPrefix(mc.MetricsPath(), "/") {
+		return fmt.Errorf("metrics path must start with /")
+	}
+	if mc.Admin {
+		if !config.Admin.Enabled {
+			return fmt.Errorf("metrics on the admin listener require the admin api to be enabled")
+		}
+		if strings.HasPrefix(mc.MetricsPath(), "/api/") {
+			return fmt.Errorf("metrics path %s is used by the admin api", mc.MetricsPath())
+		}
+	} else {
+		for i, route := range config.Routes {
+			if strings.Trim(route.Path, "/") == strings.Trim(mc.MetricsPath(), "/") {
+				return fmt.Errorf("route %d: path %s is used by the metrics endpoint", i, route.Path)
+			}
+		}
+	}
+	return validateIPRules("metrics", mc.Allow, nil)
+}
+
+// validateHealth checks that the health endpoints have distinct paths that
+// no route or the metrics endpoint uses
+func validateHealth(config *Config) error {
+	hc := config.Health
+	if hc.CertExpiry < 0 {
+		return fmt.Errorf("health cert_expiry cannot be negative")
+	}
+	paths := map[string]string{"liveness_path": hc.Liveness(), "readiness_path": hc.Readiness()}
+	for name, path := range paths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("health %s must start with /", name)
+		}
+		for i, route := range config.Routes {
+			if strings.Trim(route.Path, "/") == strings.Trim(path, "/") {
+				return fmt.Errorf("route %d: path %s is used by the health endpoints", i, route.Path)
+			}
+		}
+		if config.Metrics.Enabled && !config.Metrics.Admin && path == config.Metrics.MetricsPath() {
+			return fmt.Errorf("health %s %s is used by the metrics endpoint", name, path)
+		}
+	}
+	if hc.Liveness() == hc.Readiness() {
+		return fmt.Errorf("health liveness_path and readiness_path must differ")
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is a valid HTTP header name
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validateTracing checks the tracing exporter settings
+func validateTracing(tc *TracingConfig) error {
+	if tc.SampleRatio < 0 || tc.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample_ratio must be between 0 and 1")
+	}
+	switch tc.ExporterName() {
+	case TracingExporterFile:
+		if tc.File == "" {
+			return fmt.Errorf("tracing file cannot be empty with the file exporter")
+		}
+		return nil
+	case TracingExporterOTLP:
+	default:
+		return fmt.Errorf("invalid tracing exporter %s, must be one of: otlp, file", tc.Exporter)
+	}
+
+	switch tc.ProtocolName() {
+	case OTLPProtocolHTTPProtobuf, OTLPProtocolHTTPJSON, OTLPProtocolGRPC:
+	default:
+		return fmt.Errorf("invalid tracing protocol %s, must be one of: http/protobuf, http/json, grpc", tc.Protocol)
+	}
+	u, err := url.Parse(tc.EndpointURL())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid tracing endpoint %s, must be an http or https URL", tc.Endpoint)
+	}
+	for name := range tc.Headers {
+		if name == "" {
+			return fmt.Errorf("tracing header names cannot be empty")
+		}
+	}
+	return nil
+}
+
+// validateAdmin checks the admin API settings
+func validateAdmin(ac *AdminConfig, listeners []ListenerConfig) error {
+	if len(ac.Token) < 16 {
+		return fmt.Errorf("admin token must be at least 16 characters")
+	}
+	lc := ac.Listener()
+	anyPort := false
+	switch lc.NetworkName() {
+	case "tcp", "tcp6":
+		_, port, err := net.SplitHostPort(lc.Address)
+		if err != nil {
+			return fmt.Errorf("admin: invalid address %q: %w", lc.Address, err)
+		}
+		anyPort = port == "0"
+		if lc.Mode != "" {
+			return fmt.Errorf("admin: mode only applies to unix sockets")
+		}
+	case "unix":
+		if lc.Address == "" {
+			return fmt.Errorf("admin: unix socket path cannot be empty")
+		}
+		if _, err := lc.SocketMode(); err != nil {
+			return fmt.Errorf("admin: %w", err)
+		}
+	default:
+		return fmt.Errorf("admin: unsupported network %q (expected tcp, tcp6 or unix)", lc.Network)
+	}
+	for _, other := range listeners {
+		if !anyPort && other.NetworkName() == lc.NetworkName() && other.Address == lc.Address {
+			return fmt.Errorf("admin: address %s is already used by a server listener", lc.Address)
+		}
+	}
+	return validateIPRules("admin", ac.Allow, nil)
+}
+
+// validateListeners checks the listener list: addresses must be valid for
+// their network and unique, route restrictions must name configured routes
+// and at most one listener may obtain certificates via ACME
+func validateListeners(listeners []ListenerConfig, routes []RouteConfig) error {
+	seen := make(map[string]bool)
+	acmeListeners := 0
+	for i, lc := range listeners {
+		network := lc.NetworkName()
+		port := 0
+		switch network {
+		case "tcp", "tcp6":
+			host, portStr, err := net.SplitHostPort(lc.Address)
+			if err != nil {
+				return fmt.Errorf("listener %d: invalid address %q: %w", i, lc.Address, err)
+			}
+			port, err = strconv.Atoi(portStr)
+			if err != nil || port < 0 || port > 65535 {
+				return fmt.Errorf("listener %d: port must be between 0 and 65535, got %q", i, portStr)
+			}
+			if ip := net.ParseIP(host); network == "tcp6" && ip != nil && ip.To4() != nil {
+				return fmt.Errorf("listener %d: tcp6 listener cannot use IPv4 address %s", i, host)
+			}
+			if lc.Mode != "" {
+				return fmt.Errorf("listener %d: mode only applies to unix sockets", i)
+			}
+		case "unix":
+			if lc.Address == "" {
+				return fmt.Errorf("listener %d: unix socket path cannot be empty", i)
+			}
+			if _, err := lc.SocketMode(); err != nil {
+				return fmt.Errorf("listener %d: %w", i, err)
+			}
+			if lc.TLS.RedirectPort != 0 {
+				return fmt.Errorf("listener %d: tls redirect_port requires a tcp listener", i)
+			}
+		default:
+			return fmt.Errorf("listener %d: unsupported network %q (expected tcp, tcp6 or unix)", i, lc.Network)
+		}
+
+		key := network + " " + lc.Address
+		if seen[key] && port != 0 {
+			return fmt.Errorf("listener %d: duplicate address %s", i, lc.Address)
+		}
+		seen[key] = true
+
+		if lc.TLS.Enabled {
+			if err := validateTLS(&lc.TLS, port); err != nil {
+				return fmt.Errorf("listener %d: %w", i, err)
+			}
+			if lc.TLS.ACME.Enabled {
+				acmeListeners++
+			}
+		}
+
+		for _, path := range lc.Routes {
+			found := false
+			for _, route := range routes {
+				if strings.Trim(route.Path, "/") == strings.Trim(path, "/") {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("listener %d: unknown route %q", i, path)
+			}
+		}
+	}
+	if acmeListeners > 1 {
+		return fmt.Errorf("acme can only be enabled on one listener")
+	}
+	return nil
+}
+
+// validateTLS checks the HTTPS settings
+func validateTLS(tc *TLSConfig, port int) error {
+	if tc.ACME.Enabled {
+		if tc.CertFile != "" || tc.KeyFile != "" || len(tc.Certificates) > 0 {
+			return fmt.Errorf("tls certificates cannot be configured together with acme")
+		}
+		if err := validateACME(&tc.ACME, tc.RedirectPort); err != nil {
+			return err
+		}
+	} else if tc.CertFile == "" || tc.KeyFile == "" {
+		return fmt.Errorf("tls cert_file and key_file cannot be empty when tls is enabled")
+	}
+	for i, cert := range tc.Certificates {
+		if cert.CertFile == "" || cert.KeyFile == "" {
+			return fmt.Errorf("tls certificate %d: cert_file and key_file cannot be empty", i)
+		}
+	}
+	if _, err := tlsutil.ParseVersion(tc.MinVersion); err != nil {
+		return err
+	}
+	if _, err := tlsutil.ParseCipherSuites(tc.CipherSuites); err != nil {
+		return err
+	}
+	if err := tlsutil.ValidateALPN(tc.ALPN); err != nil {
+		return err
+	}
+	if tc.RedirectPort < 0 || tc.RedirectPort > 65535 {
+		return fmt.Errorf("tls redirect_port must be between 0 and 65535, got %d", tc.RedirectPort)
+	}
+	if tc.RedirectPort != 0 && tc.RedirectPort == port {
+		return fmt.Errorf("tls redirect_port must differ from the server port")
+	}
+	return nil
+}
+
+// validateACME checks the automatic certificate settings
+func validateACME(ac *ACMEConfig, redirectPort int) error {
+	if len(ac.Domains) == 0 {
+		return fmt.Errorf("acme domains cannot be empty when acme is enabled")
+	}
+	for _, domain := range ac.Domains {
+		if domain == "" || strings.ContainsAny(domain, "*/: ") {
+			return fmt.Errorf("invalid acme domain %q; wildcards are not supported", domain)
+		}
+	}
+	if ac.DataDir == "" {
+		return fmt.Errorf("acme data_dir cannot be empty when acme is enabled")
+	}
+	if ac.DirectoryURL != "" {
+		u, err := url.Parse(ac.DirectoryURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("acme directory_url must be an https URL, got %q", ac.DirectoryURL)
+		}
+	}
+	for _, challenge := range ac.Challenges {
+		switch challenge {
+		case "tls-alpn-01":
+		case "http-01":
+			if redirectPort == 0 {
+				return fmt.Errorf("acme http-01 challenge requires tls redirect_port")
+			}
+		default:
+			return fmt.Errorf("invalid acme challenge %q, must be one of: http-01, tls-alpn-01", challenge)
+		}
+	}
+	if ac.RenewBefore < 0 {
+		return fmt.Errorf("acme renew_before cannot be negative")
+	}
+	return nil
+}
+
+// validateOIDC checks the OpenID Connect settings
+func validateOIDC(oidc *OIDCConfig) error {
+	if oidc.Issuer == "" {
+		return fmt.Errorf("oidc issuer cannot be empty when oidc is enabled")
+	}
+	if oidc.ClientID == "" {
+		return fmt.Errorf("oidc client_id cannot be empty when oidc is enabled")
+	}
+	if oidc.CookieSecret == "" {
+		return fmt.Errorf("oidc cookie_secret cannot be empty when oidc is enabled")
+	}
+	redirect, err := url.Parse(oidc.RedirectURL)
+	if err != nil || redirect.Scheme == "" || redirect.Host == "" {
+		return fmt.Errorf("oidc redirect_url must be an absolute URL, got %q", oidc.RedirectURL)
+	}
+	if oidc.SessionTTL < 0 {
+		return fmt.Errorf("oidc session_ttl cannot be negative")
+	}
+	return nil
+}
+
+// validateLDAP checks the LDAP settings
+func validateLDAP(ldap *LDAPConfig) error {
+	u, err := url.Parse(ldap.URL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("ldap url must be of the form ldaps://host[:port], got %q", ldap.URL)
+	}
+	switch u.Scheme {
+	case "ldaps":
+	case "ldap":
+		if !ldap.StartTLS {
+			return fmt.Errorf("ldap url %q is unencrypted; use ldaps:// or enable start_tls", ldap.URL)
+		}
+	default:
+		return fmt.Errorf("unsupported ldap url scheme %q", u.Scheme)
+	}
+	if ldap.BaseDN == "" {
+		return fmt.Errorf("ldap base_dn cannot be empty when ldap is enabled")
+	}
+	if ldap.UserFilter != "" && !strings.Contains(ldap.UserFilter, "%s") {
+		return fmt.Errorf("ldap user_filter must contain %%s as the username placeholder")
+	}
+	if ldap.CacheTTL < 0 || ldap.Timeout < 0 {
+		return fmt.Errorf("ldap cache_ttl and timeout cannot be negative")
+	}
+	return nil
+}
+
+// validateClientCert checks the client certificate settings. Clients can
+// only present certificates over TLS, so a listener must serve it.
+func validateClientCert(cc *ClientCertConfig, listeners []ListenerConfig) error {
+	if cc.CAFile == "" {
+		return fmt.Errorf("client_cert ca_file cannot be empty when client_cert is enabled")
+	}
+	tls := false
+	for _, lc := range listeners {
+		tls = tls || lc.TLS.Enabled
+	}
+	if !tls {
+		return fmt.Errorf("client_cert requires tls to be enabled on at least one listener")
+	}
+	switch cc.UsernameFrom {
+	case "", "cn", "dns", "email", "uri":
+	default:
+		return fmt.Errorf("invalid client_cert username_from %q, must be one of: cn, dns, email, uri", cc.UsernameFrom)
+	}
+	return nil
+}
+
+// validateLockout checks the brute-force protection settings
+func validateLockout(lc *LockoutConfig) error {
+	if lc.MaxAttempts < 0 || lc.MaxAttemptsPerIP < 0 {
+		return fmt.Errorf("lockout attempt limits cannot be negative")
+	}
+	if lc.Window < 0 || lc.Duration < 0 || lc.MaxDuration < 0 {
+		return fmt.Errorf("lockout durations cannot be negative")
+	}
+	if lc.MaxDuration > 0 && lc.Duration > lc.MaxDuration {
+		return fmt.Errorf("lockout duration cannot exceed max_duration")
+	}
+	if lc.AdminPath != "" && !strings.HasPrefix(lc.AdminPath, "/") {
+		return fmt.Errorf("lockout admin_path must start with /")
+	}
+	return nil
+}
 
+// validateIPRules checks that allow and deny entries are CIDR ranges or addresses
+func validateIPRules(scope string, allow, deny []string) error {
+	if _, err := netutil.ParsePrefixes(allow); err != nil {
+		return fmt.Errorf("%s: invalid allow rule: %w", scope, err)
+	}
+	if _, err := netutil.ParsePrefixes(deny); err != nil {
+		return fmt.Errorf("%s: invalid deny rule: %w", scope, err)
+	}
 	return nil
 }
+
+// validateForm checks the login form settings
+func validateForm(fc *FormConfig) error {
+	for name, path := range map[string]string{"login_path": fc.LoginPath, "logout_path": fc.LogoutPath} {
+		if path != "" && !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("form %s must start with /", name)
+		}
+	}
+	if fc.SessionTTL < 0 {
+		return fmt.Errorf("form session_ttl cannot be negative")
+	}
+	if fc.Template != "" {
+		if _, err := os.Stat(fc.Template); err != nil {
+			return fmt.Errorf("form template: %w", err)
+		}
+	}
+	return nil
+}
+
+// methodEnabled reports whether a per-route authentication method is configured
+func (a AuthConfig) methodEnabled(method string) bool {
+	switch method {
+	case "basic":
+		return a.Enabled && a.Username != ""
+	case "ldap":
+		return a.LDAP.Enabled
+	case "password":
+		return a.LDAP.Enabled || (a.Enabled && a.Username != "")
+	case "oidc":
+		return a.OIDC.Enabled
+	case "client_cert":
+		return a.ClientCert.Enabled
+	case "form":
+		return a.Form.Enabled
+	default:
+		return false
+	}
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestConfigManager_Load(t *testing.T) {
@@ -269,15 +271,964 @@ func TestConfigManager_Validate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "oidc enabled",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth: AuthConfig{OIDC: OIDCConfig{
+					Enabled:      true,
+					Issuer:       "https://issuer.example.com",
+					ClientID:     "otterserve",
+					RedirectURL:  "https://files.example.com/auth/callback",
+					CookieSecret: "secret",
+				}},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: false,
+		},
+		{
+			name: "oidc with relative redirect url",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth: AuthConfig{OIDC: OIDCConfig{
+					Enabled:      true,
+					Issuer:       "https://issuer.example.com",
+					ClientID:     "otterserve",
+					RedirectURL:  "/auth/callback",
+					CookieSecret: "secret",
+				}},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "oidc without cookie secret",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth: AuthConfig{OIDC: OIDCConfig{
+					Enabled:     true,
+					Issuer:      "https://issuer.example.com",
+					ClientID:    "otterserve",
+					RedirectURL: "https://files.example.com/auth/callback",
+				}},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "ldap over ldaps",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth: AuthConfig{LDAP: LDAPConfig{
+					Enabled: true,
+					URL:     "ldaps://ldap.example.com",
+					BaseDN:  "dc=example,dc=com",
+				}},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir, Groups: []string{"staff"}},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: false,
+		},
+		{
+			name: "ldap without encryption",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth: AuthConfig{LDAP: LDAPConfig{
+					Enabled: true,
+					URL:     "ldap://ldap.example.com",
+					BaseDN:  "dc=example,dc=com",
+				}},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "ldap filter without placeholder",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth: AuthConfig{LDAP: LDAPConfig{
+					Enabled:    true,
+					URL:        "ldap://ldap.example.com",
+					StartTLS:   true,
+					BaseDN:     "dc=example,dc=com",
+					UserFilter: "(uid=alice)",
+				}},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "client cert combined with basic auth",
+			config: &Config{
+				Server: ServerConfig{
+					Host: "localhost",
+					Port: 1124,
+					TLS:  TLSConfig{Enabled: true, CertFile: "/etc/otterserve/cert.pem", KeyFile: "/etc/otterserve/key.pem"},
+				},
+				Auth: AuthConfig{
+					Enabled:    true,
+					Username:   "admin",
+					Password:   "secret",
+					ClientCert: ClientCertConfig{Enabled: true, CAFile: "/etc/otterserve/client-ca.pem"},
+				},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir, Auth: []string{"client_cert", "password"}},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: false,
+		},
+		{
+			name: "client cert without ca file",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth:   AuthConfig{ClientCert: ClientCertConfig{Enabled: true}},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "client cert without tls",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth:   AuthConfig{ClientCert: ClientCertConfig{Enabled: true, CAFile: "/etc/otterserve/client-ca.pem"}},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir, Auth: []string{"client_cert"}},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "route auth method not enabled",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth:   AuthConfig{Enabled: true, Username: "admin", Password: "secret"},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir, Auth: []string{"client_cert"}},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "lockout with relative admin path",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth: AuthConfig{
+					Enabled:  true,
+					Username: "admin",
+					Password: "secret",
+					Lockout:  LockoutConfig{Enabled: true, AdminPath: "admin/lockouts"},
+				},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "ip allow and deny lists",
+			config: &Config{
+				Server: ServerConfig{
+					Host:           "localhost",
+					Port:           1124,
+					Deny:           []string{"203.0.113.0/24"},
+					TrustedProxies: []string{"127.0.0.1", "10.0.0.0/8"},
+				},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir, Allow: []string{"192.168.1.0/24", "2001:db8::/32"}},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid route cidr",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir, Allow: []string{"192.168.1.0/33"}},
+				},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid trusted proxy",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124, TrustedProxies: []string{"proxy.local"}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "form login with basic credentials",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth: AuthConfig{
+					Enabled:  true,
+					Username: "admin",
+					Password: "secret",
+					Form:     FormConfig{Enabled: true, LoginPath: "/signin"},
+				},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: false,
+		},
+		{
+			name: "form login without password method",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Auth:    AuthConfig{Form: FormConfig{Enabled: true}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "totp without secrets file",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Auth: AuthConfig{
+					Enabled:  true,
+					Username: "admin",
+					Password: "secret",
+					TOTP:     TOTPConfig{Enabled: true},
+				},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
 		{
 			name: "invalid log level",
 			config: &Config{
 				Server: ServerConfig{Host: "localhost", Port: 1124},
-				Auth:   AuthConfig{Enabled: false},
-				Routes: []RouteConfig{
-					{Path: "/static", Directory: staticDir},
-				},
-				Logging: LoggingConfig{Level: "invalid"},
+				Auth:   AuthConfig{Enabled: false},
+				Routes: []RouteConfig{
+					{Path: "/static", Directory: staticDir},
+				},
+				Logging: LoggingConfig{Level: "invalid"},
+			},
+			expectError: true,
+		},
+		{
+			name: "tls with sni certificates",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 8443, TLS: TLSConfig{
+					Enabled:      true,
+					CertFile:     "/etc/otterserve/files.pem",
+					KeyFile:      "/etc/otterserve/files.key",
+					Certificates: []CertificateConfig{{CertFile: "/etc/otterserve/docs.pem", KeyFile: "/etc/otterserve/docs.key"}},
+					MinVersion:   "1.3",
+					ALPN:         []string{"http/1.1"},
+					RedirectPort: 8080,
+				}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: false,
+		},
+		{
+			name: "tls without key file",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 8443, TLS: TLSConfig{Enabled: true, CertFile: "/etc/otterserve/files.pem"}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "tls with insecure cipher suite",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 8443, TLS: TLSConfig{
+					Enabled:      true,
+					CertFile:     "/etc/otterserve/files.pem",
+					KeyFile:      "/etc/otterserve/files.key",
+					CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"},
+				}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "tls redirect on server port",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 8443, TLS: TLSConfig{
+					Enabled:      true,
+					CertFile:     "/etc/otterserve/files.pem",
+					KeyFile:      "/etc/otterserve/files.key",
+					RedirectPort: 8443,
+				}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "tcp and unix listeners",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1123, Listeners: []ListenerConfig{
+					{Address: "127.0.0.1:1123"},
+					{Network: "tcp6", Address: "[::1]:1123"},
+					{Network: "unix", Address: "/run/otterserve/http.sock", Mode: "0660", Routes: []string{"static"}},
+				}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: false,
+		},
+		{
+			name: "listener with unknown network",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1123, Listeners: []ListenerConfig{{Network: "udp", Address: "127.0.0.1:1123"}}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "listener with invalid socket mode",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1123, Listeners: []ListenerConfig{{Network: "unix", Address: "/run/otter.sock", Mode: "rw-rw----"}}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "listener with unknown route",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1123, Listeners: []ListenerConfig{{Address: "127.0.0.1:1123", Routes: []string{"/docs"}}}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "duplicate listener address",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1123, Listeners: []ListenerConfig{
+					{Address: "127.0.0.1:1123"},
+					{Network: "tcp", Address: "127.0.0.1:1123"},
+				}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "server tls together with listeners",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1123,
+					TLS:       TLSConfig{Enabled: true, CertFile: "/etc/otterserve/files.pem", KeyFile: "/etc/otterserve/files.key"},
+					Listeners: []ListenerConfig{{Address: "127.0.0.1:1123"}},
+				},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "tls with acme",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 443, TLS: TLSConfig{
+					Enabled:      true,
+					RedirectPort: 80,
+					ACME: ACMEConfig{
+						Enabled:      true,
+						DirectoryURL: "https://localhost:14000/dir",
+						Domains:      []string{"files.example.com"},
+						DataDir:      "/var/lib/otterserve/acme",
+						Challenges:   []string{"tls-alpn-01", "http-01"},
+					},
+				}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: false,
+		},
+		{
+			name: "acme http-01 without redirect port",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 443, TLS: TLSConfig{
+					Enabled: true,
+					ACME: ACMEConfig{
+						Enabled:    true,
+						Domains:    []string{"files.example.com"},
+						DataDir:    "/var/lib/otterserve/acme",
+						Challenges: []string{"http-01"},
+					},
+				}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "acme wildcard domain",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 443, TLS: TLSConfig{
+					Enabled: true,
+					ACME:    ACMEConfig{Enabled: true, Domains: []string{"*.example.com"}, DataDir: "/var/lib/otterserve/acme"},
+				}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "acme with certificate files",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 443, TLS: TLSConfig{
+					Enabled:  true,
+					CertFile: "/etc/otterserve/files.pem",
+					KeyFile:  "/etc/otterserve/files.key",
+					ACME:     ACMEConfig{Enabled: true, Domains: []string{"files.example.com"}, DataDir: "/var/lib/otterserve/acme"},
+				}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "audit log without file",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Audit: AuditConfig{Enabled: true}},
+			},
+			expectError: true,
+		},
+		{
+			name: "audit log with hash chain",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Routes: []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{
+					Level: "info",
+					Audit: AuditConfig{Enabled: true, File: filepath.Join(tempDir, "audit.log"), HashChain: true},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "watched configuration",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124, Reload: ReloadConfig{Watch: true, Interval: 5 * time.Second}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: false,
+		},
+		{
+			name: "admin api",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Admin:   AdminConfig{Enabled: true, Address: "127.0.0.1:1125", Token: "0123456789abcdef", Allow: []string{"127.0.0.1"}},
+			},
+			expectError: false,
+		},
+		{
+			name: "admin api with short token",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Admin:   AdminConfig{Enabled: true, Address: "127.0.0.1:1125", Token: "secret"},
+			},
+			expectError: true,
+		},
+		{
+			name: "admin api on a server address",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Admin:   AdminConfig{Enabled: true, Address: "localhost:1124", Token: "0123456789abcdef"},
+			},
+			expectError: true,
+		},
+		{
+			name: "admin api on a unix socket with mode",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Admin:   AdminConfig{Enabled: true, Network: "unix", Address: "/run/otter-admin.sock", Mode: "0600", Token: "0123456789abcdef"},
+			},
+			expectError: false,
+		},
+		{
+			name: "admin api with mode on tcp",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Admin:   AdminConfig{Enabled: true, Address: "127.0.0.1:1125", Mode: "0600", Token: "0123456789abcdef"},
+			},
+			expectError: true,
+		},
+		{
+			name: "metrics on server listeners",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Metrics: MetricsConfig{Enabled: true, Allow: []string{"10.0.0.0/8"}},
+			},
+			expectError: false,
+		},
+		{
+			name: "metrics path used by a route",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/metrics/", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Metrics: MetricsConfig{Enabled: true},
+			},
+			expectError: true,
+		},
+		{
+			name: "metrics on admin listener without admin api",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Metrics: MetricsConfig{Enabled: true, Admin: true},
+			},
+			expectError: true,
+		},
+		{
+			name: "custom health paths",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Health:  HealthConfig{LivenessPath: "/live", ReadinessPath: "/ready", CertExpiry: 24 * time.Hour},
+			},
+			expectError: false,
+		},
+		{
+			name: "health path used by a route",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/healthz", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "health paths equal",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Health:  HealthConfig{LivenessPath: "/health", ReadinessPath: "/health"},
+			},
+			expectError: true,
+		},
+		{
+			name: "health path without leading slash",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Health:  HealthConfig{ReadinessPath: "ready"},
+			},
+			expectError: true,
+		},
+		{
+			name: "otlp tracing with defaults",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Tracing: TracingConfig{Enabled: true},
+			},
+			expectError: false,
+		},
+		{
+			name: "grpc tracing",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Tracing: TracingConfig{Enabled: true, Protocol: OTLPProtocolGRPC, Endpoint: "https://collector:4317"},
+			},
+			expectError: false,
+		},
+		{
+			name: "file tracing without file",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Tracing: TracingConfig{Enabled: true, Exporter: TracingExporterFile},
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown tracing exporter",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Tracing: TracingConfig{Enabled: true, Exporter: "zipkin"},
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown tracing protocol",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Tracing: TracingConfig{Enabled: true, Protocol: "thrift"},
+			},
+			expectError: true,
+		},
+		{
+			name: "tracing endpoint without scheme",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Tracing: TracingConfig{Enabled: true, Endpoint: "collector:4318"},
+			},
+			expectError: true,
+		},
+		{
+			name: "tracing sample ratio above one",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+				Tracing: TracingConfig{Enabled: true, SampleRatio: 1.5},
+			},
+			expectError: true,
+		},
+		{
+			name: "custom request ID header",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124, RequestIDHeader: "X-Correlation-ID"},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid request ID header",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124, RequestIDHeader: "X Request ID"},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
+			},
+			expectError: true,
+		},
+		{
+			name: "json log format in UTC",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Format: LogFormatJSON, TimeZone: "UTC", TimePrecision: "ms"},
+			},
+			expectError: false,
+		},
+		{
+			name: "unknown log format",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Format: "xml"},
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown log time zone",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", TimeZone: "Mars/Olympus_Mons"},
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown log time precision",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", TimePrecision: "minutes"},
+			},
+			expectError: true,
+		},
+		{
+			name: "log rotation",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", File: "otterserve.log", Rotation: RotationConfig{MaxSize: 50, MaxAge: 168 * time.Hour, Compress: true}},
+			},
+			expectError: false,
+		},
+		{
+			name: "log rotation without file",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Rotation: RotationConfig{MaxBackups: 3}},
+			},
+			expectError: true,
+		},
+		{
+			name: "negative audit log rotation",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Routes: []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Audit: AuditConfig{
+					Enabled:  true,
+					File:     "audit.log",
+					Rotation: RotationConfig{MaxAge: -time.Hour},
+				}},
+			},
+			expectError: true,
+		},
+		{
+			name: "completed request lines",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Requests: RequestLinesCompleted},
+			},
+			expectError: false,
+		},
+		{
+			name: "unknown request lines",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Requests: "started"},
+			},
+			expectError: true,
+		},
+		{
+			name: "access log with rotation",
+			config: &Config{
+				Server: ServerConfig{Host: "localhost", Port: 1124},
+				Routes: []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Access: AccessLogConfig{
+					Enabled:  true,
+					File:     "access.log",
+					Format:   AccessLogJSON,
+					Rotation: RotationConfig{MaxSize: 100, MaxBackups: 5, MaxAge: 720 * time.Hour},
+				}},
+			},
+			expectError: false,
+		},
+		{
+			name: "unknown access log format",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Access: AccessLogConfig{Enabled: true, Format: "w3c"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "access log template format without template",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Access: AccessLogConfig{Enabled: true, Format: AccessLogTemplate}},
+			},
+			expectError: true,
+		},
+		{
+			name: "access log template with another format",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Access: AccessLogConfig{Enabled: true, Template: "{method} {path}"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "access log rotation without file",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Access: AccessLogConfig{Enabled: true, Rotation: RotationConfig{MaxSize: 10}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "negative access log rotation",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Access: AccessLogConfig{Enabled: true, File: "access.log", Rotation: RotationConfig{MaxBackups: -1}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "log sinks",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Sinks: []LogSinkConfig{{Type: LogSinkFile, Level: "debug", File: "debug.log", Format: LogFormatJSON}, {Type: LogSinkSyslog, Level: "error", Network: "udp", Address: "127.0.0.1:514", Facility: "local0"}}},
+			},
+			expectError: false,
+		},
+		{
+			name: "unknown log sink type",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Sinks: []LogSinkConfig{{Type: "kafka"}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid log sink level",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Sinks: []LogSinkConfig{{Type: LogSinkStdout, Level: "trace"}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "file log sink without file",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Sinks: []LogSinkConfig{{Type: LogSinkFile}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "syslog sink with file format",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Sinks: []LogSinkConfig{{Type: LogSinkSyslog, Network: "udp", Address: "127.0.0.1:514", Format: LogFormatJSON}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "syslog sink without address",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Sinks: []LogSinkConfig{{Type: LogSinkSyslog, Network: "tcp"}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown syslog facility",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Sinks: []LogSinkConfig{{Type: LogSinkSyslog, Network: "udp", Address: "127.0.0.1:514", Facility: "local9"}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown syslog network",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Sinks: []LogSinkConfig{{Type: LogSinkSyslog, Network: "http", Address: "127.0.0.1:514"}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "journald sink with facility",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Sinks: []LogSinkConfig{{Type: LogSinkJournald, Facility: "daemon"}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "async logging",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Async: AsyncLogConfig{Enabled: true, BufferSize: 1024, Overflow: LogOverflowBlock}},
+			},
+			expectError: false,
+		},
+		{
+			name: "negative async log buffer",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Async: AsyncLogConfig{Enabled: true, BufferSize: -1}},
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown async log overflow",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Async: AsyncLogConfig{Enabled: true, Overflow: "wait"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "log redaction",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Redact: RedactConfig{Fields: []string{"session"}, Patterns: []string{`sk_live_\w+`}, QueryParams: []string{"share"}}},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid log redact pattern",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info", Redact: RedactConfig{Patterns: []string{"("}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "negative reload interval",
+			config: &Config{
+				Server:  ServerConfig{Host: "localhost", Port: 1124, Reload: ReloadConfig{Watch: true, Interval: -time.Second}},
+				Routes:  []RouteConfig{{Path: "/static", Directory: staticDir}},
+				Logging: LoggingConfig{Level: "info"},
 			},
 			expectError: true,
 		},
@@ -490,3 +1441,57 @@ func TestConfigManager_CreateDefaultDirectories(t *testing.T) {
 		}
 	}
 }
+
+func TestServerConfig_ListenerConfigs(t *testing.T) {
+	sc := ServerConfig{Host: "::1", Port: 8443, TLS: TLSConfig{Enabled: true}}
+	listeners := sc.ListenerConfigs()
+	if len(listeners) != 1 {
+		t.Fatalf("Expected one implicit listener, got %d", len(listeners))
+	}
+	if listeners[0].NetworkName() != "tcp" || listeners[0].Address != "[::1]:8443" || !listeners[0].TLS.Enabled {
+		t.Errorf("Unexpected implicit listener: %+v", listeners[0])
+	}
+
+	sc.Listeners = []ListenerConfig{{Network: "unix", Address: "/run/otter.sock", Mode: "0660", Routes: []string{"/docs/"}}}
+	listeners = sc.ListenerConfigs()
+	if len(listeners) != 1 || listeners[0].Address != "/run/otter.sock" {
+		t.Fatalf("Expected configured listeners, got %+v", listeners)
+	}
+	if mode, err := listeners[0].SocketMode(); err != nil || mode != 0660 {
+		t.Errorf("Expected socket mode 0660, got %v (%v)", mode, err)
+	}
+	if !listeners[0].ExposesRoute("docs") || listeners[0].ExposesRoute("/static") {
+		t.Error("Expected only the docs route to be exposed")
+	}
+}
+
+func TestConfigManager_Validate_PlatformLogSinks(t *testing.T) {
+	cm := NewConfigManager()
+	base := func(sink LogSinkConfig) *Config {
+		return &Config{
+			Server:  ServerConfig{Host: "localhost", Port: 1124},
+			Routes:  []RouteConfig{{Path: "/static", Directory: t.TempDir()}},
+			Logging: LoggingConfig{Level: "info", Sinks: []LogSinkConfig{sink}},
+		}
+	}
+
+	err := cm.Validate(base(LogSinkConfig{Type: LogSinkJournald}))
+	if (runtime.GOOS == "linux") != (err == nil) {
+		t.Errorf("Unexpected journald validation result on %s: %v", runtime.GOOS, err)
+	}
+	err = cm.Validate(base(LogSinkConfig{Type: LogSinkSyslog}))
+	if (runtime.GOOS != "windows") != (err == nil) {
+		t.Errorf("Unexpected unix syslog validation result on %s: %v", runtime.GOOS, err)
+	}
+}
+
+func TestLogSinkConfig_Defaults(t *testing.T) {
+	var sc LogSinkConfig
+	if sc.LevelName("warn") != "warn" || sc.NetworkName() != "unix" || sc.FacilityName() != "daemon" || sc.TagName() != "otterserve" {
+		t.Errorf("Unexpected defaults %q %q %q %q", sc.LevelName("warn"), sc.NetworkName(), sc.FacilityName(), sc.TagName())
+	}
+	sc = LogSinkConfig{Level: "error", Network: "tcp", Facility: "local3", Tag: "files"}
+	if sc.LevelName("warn") != "error" || sc.NetworkName() != "tcp" || sc.FacilityName() != "local3" || sc.TagName() != "files" {
+		t.Error("Expected configured values to override the defaults")
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"otterserve/internal/requestid"
+	"otterserve/internal/tracing"
 )
 
 // FileServer interface defines file serving operations
@@ -38,27 +41,33 @@ func (fs *DefaultFileServer) ServeFiles(w http.ResponseWriter, r *http.Request,
 	// Clean the path to prevent directory traversal attacks
 	cleanPath := filepath.Clean(relativePath)
 	if strings.HasPrefix(cleanPath, "..") || filepath.IsAbs(cleanPath) {
-		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		requestid.Error(w, r, "403 Forbidden", http.StatusForbidden)
 		return
 	}
 
 	// Construct the full file path
 	fullPath := filepath.Join(directory, cleanPath)
 
+	ctx, span := tracing.Start(r.Context(), "fileserver.serve", tracing.String("file.path", cleanPath))
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	// Get file info
 	fileInfo, err := os.Stat(fullPath)
 	if err != nil {
+		span.SetError(err)
 		if os.IsNotExist(err) {
-			http.Error(w, "404 Not Found", http.StatusNotFound)
+			requestid.Error(w, r, "404 Not Found", http.StatusNotFound)
 		} else if os.IsPermission(err) {
-			http.Error(w, "403 Forbidden", http.StatusForbidden)
+			requestid.Error(w, r, "403 Forbidden", http.StatusForbidden)
 		} else {
-			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			requestid.Error(w, r, "500 Internal Server Error", http.StatusInternalServerError)
 		}
 		return
 	}
 
 	// If it's a directory, try to serve index file or show directory listing
+	span.SetAttributes(tracing.Bool("file.directory", fileInfo.IsDir()))
 	if fileInfo.IsDir() {
 		fs.handleDirectory(w, r, fullPath, basePath, relativePath)
 		return
@@ -86,13 +95,17 @@ func (fs *DefaultFileServer) handleDirectory(w http.ResponseWriter, r *http.Requ
 
 // serveFile serves a single file
 func (fs *DefaultFileServer) serveFile(w http.ResponseWriter, r *http.Request, filePath string, fileInfo os.FileInfo) {
+	_, span := tracing.Start(r.Context(), "fileserver.read", tracing.Int("file.size", fileInfo.Size()))
+	defer span.End()
+
 	// Open the file
 	file, err := os.Open(filePath)
 	if err != nil {
+		span.SetError(err)
 		if os.IsPermission(err) {
-			http.Error(w, "403 Forbidden", http.StatusForbidden)
+			requestid.Error(w, r, "403 Forbidden", http.StatusForbidden)
 		} else {
-			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			requestid.Error(w, r, "500 Internal Server Error", http.StatusInternalServerError)
 		}
 		return
 	}
@@ -115,13 +128,17 @@ func (fs *DefaultFileServer) serveFile(w http.ResponseWriter, r *http.Request, f
 
 // ListDirectory generates and serves a directory listing
 func (fs *DefaultFileServer) ListDirectory(w http.ResponseWriter, r *http.Request, directory string) {
+	_, span := tracing.Start(r.Context(), "fileserver.readdir")
+	defer span.End()
+
 	// Read directory contents
 	entries, err := os.ReadDir(directory)
 	if err != nil {
+		span.SetError(err)
 		if os.IsPermission(err) {
-			http.Error(w, "403 Forbidden", http.StatusForbidden)
+			requestid.Error(w, r, "403 Forbidden", http.StatusForbidden)
 		} else {
-			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			requestid.Error(w, r, "500 Internal Server Error", http.StatusInternalServerError)
 		}
 		return
 	}
@@ -142,6 +159,8 @@ func (fs *DefaultFileServer) ListDirectory(w http.ResponseWriter, r *http.Reques
 		})
 	}
 
+	span.SetAttributes(tracing.Int("file.entries", int64(len(files))))
+
 	// Sort files: directories first, then by name
 	sort.Slice(files, func(i, j int) bool {
 		if files[i].IsDir != files[j].IsDir {
@@ -159,7 +178,7 @@ func (fs *DefaultFileServer) ListDirectory(w http.ResponseWriter, r *http.Reques
 	}
 
 	if err := directoryTemplate.Execute(w, data); err != nil {
-		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		requestid.Error(w, r, "500 Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
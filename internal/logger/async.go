@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"otterserve/internal/metrics"
+)
+
+// Overflow policies of asynchronous output, applied when the buffer is full
+const (
+	OverflowDrop  = "drop"  // discard the message and count it
+	OverflowBlock = "block" // wait until the buffer has room
+)
+
+// DefaultAsyncBufferSize is the number of messages an asynchronous output
+// holds unless configured
+const DefaultAsyncBufferSize = 8192
+
+var messagesDropped = metrics.Default.NewCounterVec("otterserve_log_messages_dropped_total",
+	"Log messages discarded because the asynchronous log buffer was full.")
+
+// AsyncOptions selects asynchronous output: messages are queued and written
+// in batches by a background goroutine, so logging never waits for the disk
+type AsyncOptions struct {
+	Enabled    bool
+	BufferSize int    // messages held; DefaultAsyncBufferSize when zero
+	Overflow   string // drop (default) or block
+}
+
+// validate checks the options without creating a writer
+func (o AsyncOptions) validate() error {
+	if o.BufferSize < 0 {
+		return fmt.Errorf("log buffer size cannot be negative")
+	}
+	switch o.Overflow {
+	case "", OverflowDrop, OverflowBlock:
+		return nil
+	}
+	return fmt.Errorf("invalid log overflow policy %s, must be one of: drop, block", o.Overflow)
+}
+
+// AsyncWriter queues each write in a bounded ring buffer and passes the
+// queued writes to the underlying writer in batches from one goroutine.
+// Every write is taken to be one message. Writers are flushed by FlushAll.
+type AsyncWriter struct {
+	w     io.Writer
+	block bool
+
+	mu      sync.Mutex
+	cond    *sync.Cond // signalled when messages are queued or written
+	ring    [][]byte   // slots keep their arrays to avoid allocating
+	head    int        // oldest queued message
+	count   int        // queued messages
+	writing bool       // a batch is being written
+	closed  bool
+
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+// asyncWriters holds the writers that have not been closed, for FlushAll
+var (
+	asyncMu      sync.Mutex
+	asyncWriters = make(map[*AsyncWriter]struct{})
+)
+
+// NewAsyncWriter starts writing to w in the background
+func NewAsyncWriter(w io.Writer, opts AsyncOptions) (*AsyncWriter, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	size := opts.BufferSize
+	if size == 0 {
+		size = DefaultAsyncBufferSize
+	}
+	a := &AsyncWriter{
+		w:     w,
+		block: opts.Overflow == OverflowBlock,
+		ring:  make([][]byte, size),
+		done:  make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mu)
+	go a.run()
+
+	asyncMu.Lock()
+	asyncWriters[a] = struct{}{}
+	asyncMu.Unlock()
+	return a, nil
+}
+
+// FlushAll waits until every open asynchronous writer has written what is
+// queued, as before the process exits
+func FlushAll() {
+	asyncMu.Lock()
+	open := make([]*AsyncWriter, 0, len(asyncWriters))
+	for a := range asyncWriters {
+		open = append(open, a)
+	}
+	asyncMu.Unlock()
+
+	for _, a := range open {
+		a.Flush()
+	}
+}
+
+// Write queues a copy of p. When the buffer is full, p is dropped or the
+// call waits, depending on the overflow policy. After Close, p is written
+// directly once the queued messages are.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	for a.count == len(a.ring) && a.block && !a.closed {
+		a.cond.Wait()
+	}
+	if a.closed {
+		a.mu.Unlock()
+		<-a.done
+		return a.w.Write(p)
+	}
+	if a.count == len(a.ring) {
+		a.mu.Unlock()
+		a.dropped.Add(1)
+		messagesDropped.WithLabelValues().Inc()
+		// Report success: the caller cannot do anything about it
+		return len(p), nil
+	}
+	i := (a.head + a.count) % len(a.ring)
+	a.ring[i] = append(a.ring[i][:0], p...)
+	a.count++
+	a.mu.Unlock()
+	a.cond.Broadcast()
+	return len(p), nil
+}
+
+// Dropped returns the number of messages discarded because the buffer was
+// full
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Flush waits until the queued messages are written
+func (a *AsyncWriter) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for (a.count > 0 || a.writing) && !a.closed {
+		a.cond.Wait()
+	}
+}
+
+// Close writes the queued messages and stops the goroutine. The underlying
+// writer is left open.
+func (a *AsyncWriter) Close() error {
+	asyncMu.Lock()
+	delete(asyncWriters, a)
+	asyncMu.Unlock()
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.mu.Unlock()
+	a.cond.Broadcast()
+	<-a.done
+	return nil
+}
+
+// run writes the queued messages as one batch whenever there are any.
+// Messages queued while a batch is written go in the next batch, so the
+// batches grow as the underlying writer slows down.
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	var batch []byte
+	for {
+		a.mu.Lock()
+		for a.count == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if a.count == 0 {
+			a.mu.Unlock()
+			return
+		}
+		batch = batch[:0]
+		for ; a.count > 0; a.count-- {
+			batch = append(batch, a.ring[a.head]...)
+			a.head = (a.head + 1) % len(a.ring)
+		}
+		a.writing = true
+		a.mu.Unlock()
+		// Writers waiting for room can go on while the batch is written
+		a.cond.Broadcast()
+
+		a.w.Write(batch)
+
+		a.mu.Lock()
+		a.writing = false
+		a.mu.Unlock()
+		a.cond.Broadcast()
+	}
+}
@@ -0,0 +1,246 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter holds up every write until released, recording what was
+// written and how often
+type gatedWriter struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}, 16), release: make(chan struct{})}
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	g.started <- struct{}{}
+	<-g.release
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writes++
+	return g.buf.Write(p)
+}
+
+func (g *gatedWriter) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.String()
+}
+
+func TestAsyncWriter_BatchesInOrder(t *testing.T) {
+	g := newGatedWriter()
+	a, err := NewAsyncWriter(g, AsyncOptions{Enabled: true, BufferSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	a.Write([]byte("a\n"))
+	<-g.started
+	// Messages queued while a batch is written go out together
+	buf := []byte("b\n")
+	a.Write(buf)
+	copy(buf, "x\n") // the writer keeps its own copy
+	a.Write([]byte("c\n"))
+	close(g.release)
+	a.Flush()
+
+	if got := g.String(); got != "a\nb\nc\n" {
+		t.Errorf("Expected the messages in order, got %q", got)
+	}
+	if g.writes != 2 {
+		t.Errorf("Expected 2 batches, got %d", g.writes)
+	}
+}
+
+func TestAsyncWriter_DropsWhenFull(t *testing.T) {
+	g := newGatedWriter()
+	a, _ := NewAsyncWriter(g, AsyncOptions{Enabled: true, BufferSize: 2})
+	defer a.Close()
+
+	a.Write([]byte("a\n"))
+	<-g.started
+	for _, msg := range []string{"b\n", "c\n", "d\n"} {
+		if n, err := a.Write([]byte(msg)); n != len(msg) || err != nil {
+			t.Errorf("Expected writes to succeed when dropped, got %d, %v", n, err)
+		}
+	}
+	if a.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped message, got %d", a.Dropped())
+	}
+	close(g.release)
+	a.Flush()
+	if got := g.String(); got != "a\nb\nc\n" {
+		t.Errorf("Expected the message beyond the buffer to be dropped, got %q", got)
+	}
+}
+
+func TestAsyncWriter_BlocksWhenFull(t *testing.T) {
+	g := newGatedWriter()
+	a, _ := NewAsyncWriter(g, AsyncOptions{Enabled: true, BufferSize: 1, Overflow: OverflowBlock})
+	defer a.Close()
+
+	a.Write([]byte("a\n"))
+	<-g.started
+	a.Write([]byte("b\n"))
+	written := make(chan struct{})
+	go func() {
+		a.Write([]byte("c\n"))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("Expected the write to wait while the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(g.release)
+	<-written
+	a.Flush()
+	if got := g.String(); got != "a\nb\nc\n" || a.Dropped() != 0 {
+		t.Errorf("Expected every message to be written, got %q with %d dropped", got, a.Dropped())
+	}
+}
+
+func TestAsyncWriter_Close(t *testing.T) {
+	var buf bytes.Buffer
+	a, _ := NewAsyncWriter(&buf, AsyncOptions{Enabled: true})
+	a.Write([]byte("queued\n"))
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	a.Write([]byte("late\n"))
+	if got := buf.String(); got != "queued\nlate\n" {
+		t.Errorf("Expected queued messages written on Close and later ones directly, got %q", got)
+	}
+}
+
+func TestAsyncWriter_WriteWhileClosing(t *testing.T) {
+	g := newGatedWriter()
+	a, _ := NewAsyncWriter(g, AsyncOptions{Enabled: true})
+	a.Write([]byte("a\n"))
+	<-g.started
+	a.Write([]byte("b\n"))
+
+	closed := make(chan struct{})
+	go func() {
+		a.Close()
+		close(closed)
+	}()
+	for {
+		a.mu.Lock()
+		closing := a.closed
+		a.mu.Unlock()
+		if closing {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A message logged while the queue is drained goes after it
+	written := make(chan struct{})
+	go func() {
+		a.Write([]byte("late\n"))
+		close(written)
+	}()
+	select {
+	case <-g.started:
+		t.Fatal("Expected the late message to wait for the queued ones")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(g.release)
+	<-closed
+	<-written
+	if got := g.String(); got != "a\nb\nlate\n" {
+		t.Errorf("Expected the late message after the queued ones, got %q", got)
+	}
+}
+
+func TestFlushAll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := NewLoggerFromConfig("info", path, Options{Format: FormatJSON, Async: AsyncOptions{Enabled: true}})
+	if err != nil {
+		t.Fatalf("NewLoggerFromConfig failed: %v", err)
+	}
+	l.Info("Server shutdown completed")
+	FlushAll()
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"msg":"Server shutdown completed"`) {
+		t.Errorf("Expected the message in the file after FlushAll, got %q", data)
+	}
+}
+
+func TestAsyncOptions_Invalid(t *testing.T) {
+	for _, opts := range []AsyncOptions{{BufferSize: -1}, {Overflow: "wait"}} {
+		if _, err := NewAsyncWriter(io.Discard, opts); err == nil {
+			t.Errorf("Expected %+v to be rejected", opts)
+		}
+	}
+}
+
+// slowDisk stands in for a disk that takes a while for every write
+type slowDisk struct{}
+
+func (slowDisk) Write(p []byte) (int, error) {
+	time.Sleep(50 * time.Microsecond)
+	return len(p), nil
+}
+
+// benchmarkRequestLines logs the two lines of a request, as the logging
+// middleware does
+func benchmarkRequestLines(b *testing.B, l Logger) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rl := WithRequest(l, "01HX5Z9K3QW8J2V7N4T6R1M0PC", "GET", "/files/report.pdf", "192.0.2.1:50312")
+			rl.Info("Request started")
+			rl.Info("Request completed", Fields{"status_code": 200, "bytes": 5120, "duration_ms": 3})
+		}
+	})
+}
+
+func BenchmarkLogger(b *testing.B) {
+	outputs := map[string]func(b *testing.B) io.Writer{
+		"File": func(b *testing.B) io.Writer {
+			file, err := os.Create(filepath.Join(b.TempDir(), "bench.log"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { file.Close() })
+			return file
+		},
+		"SlowDisk": func(*testing.B) io.Writer { return slowDisk{} },
+	}
+	for _, name := range []string{"File", "SlowDisk"} {
+		b.Run(name+"/Sync", func(b *testing.B) {
+			benchmarkRequestLines(b, NewLogger(InfoLevel, outputs[name](b)))
+		})
+		for _, overflow := range []string{OverflowBlock, OverflowDrop} {
+			b.Run(name+"/Async/"+overflow, func(b *testing.B) {
+				a, err := NewAsyncWriter(outputs[name](b), AsyncOptions{Enabled: true, Overflow: overflow})
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer a.Close()
+				benchmarkRequestLines(b, NewLogger(InfoLevel, a))
+				a.Flush()
+			})
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"otterserve/internal/rotate"
+)
+
+// Output formats
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// Options selects how a logger writes messages. The zero value is the
+// text format with local times to the second, to a file that is never
+// rotated.
+type Options struct {
+	Format        string // text (default), json or logfmt
+	TimeZone      string // Local (default), UTC or an IANA zone name
+	TimePrecision string // s (default), ms, us or ns
+
+	// Rotation applies to log files opened by NewLoggerFromConfig
+	Rotation rotate.Options
+
+	// Async applies to the output opened by NewLoggerFromConfig
+	Async AsyncOptions
+}
+
+// precisionDigits maps time precisions to digits of fractional seconds
+var precisionDigits = map[string]int{"": 0, "s": 0, "ms": 3, "us": 6, "ns": 9}
+
+// timeSettings returns the zone and layout of timestamps, based on layout
+// without fractional seconds
+func (o Options) timeSettings(layout string) (*time.Location, string, error) {
+	location := time.Local
+	if o.TimeZone != "" {
+		loc, err := time.LoadLocation(o.TimeZone)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid log time zone %s: %w", o.TimeZone, err)
+		}
+		location = loc
+	}
+	digits, ok := precisionDigits[o.TimePrecision]
+	if !ok {
+		return nil, "", fmt.Errorf("invalid log time precision %s, must be one of: s, ms, us, ns", o.TimePrecision)
+	}
+	if digits > 0 {
+		// Fractional seconds go after the seconds, before any zone offset
+		i := strings.Index(layout, "05") + 2
+		layout = layout[:i] + "." + strings.Repeat("0", digits) + layout[i:]
+	}
+	return location, layout, nil
+}
+
+// validate checks the options without creating a logger
+func (o Options) validate() error {
+	switch o.Format {
+	case "", FormatText, FormatJSON, FormatLogfmt:
+	default:
+		return fmt.Errorf("invalid log format %s, must be one of: text, json, logfmt", o.Format)
+	}
+	if _, _, err := o.timeSettings(time.RFC3339); err != nil {
+		return err
+	}
+	return o.Async.validate()
+}
+
+// NewLoggerWithOptions creates a logger writing in the format selected by
+// opts
+func NewLoggerWithOptions(level LogLevel, output io.Writer, opts Options) (Logger, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	layout := "2006-01-02 15:04:05"
+	if opts.Format == FormatJSON || opts.Format == FormatLogfmt {
+		layout = time.RFC3339
+	}
+	location, layout, err := opts.timeSettings(layout)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewLogger(level, output).(*DefaultLogger)
+	l.location, l.layout = location, layout
+
+	handlerOpts := &slog.HandlerOptions{
+		Level: slog.LevelDebug, // the logger filters by level itself
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				a.Value = slog.StringValue(a.Value.Time().In(location).Format(layout))
+			}
+			return a
+		},
+	}
+	switch opts.Format {
+	case FormatJSON:
+		l.handler = slog.NewJSONHandler(l.output, handlerOpts)
+	case FormatLogfmt:
+		l.handler = slog.NewTextHandler(l.output, handlerOpts)
+	}
+	return l, nil
+}
+
+// sortedKeys returns the keys of fields in order, so fields are written in
+// the same order every time
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func newTestLogger(t *testing.T, opts Options) (Logger, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	l, err := NewLoggerWithOptions(DebugLevel, &buf, opts)
+	if err != nil {
+		t.Fatalf("NewLoggerWithOptions failed: %v", err)
+	}
+	return l, &buf
+}
+
+func TestLogger_TextFieldOrder(t *testing.T) {
+	l, buf := newTestLogger(t, Options{})
+	l.Info("Request completed", Fields{"status_code": 200, "bytes": 5, "method": "GET", "duration_ms": 3})
+
+	if !strings.HasSuffix(buf.String(), "INFO: Request completed | bytes=5 duration_ms=3 method=GET status_code=200\n") {
+		t.Errorf("Expected fields sorted by key, got %q", buf.String())
+	}
+}
+
+func TestLogger_JSON(t *testing.T) {
+	l, buf := newTestLogger(t, Options{Format: FormatJSON, TimeZone: "UTC", TimePrecision: "ms"})
+	l.Warn("Failed to export spans", Fields{
+		"spans":   12,
+		"ratio":   0.5,
+		"retry":   true,
+		"error":   errors.New("connection refused"),
+		"message": "quote \" and\nnewline",
+	})
+
+	line := buf.String()
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+		t.Fatalf("Expected a JSON object, got %q: %v", line, err)
+	}
+	want := map[string]interface{}{
+		"level":   "WARN",
+		"msg":     "Failed to export spans",
+		"spans":   float64(12),
+		"ratio":   0.5,
+		"retry":   true,
+		"error":   "connection refused",
+		"message": "quote \" and\nnewline",
+	}
+	for key, value := range want {
+		if decoded[key] != value {
+			t.Errorf("Expected %s %#v, got %#v", key, value, decoded[key])
+		}
+	}
+	if ts, _ := decoded["time"].(string); !regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z$`).MatchString(ts) {
+		t.Errorf("Expected an RFC 3339 UTC time with milliseconds, got %q", ts)
+	}
+	if i, j := strings.Index(line, `"error"`), strings.Index(line, `"spans"`); i < 0 || i > j {
+		t.Errorf("Expected fields sorted by key, got %s", line)
+	}
+}
+
+func TestLogger_Logfmt(t *testing.T) {
+	l, buf := newTestLogger(t, Options{Format: FormatLogfmt, TimeZone: "UTC"})
+	l.Error("Failed to close audit log", Fields{"path": "/var/log/audit.log", "error": "disk full", "attempt": 2})
+
+	pattern := `^time=\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ level=ERROR msg="Failed to close audit log" attempt=2 error="disk full" path=/var/log/audit.log\n$`
+	if !regexp.MustCompile(pattern).MatchString(buf.String()) {
+		t.Errorf("Unexpected logfmt line %q", buf.String())
+	}
+}
+
+func TestLogger_TimeZoneAndPrecision(t *testing.T) {
+	l, buf := newTestLogger(t, Options{TimeZone: "UTC", TimePrecision: "us"})
+	l.Info("Started")
+
+	if !regexp.MustCompile(`^\[\d{4}-\d\d-\d\d \d\d:\d\d:\d\d\.\d{6}\] INFO: Started\n$`).MatchString(buf.String()) {
+		t.Errorf("Expected a text timestamp with microseconds, got %q", buf.String())
+	}
+}
+
+func TestLogger_LevelFilterWithFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerWithOptions(WarnLevel, &buf, Options{Format: FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("Expected messages below the level to be dropped, got %q", buf.String())
+	}
+}
+
+func TestNewLoggerWithOptions_Invalid(t *testing.T) {
+	for _, opts := range []Options{
+		{Format: "xml"},
+		{TimeZone: "Mars/Olympus_Mons"},
+		{TimePrecision: "ps"},
+	} {
+		if _, err := NewLoggerWithOptions(InfoLevel, nil, opts); err == nil {
+			t.Errorf("Expected options %+v to be rejected", opts)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// field is an attribute flattened to a key and its text
+type field struct {
+	key   string
+	value string
+}
+
+// handlerAttrs holds the attributes and group added to a handler with
+// WithAttrs and WithGroup, for handlers that write flat key-value pairs.
+// Groups become key prefixes separated by dots.
+type handlerAttrs struct {
+	prefix string
+	fields []field
+}
+
+// withAttrs returns a copy with attrs added
+func (h handlerAttrs) withAttrs(attrs []slog.Attr) handlerAttrs {
+	fields := append([]field(nil), h.fields...)
+	for _, a := range attrs {
+		fields = appendAttr(fields, h.prefix, a)
+	}
+	return handlerAttrs{prefix: h.prefix, fields: fields}
+}
+
+// withGroup returns a copy in which later attributes belong to group name
+func (h handlerAttrs) withGroup(name string) handlerAttrs {
+	if name == "" {
+		return h
+	}
+	return handlerAttrs{prefix: h.prefix + name + ".", fields: h.fields}
+}
+
+// recordFields returns the handler's attributes followed by the record's
+func (h handlerAttrs) recordFields(r slog.Record) []field {
+	fields := append([]field(nil), h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.prefix, a)
+		return true
+	})
+	return fields
+}
+
+// appendAttr flattens an attribute into fields
+func appendAttr(fields []field, prefix string, a slog.Attr) []field {
+	value := a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	switch value.Kind() {
+	case slog.KindGroup:
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, member := range value.Group() {
+			fields = appendAttr(fields, groupPrefix, member)
+		}
+		return fields
+	case slog.KindTime:
+		return append(fields, field{prefix + a.Key, value.Time().Format(time.RFC3339Nano)})
+	}
+	return append(fields, field{prefix + a.Key, value.String()})
+}
+
+// socketConn is a connection to a log daemon that is re-established when
+// sending fails, as when the daemon restarted
+type socketConn struct {
+	network string // unix, unixgram, udp or tcp
+	address string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	stream bool
+}
+
+// connect dials the daemon, the caller holding mu or having sole access.
+// Local syslog daemons listen on datagram sockets, or on stream sockets on
+// some systems, so unix tries both.
+func (c *socketConn) connect() error {
+	var conn net.Conn
+	var err error
+	switch c.network {
+	case "unix":
+		conn, err = net.Dial("unixgram", c.address)
+		c.stream = false
+		if err != nil {
+			conn, err = net.Dial("unix", c.address)
+			c.stream = true
+		}
+	default:
+		conn, err = net.DialTimeout(c.network, c.address, 5*time.Second)
+		c.stream = c.network == "tcp"
+	}
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+// send writes a message, reconnecting once if writing fails. Messages on
+// streams are framed by octet counting (RFC 6587).
+func (c *socketConn) send(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if err = c.connect(); err != nil {
+				continue
+			}
+		}
+		frame := msg
+		if c.stream {
+			frame = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+		if _, err = c.conn.Write(frame); err == nil {
+			return nil
+		}
+		c.conn.Close()
+		c.conn = nil
+	}
+	return err
+}
+
+// close closes the connection
+func (c *socketConn) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
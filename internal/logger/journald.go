@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// DefaultJournalSocket is where systemd-journald receives native messages
+const DefaultJournalSocket = "/run/systemd/journal/socket"
+
+// journalReserved are the journal fields set by the handler or by journald
+// itself, which message fields must not override
+var journalReserved = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+	"MESSAGE_ID":        true,
+	"SYSLOG_FACILITY":   true,
+	"SYSLOG_PID":        true,
+}
+
+// JournalHandler is a log/slog handler sending messages to systemd-journald
+// over its native protocol. Attributes become journal fields, with names
+// upper-cased as journald requires: request_id becomes REQUEST_ID. It
+// handles every level; wrap it in a logger to filter.
+type JournalHandler struct {
+	conn  *socketConn
+	tag   string
+	attrs handlerAttrs
+}
+
+// NewJournalHandler connects to journald at socket, the default socket when
+// empty. tag is sent as the SYSLOG_IDENTIFIER of each message.
+func NewJournalHandler(socket, tag string) (*JournalHandler, error) {
+	if socket == "" {
+		socket = DefaultJournalSocket
+	}
+	conn := &socketConn{network: "unixgram", address: socket}
+	if err := conn.connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to journald at %s: %w", socket, err)
+	}
+	return &JournalHandler{conn: conn, tag: tag}, nil
+}
+
+// Enabled reports that every level is handled
+func (h *JournalHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle sends a record as one journal entry
+func (h *JournalHandler) Handle(_ context.Context, r slog.Record) error {
+	return h.conn.send(h.format(r))
+}
+
+// WithAttrs returns a handler adding attrs to every entry
+func (h *JournalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = h.attrs.withAttrs(attrs)
+	return &clone
+}
+
+// WithGroup returns a handler qualifying later attributes with name
+func (h *JournalHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.attrs = h.attrs.withGroup(name)
+	return &clone
+}
+
+// Close closes the connection to journald
+func (h *JournalHandler) Close() error {
+	return h.conn.close()
+}
+
+// format builds the native protocol payload of a record
+func (h *JournalHandler) format(r slog.Record) []byte {
+	var b []byte
+	b = appendJournalField(b, "MESSAGE", r.Message)
+	b = appendJournalField(b, "PRIORITY", strconv.Itoa(syslogSeverity(r.Level)))
+	if h.tag != "" {
+		b = appendJournalField(b, "SYSLOG_IDENTIFIER", h.tag)
+	}
+	for _, f := range h.attrs.recordFields(r) {
+		b = appendJournalField(b, journalFieldName(f.key), f.value)
+	}
+	return b
+}
+
+// appendJournalField appends a field as NAME=value, or in the binary form
+// of a name line followed by the little-endian 64-bit length and the value
+// when the value spans several lines
+func appendJournalField(b []byte, name, value string) []byte {
+	b = append(b, name...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	b = append(b, '\n')
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// journalFieldName turns a key into a valid journal field name: upper-case
+// letters, digits and underscores, starting with a letter. Keys that do not
+// start with a letter or that name a reserved field are prefixed with F_.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if name == "" || name[0] < 'A' || name[0] > 'Z' || journalReserved[name] {
+		name = "F_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
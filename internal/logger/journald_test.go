@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestJournalHandler_Format(t *testing.T) {
+	h := (&JournalHandler{tag: "otterserve"}).WithAttrs([]slog.Attr{slog.String("request_id", "r1")})
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, "Login failed", 0)
+	r.AddAttrs(slog.String("user", "alice"), slog.String("message", "spoofed"), slog.Int("2fa", 1))
+
+	want := "MESSAGE=Login failed\nPRIORITY=4\nSYSLOG_IDENTIFIER=otterserve\n" +
+		"REQUEST_ID=r1\nUSER=alice\nF_MESSAGE=spoofed\nF_2FA=1\n"
+	if got := string(h.(*JournalHandler).format(r)); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestJournalHandler_MultilineValue(t *testing.T) {
+	h := &JournalHandler{}
+	r := slog.NewRecord(time.Now(), slog.LevelError, "Panic", 0)
+	r.AddAttrs(slog.String("stack", "line 1\nline 2"))
+
+	got := h.format(r)
+	prefix := "MESSAGE=Panic\nPRIORITY=3\nSTACK\n"
+	if string(got[:len(prefix)]) != prefix {
+		t.Fatalf("Expected the field name on its own line, got %q", got)
+	}
+	size := binary.LittleEndian.Uint64(got[len(prefix):])
+	value := got[len(prefix)+8:]
+	if size != 13 || string(value) != "line 1\nline 2\n" {
+		t.Errorf("Expected a length-prefixed value, got length %d and %q", size, value)
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	tests := map[string]string{
+		"status_code": "STATUS_CODE",
+		"req.id":      "REQ_ID",
+		"_private":    "F__PRIVATE",
+		"priority":    "F_PRIORITY",
+		"":            "F_",
+	}
+	for key, want := range tests {
+		if got := journalFieldName(key); got != want {
+			t.Errorf("journalFieldName(%q) = %q, expected %q", key, got, want)
+		}
+	}
+}
+
+func TestJournaldSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix datagram sockets are not available on Windows")
+	}
+	socket := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenPacket("unixgram", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	l, err := NewSinkLogger(SinkOptions{Type: SinkJournald, Level: "info", Address: socket, Tag: "otterserve"})
+	if err != nil {
+		t.Fatalf("NewSinkLogger failed: %v", err)
+	}
+	l.Debug("below the sink's level")
+	l.Info("Server started", Fields{"port": 1123})
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Expected a datagram: %v", err)
+	}
+	want := "MESSAGE=Server started\nPRIORITY=6\nSYSLOG_IDENTIFIER=otterserve\nPORT=1123\n"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
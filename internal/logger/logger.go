@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"otterserve/internal/rotate"
 )
 
 // LogLevel represents the severity level of a log message
@@ -64,11 +68,15 @@ type Logger interface {
 	GetLevel() LogLevel
 }
 
-// DefaultLogger implements the Logger interface
+// DefaultLogger implements the Logger interface. The level may be changed
+// while other goroutines log, as on a configuration reload.
 type DefaultLogger struct {
-	level  LogLevel
-	output io.Writer
-	logger *log.Logger
+	level    atomic.Int32
+	output   io.Writer
+	logger   *log.Logger
+	handler  slog.Handler // writes the json and logfmt formats, nil for text
+	location *time.Location
+	layout   string
 }
 
 // NewLogger creates a new logger instance
@@ -77,73 +85,90 @@ func NewLogger(level LogLevel, output io.Writer) Logger {
 		output = os.Stdout
 	}
 	
-	return &DefaultLogger{
-		level:  level,
-		output: output,
-		logger: log.New(output, "", 0), // No default prefix or flags
+	l := &DefaultLogger{
+		output:   output,
+		logger:   log.New(output, "", 0), // No default prefix or flags
+		location: time.Local,
+		layout:   "2006-01-02 15:04:05",
 	}
+	l.level.Store(int32(level))
+	return l
 }
 
 // NewLoggerFromConfig creates a logger from configuration
-func NewLoggerFromConfig(levelStr, filename string) (Logger, error) {
+func NewLoggerFromConfig(levelStr, filename string, opts Options) (Logger, error) {
 	level, err := ParseLogLevel(levelStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid log level: %w", err)
 	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
 
 	var output io.Writer = os.Stdout
 	if filename != "" {
-		file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		file, err := rotate.Open(filename, 0644, opts.Rotation)
 		if err != nil {
-			return nil, fmt.Errorf("failed to open log file %s: %w", filename, err)
+			return nil, fmt.Errorf("failed to open log file: %w", err)
 		}
 		output = file
 	}
+	if opts.Async.Enabled {
+		async, err := NewAsyncWriter(output, opts.Async)
+		if err != nil {
+			return nil, err
+		}
+		output = async
+	}
 
-	return NewLogger(level, output), nil
+	return NewLoggerWithOptions(level, output, opts)
 }
 
 // Debug logs a debug message with optional fields
 func (l *DefaultLogger) Debug(msg string, fields ...Fields) {
-	if l.level <= DebugLevel {
+	if l.GetLevel() <= DebugLevel {
 		l.log(DebugLevel, msg, fields...)
 	}
 }
 
 // Info logs an info message with optional fields
 func (l *DefaultLogger) Info(msg string, fields ...Fields) {
-	if l.level <= InfoLevel {
+	if l.GetLevel() <= InfoLevel {
 		l.log(InfoLevel, msg, fields...)
 	}
 }
 
 // Warn logs a warning message with optional fields
 func (l *DefaultLogger) Warn(msg string, fields ...Fields) {
-	if l.level <= WarnLevel {
+	if l.GetLevel() <= WarnLevel {
 		l.log(WarnLevel, msg, fields...)
 	}
 }
 
 // Error logs an error message with optional fields
 func (l *DefaultLogger) Error(msg string, fields ...Fields) {
-	if l.level <= ErrorLevel {
+	if l.GetLevel() <= ErrorLevel {
 		l.log(ErrorLevel, msg, fields...)
 	}
 }
 
 // SetLevel sets the minimum log level
 func (l *DefaultLogger) SetLevel(level LogLevel) {
-	l.level = level
+	l.level.Store(int32(level))
 }
 
 // GetLevel returns the current log level
 func (l *DefaultLogger) GetLevel() LogLevel {
-	return l.level
+	return LogLevel(l.level.Load())
 }
 
 // log formats and writes a log message
 func (l *DefaultLogger) log(level LogLevel, msg string, fields ...Fields) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	if l.handler != nil {
+		handle(l.handler, level, msg, fields...)
+		return
+	}
+	timestamp := time.Now().In(l.location).Format(l.layout)
 	
 	// Build the log message
 	logMsg := fmt.Sprintf("[%s] %s: %s", timestamp, level.String(), msg)
@@ -151,8 +176,8 @@ func (l *DefaultLogger) log(level LogLevel, msg string, fields ...Fields) {
 	// Add structured fields if provided
 	if len(fields) > 0 && fields[0] != nil {
 		var fieldStrs []string
-		for key, value := range fields[0] {
-			fieldStrs = append(fieldStrs, fmt.Sprintf("%s=%v", key, value))
+		for _, key := range sortedKeys(fields[0]) {
+			fieldStrs = append(fieldStrs, fmt.Sprintf("%s=%v", key, fields[0][key]))
 		}
 		if len(fieldStrs) > 0 {
 			logMsg += " | " + strings.Join(fieldStrs, " ")
@@ -164,8 +189,14 @@ func (l *DefaultLogger) log(level LogLevel, msg string, fields ...Fields) {
 
 // RequestLogger creates a logger with request-specific fields
 func (l *DefaultLogger) RequestLogger(requestID, method, path, remoteAddr string) Logger {
+	return WithRequest(l, requestID, method, path, remoteAddr)
+}
+
+// WithRequest creates a logger adding request-specific fields to the
+// messages of parent, whatever its implementation
+func WithRequest(parent Logger, requestID, method, path, remoteAddr string) Logger {
 	return &RequestLogger{
-		parent: l,
+		parent: parent,
 		fields: Fields{
 			"request_id":  requestID,
 			"method":      method,
@@ -3,8 +3,11 @@ package logger
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"otterserve/internal/rotate"
 )
 
 func TestParseLogLevel(t *testing.T) {
@@ -85,7 +88,7 @@ func TestNewLogger_NilOutput(t *testing.T) {
 
 func TestNewLoggerFromConfig(t *testing.T) {
 	// Test with stdout
-	logger, err := NewLoggerFromConfig("info", "")
+	logger, err := NewLoggerFromConfig("info", "", Options{})
 	if err != nil {
 		t.Fatalf("Failed to create logger: %v", err)
 	}
@@ -107,7 +110,7 @@ func TestNewLoggerFromConfig(t *testing.T) {
 		}
 	}()
 	
-	logger, err = NewLoggerFromConfig("debug", logFile)
+	logger, err = NewLoggerFromConfig("debug", logFile, Options{})
 	if err != nil {
 		t.Fatalf("Failed to create logger with file: %v", err)
 	}
@@ -116,7 +119,7 @@ func TestNewLoggerFromConfig(t *testing.T) {
 	}
 
 	// Test with invalid level
-	_, err = NewLoggerFromConfig("invalid", "")
+	_, err = NewLoggerFromConfig("invalid", "", Options{})
 	if err == nil {
 		t.Error("Expected error for invalid log level")
 	}
@@ -277,4 +280,22 @@ func TestRequestLogger_LevelOperations(t *testing.T) {
 	if parentLogger.GetLevel() != DebugLevel {
 		t.Errorf("Expected parent level %v, got %v", DebugLevel, parentLogger.GetLevel())
 	}
-}
\ No newline at end of file
+}
+func TestNewLoggerFromConfig_Rotation(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "otterserve.log")
+	log, err := NewLoggerFromConfig("info", logFile, Options{Rotation: rotate.Options{MaxSize: 100, MaxBackups: 2}})
+	if err != nil {
+		t.Fatalf("Failed to create logger with file: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		log.Info("A message long enough to fill the file after a couple of lines")
+	}
+
+	backups, err := rotate.Backups(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) == 0 {
+		t.Error("Expected the log file to be rotated once it reached the maximum size")
+	}
+}
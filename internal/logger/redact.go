@@ -0,0 +1,274 @@
+package logger
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces masked values
+const Redacted = "[REDACTED]"
+
+// DefaultRedactFields are the field names whose values are always masked
+var DefaultRedactFields = []string{
+	"password", "passwd", "secret", "token", "authorization", "cookie",
+	"api_key", "apikey", "credential", "private_key", "signature",
+}
+
+// DefaultRedactPatterns mask secrets inside values, such as the signature
+// of a signed URL in a logged query string or a token passed on the command
+// line. Only the first group of a pattern is masked, or the whole match if
+// it has none.
+var DefaultRedactPatterns = []string{
+	`(?i)\b[\w-]*(?:password|passwd|secret|token|api[_-]?key|signature|sig|credential)=([^&\s"',;]+)`,
+	`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{16,}=*)`,
+}
+
+// DefaultRedactQueryParams are the query parameters removed from logged URLs
+var DefaultRedactQueryParams = []string{
+	"token", "access_token", "id_token", "refresh_token", "code", "password",
+	"api_key", "apikey", "signature", "sig",
+	"x-amz-signature", "x-amz-credential", "x-amz-security-token",
+	"x-goog-signature", "x-goog-credential",
+}
+
+// Redactor masks secrets before they are logged: the values of sensitive
+// fields, parts of values matching patterns, and sensitive query parameters
+// of URLs. A nil Redactor leaves everything as it is.
+type Redactor struct {
+	fields   []string // lower case
+	params   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// defaultRedactor masks the defaults only
+var defaultRedactor = mustRedactor()
+
+// mustRedactor creates the default redactor, whose patterns always compile
+func mustRedactor() *Redactor {
+	r, err := NewRedactor(nil, nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// DefaultRedactor returns a redactor masking the default fields, patterns
+// and query parameters
+func DefaultRedactor() *Redactor {
+	return defaultRedactor
+}
+
+// NewRedactor creates a redactor masking the given field names, regular
+// expressions and query parameters in addition to the defaults. Names are
+// matched regardless of case. A field is also sensitive when its name ends
+// in a sensitive name after a separator, as db_password does.
+func NewRedactor(fields, patterns, queryParams []string) (*Redactor, error) {
+	r := &Redactor{params: make(map[string]bool)}
+	for _, name := range append(append([]string(nil), DefaultRedactFields...), fields...) {
+		r.fields = append(r.fields, strings.ToLower(name))
+	}
+	for _, name := range append(append([]string(nil), DefaultRedactQueryParams...), queryParams...) {
+		r.params[strings.ToLower(name)] = true
+	}
+	for _, expr := range append(append([]string(nil), DefaultRedactPatterns...), patterns...) {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %s: %w", expr, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// sensitive reports whether the values of a field are masked
+func (r *Redactor) sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, name := range r.fields {
+		if key == name {
+			return true
+		}
+		if strings.HasSuffix(key, name) {
+			switch key[len(key)-len(name)-1] {
+			case '_', '-', '.':
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String masks the parts of s matching the patterns
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, re := range r.patterns {
+		matches := re.FindAllStringSubmatchIndex(s, -1)
+		if matches == nil {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(Redacted)
+			last = end
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
+
+// Fields returns fields with sensitive values masked. fields itself is
+// returned when nothing needs masking.
+func (r *Redactor) Fields(fields Fields) Fields {
+	if r == nil || len(fields) == 0 {
+		return fields
+	}
+	var masked Fields
+	for key, value := range fields {
+		redacted, changed := r.value(key, value)
+		if !changed {
+			continue
+		}
+		if masked == nil {
+			masked = make(Fields, len(fields))
+			for k, v := range fields {
+				masked[k] = v
+			}
+		}
+		masked[key] = redacted
+	}
+	if masked == nil {
+		return fields
+	}
+	return masked
+}
+
+// value masks one field value and reports whether it changed. Values of
+// types other than strings, string slices and errors are kept unless the
+// field is sensitive.
+func (r *Redactor) value(key string, value interface{}) (interface{}, bool) {
+	if r.sensitive(key) {
+		return Redacted, true
+	}
+	switch v := value.(type) {
+	case string:
+		if s := r.String(v); s != v {
+			return s, true
+		}
+	case error:
+		if s := r.String(v.Error()); s != v.Error() {
+			return s, true
+		}
+	case []string:
+		var masked []string
+		for i, s := range v {
+			if m := r.String(s); m != s {
+				if masked == nil {
+					masked = append([]string(nil), v...)
+				}
+				masked[i] = m
+			}
+		}
+		if masked != nil {
+			return masked, true
+		}
+	}
+	return value, false
+}
+
+// URL removes sensitive query parameters from a URL or request target and
+// masks the rest with the patterns. The remaining parameters keep their
+// order and encoding.
+func (r *Redactor) URL(s string) string {
+	if r == nil {
+		return s
+	}
+	i := strings.IndexByte(s, '?')
+	if i < 0 {
+		return s
+	}
+	query, fragment := s[i+1:], ""
+	if j := strings.IndexByte(query, '#'); j >= 0 {
+		query, fragment = query[:j], query[j:]
+	}
+
+	var kept []string
+	for _, param := range strings.Split(query, "&") {
+		name := param
+		if j := strings.IndexByte(param, '='); j >= 0 {
+			name = param[:j]
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if r.params[strings.ToLower(name)] || r.sensitive(name) {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	s = s[:i]
+	if len(kept) > 0 {
+		s += "?" + strings.Join(kept, "&")
+	}
+	return r.String(s + fragment)
+}
+
+// RedactingLogger masks secrets in the messages and fields of another
+// logger, so they are masked in every destination of that logger
+type RedactingLogger struct {
+	next     Logger
+	redactor *Redactor
+}
+
+// NewRedactingLogger creates a logger passing messages to next after
+// masking them with redactor
+func NewRedactingLogger(next Logger, redactor *Redactor) Logger {
+	return &RedactingLogger{next: next, redactor: redactor}
+}
+
+// Debug logs a debug message with optional fields
+func (l *RedactingLogger) Debug(msg string, fields ...Fields) {
+	l.next.Debug(l.redactor.String(msg), l.fields(fields)...)
+}
+
+// Info logs an info message with optional fields
+func (l *RedactingLogger) Info(msg string, fields ...Fields) {
+	l.next.Info(l.redactor.String(msg), l.fields(fields)...)
+}
+
+// Warn logs a warning message with optional fields
+func (l *RedactingLogger) Warn(msg string, fields ...Fields) {
+	l.next.Warn(l.redactor.String(msg), l.fields(fields)...)
+}
+
+// Error logs an error message with optional fields
+func (l *RedactingLogger) Error(msg string, fields ...Fields) {
+	l.next.Error(l.redactor.String(msg), l.fields(fields)...)
+}
+
+// SetLevel sets the minimum log level
+func (l *RedactingLogger) SetLevel(level LogLevel) {
+	l.next.SetLevel(level)
+}
+
+// GetLevel returns the current log level
+func (l *RedactingLogger) GetLevel() LogLevel {
+	return l.next.GetLevel()
+}
+
+// fields masks the optional fields of a message
+func (l *RedactingLogger) fields(fields []Fields) []Fields {
+	if len(fields) == 0 || fields[0] == nil {
+		return fields
+	}
+	return []Fields{l.redactor.Fields(fields[0])}
+}
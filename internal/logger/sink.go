@@ -0,0 +1,121 @@
+package logger
+
+import "fmt"
+
+// Sink types
+const (
+	SinkFile     = "file"
+	SinkStdout   = "stdout"
+	SinkSyslog   = "syslog"
+	SinkJournald = "journald"
+)
+
+// SinkOptions describes a destination of log messages with its own minimum
+// level
+type SinkOptions struct {
+	Type  string // file, stdout, syslog or journald
+	Level string // debug, info, warn or error
+
+	// Options sets the format, time settings, rotation and asynchronous
+	// output of file and stdout sinks
+	Options
+	File string
+
+	// Syslog and journald settings; Address is the journald socket, or the
+	// syslog socket path or host:port
+	Network  string // unix, udp or tcp
+	Address  string
+	Facility string
+	Tag      string
+}
+
+// NewSinkLogger creates a logger writing to the destination opts describes
+func NewSinkLogger(opts SinkOptions) (Logger, error) {
+	level, err := ParseLogLevel(opts.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
+	}
+
+	switch opts.Type {
+	case SinkFile:
+		if opts.File == "" {
+			return nil, fmt.Errorf("file sink requires a file")
+		}
+		return NewLoggerFromConfig(opts.Level, opts.File, opts.Options)
+	case SinkStdout:
+		return NewLoggerFromConfig(opts.Level, "", opts.Options)
+	case SinkSyslog:
+		h, err := NewSyslogHandler(opts.Network, opts.Address, opts.Facility, opts.Tag)
+		if err != nil {
+			return nil, err
+		}
+		return NewSlogLogger(h, level), nil
+	case SinkJournald:
+		h, err := NewJournalHandler(opts.Address, opts.Tag)
+		if err != nil {
+			return nil, err
+		}
+		return NewSlogLogger(h, level), nil
+	default:
+		return nil, fmt.Errorf("invalid log sink type %s, must be one of: file, stdout, syslog, journald", opts.Type)
+	}
+}
+
+// MultiLogger passes messages to several loggers, each writing the messages
+// at or above its own level
+type MultiLogger struct {
+	primary Logger
+	others  []Logger
+}
+
+// NewMultiLogger creates a logger writing to primary and others. The level
+// is that of primary: changing it leaves the levels of the others alone.
+// Without others, primary is returned as it is.
+func NewMultiLogger(primary Logger, others ...Logger) Logger {
+	if len(others) == 0 {
+		return primary
+	}
+	return &MultiLogger{primary: primary, others: others}
+}
+
+// Debug logs a debug message with optional fields
+func (m *MultiLogger) Debug(msg string, fields ...Fields) {
+	m.primary.Debug(msg, fields...)
+	for _, l := range m.others {
+		l.Debug(msg, fields...)
+	}
+}
+
+// Info logs an info message with optional fields
+func (m *MultiLogger) Info(msg string, fields ...Fields) {
+	m.primary.Info(msg, fields...)
+	for _, l := range m.others {
+		l.Info(msg, fields...)
+	}
+}
+
+// Warn logs a warning message with optional fields
+func (m *MultiLogger) Warn(msg string, fields ...Fields) {
+	m.primary.Warn(msg, fields...)
+	for _, l := range m.others {
+		l.Warn(msg, fields...)
+	}
+}
+
+// Error logs an error message with optional fields
+func (m *MultiLogger) Error(msg string, fields ...Fields) {
+	m.primary.Error(msg, fields...)
+	for _, l := range m.others {
+		l.Error(msg, fields...)
+	}
+}
+
+// SetLevel sets the minimum level of the primary logger
+func (m *MultiLogger) SetLevel(level LogLevel) {
+	m.primary.SetLevel(level)
+}
+
+// GetLevel returns the level of the primary logger
+func (m *MultiLogger) GetLevel() LogLevel {
+	return m.primary.GetLevel()
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultiLogger_IndependentLevels(t *testing.T) {
+	var primary, errors bytes.Buffer
+	l := NewMultiLogger(NewLogger(DebugLevel, &primary), NewLogger(ErrorLevel, &errors))
+
+	l.Debug("Cache miss")
+	l.Error("Disk full")
+
+	if !strings.Contains(primary.String(), "Cache miss") || !strings.Contains(primary.String(), "Disk full") {
+		t.Errorf("Expected both messages in the debug logger, got %q", primary.String())
+	}
+	if strings.Contains(errors.String(), "Cache miss") || !strings.Contains(errors.String(), "Disk full") {
+		t.Errorf("Expected only the error in the error logger, got %q", errors.String())
+	}
+}
+
+func TestMultiLogger_Level(t *testing.T) {
+	var primary, other bytes.Buffer
+	sink := NewLogger(DebugLevel, &other)
+	l := NewMultiLogger(NewLogger(InfoLevel, &primary), sink)
+
+	l.SetLevel(ErrorLevel)
+	if l.GetLevel() != ErrorLevel {
+		t.Errorf("Expected level %v, got %v", ErrorLevel, l.GetLevel())
+	}
+	if sink.GetLevel() != DebugLevel {
+		t.Errorf("Expected the other logger to keep its level, got %v", sink.GetLevel())
+	}
+}
+
+func TestNewMultiLogger_Single(t *testing.T) {
+	primary := NewLogger(InfoLevel, &bytes.Buffer{})
+	if NewMultiLogger(primary) != primary {
+		t.Error("Expected a single logger to be returned as it is")
+	}
+}
+
+func TestNewSinkLogger_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	l, err := NewSinkLogger(SinkOptions{Type: SinkFile, Level: "debug", File: path, Options: Options{Format: FormatJSON}})
+	if err != nil {
+		t.Fatalf("NewSinkLogger failed: %v", err)
+	}
+	l.Debug("Cache miss", Fields{"key": "a"})
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"msg":"Cache miss"`) || !strings.Contains(string(data), `"key":"a"`) {
+		t.Errorf("Expected a JSON debug line, got %q", data)
+	}
+}
+
+func TestNewSinkLogger_Invalid(t *testing.T) {
+	tests := map[string]SinkOptions{
+		"unknown type":      {Type: "kafka", Level: "info"},
+		"unknown level":     {Type: SinkStdout, Level: "trace"},
+		"file without file": {Type: SinkFile, Level: "info"},
+		"unknown format":    {Type: SinkStdout, Level: "info", Options: Options{Format: "xml"}},
+	}
+	for name, opts := range tests {
+		if _, err := NewSinkLogger(opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// SlogLogger implements the Logger interface on top of a log/slog handler,
+// so messages can go wherever a slog handler sends them
+type SlogLogger struct {
+	level   atomic.Int32
+	handler slog.Handler
+}
+
+// NewSlogLogger creates a logger passing messages at or above level to
+// handler, which may filter them further
+func NewSlogLogger(handler slog.Handler, level LogLevel) Logger {
+	l := &SlogLogger{handler: handler}
+	l.level.Store(int32(level))
+	return l
+}
+
+// Debug logs a debug message with optional fields
+func (l *SlogLogger) Debug(msg string, fields ...Fields) {
+	l.log(DebugLevel, msg, fields...)
+}
+
+// Info logs an info message with optional fields
+func (l *SlogLogger) Info(msg string, fields ...Fields) {
+	l.log(InfoLevel, msg, fields...)
+}
+
+// Warn logs a warning message with optional fields
+func (l *SlogLogger) Warn(msg string, fields ...Fields) {
+	l.log(WarnLevel, msg, fields...)
+}
+
+// Error logs an error message with optional fields
+func (l *SlogLogger) Error(msg string, fields ...Fields) {
+	l.log(ErrorLevel, msg, fields...)
+}
+
+// SetLevel sets the minimum log level
+func (l *SlogLogger) SetLevel(level LogLevel) {
+	l.level.Store(int32(level))
+}
+
+// GetLevel returns the current log level
+func (l *SlogLogger) GetLevel() LogLevel {
+	return LogLevel(l.level.Load())
+}
+
+// log passes a message to the handler if its level is enabled
+func (l *SlogLogger) log(level LogLevel, msg string, fields ...Fields) {
+	if l.GetLevel() <= level {
+		handle(l.handler, level, msg, fields...)
+	}
+}
+
+// SlogLevel returns the log/slog level corresponding to level
+func SlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// handle passes a message to a slog handler with its fields as attributes,
+// sorted by key. Values keep their types, so handlers write numbers as
+// numbers and quote strings as their format requires.
+func handle(h slog.Handler, level LogLevel, msg string, fields ...Fields) {
+	ctx := context.Background()
+	if !h.Enabled(ctx, SlogLevel(level)) {
+		return
+	}
+	record := slog.NewRecord(time.Now(), SlogLevel(level), msg, 0)
+	if len(fields) > 0 && fields[0] != nil {
+		for _, key := range sortedKeys(fields[0]) {
+			record.AddAttrs(slog.Any(key, fields[0][key]))
+		}
+	}
+	h.Handle(ctx, record)
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	l := NewSlogLogger(handler, InfoLevel)
+
+	l.Debug("hidden")
+	l.Info("Route added", Fields{"path": "/docs", "directory": "/srv/docs"})
+	if got, want := buf.String(), "level=INFO msg=\"Route added\" directory=/srv/docs path=/docs\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	l.SetLevel(DebugLevel)
+	if l.GetLevel() != DebugLevel {
+		t.Errorf("Expected level %v, got %v", DebugLevel, l.GetLevel())
+	}
+	buf.Reset()
+	l.Debug("shown")
+	if !strings.Contains(buf.String(), "level=DEBUG") {
+		t.Errorf("Expected a debug message after lowering the level, got %q", buf.String())
+	}
+}
+
+func TestSlogLogger_HandlerFilters(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError}), DebugLevel)
+	l.Warn("dropped by the handler")
+	if buf.Len() != 0 {
+		t.Errorf("Expected the handler's level to apply, got %q", buf.String())
+	}
+}
+
+func TestWithRequest_SlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := WithRequest(NewSlogLogger(slog.NewJSONHandler(&buf, nil), InfoLevel), "req-1", "GET", "/a", "192.0.2.1:1234")
+	l.Info("Request completed", Fields{"status_code": 200})
+
+	for _, want := range []string{`"request_id":"req-1"`, `"method":"GET"`, `"status_code":200`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %s in %s", want, buf.String())
+		}
+	}
+}
+
+func TestSlogLevel(t *testing.T) {
+	tests := map[LogLevel]slog.Level{
+		DebugLevel: slog.LevelDebug,
+		InfoLevel:  slog.LevelInfo,
+		WarnLevel:  slog.LevelWarn,
+		ErrorLevel: slog.LevelError,
+	}
+	for level, want := range tests {
+		if got := SlogLevel(level); got != want {
+			t.Errorf("SlogLevel(%v) = %v, want %v", level, got, want)
+		}
+	}
+}
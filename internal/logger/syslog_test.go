@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogHandler_Format(t *testing.T) {
+	h := &SyslogHandler{facility: 16, tag: "otterserve", hostname: "files.example", pid: "42"}
+	r := slog.NewRecord(time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC), slog.LevelError, "Upload failed", 0)
+	r.AddAttrs(slog.String("path", `/a"b]`), slog.Int("status", 500))
+
+	want := `<131>1 2024-05-01T10:00:00.123456Z files.example otterserve 42 - [otterserve@32473 path="/a\"b\]" status="500"] Upload failed`
+	if got := string(h.WithAttrs(nil).(*SyslogHandler).format(r)); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	r = slog.NewRecord(r.Time, slog.LevelDebug, "Plain", 0)
+	if got := string(h.format(r)); !strings.HasPrefix(got, "<135>1 ") || !strings.HasSuffix(got, " 42 - - Plain") {
+		t.Errorf("Expected a debug message without structured data, got %s", got)
+	}
+}
+
+func TestSyslogHandler_Groups(t *testing.T) {
+	h := (&SyslogHandler{facility: 3, hostname: "h", pid: "1"}).WithAttrs([]slog.Attr{slog.String("service", "files")}).WithGroup("req")
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "m", 0)
+	r.AddAttrs(slog.String("id", "r1"), slog.Group("client", slog.String("ip", "192.0.2.1")))
+
+	got := string(h.(*SyslogHandler).format(r))
+	if !strings.Contains(got, `[otterserve@32473 service="files" req.id="r1" req.client.ip="192.0.2.1"]`) {
+		t.Errorf("Expected grouped attributes to become dotted names, got %s", got)
+	}
+}
+
+func TestSyslogHandler_InvalidSettings(t *testing.T) {
+	if _, err := NewSyslogHandler("udp", "127.0.0.1:514", "local9", "t"); err == nil {
+		t.Error("Expected an unknown facility to be rejected")
+	}
+	if _, err := NewSyslogHandler("http", "127.0.0.1:514", "daemon", "t"); err == nil {
+		t.Error("Expected an unknown network to be rejected")
+	}
+	if _, err := NewSyslogHandler("tcp", "", "daemon", "t"); err == nil {
+		t.Error("Expected tcp without an address to be rejected")
+	}
+}
+
+// syslogPattern matches the messages the tests send
+var syslogPattern = regexp.MustCompile(`^<(\d+)>1 \S+ \S+ otterserve \d+ - \[otterserve@32473 user="alice"\] Login failed$`)
+
+func checkSyslogMessage(t *testing.T, msg string) {
+	t.Helper()
+	m := syslogPattern.FindStringSubmatch(msg)
+	if m == nil {
+		t.Fatalf("Unexpected syslog message %q", msg)
+	}
+	// local0 (16) at warning (4)
+	if m[1] != "132" {
+		t.Errorf("Expected priority 132, got %s", m[1])
+	}
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	l, err := NewSinkLogger(SinkOptions{Type: SinkSyslog, Level: "warn", Network: "udp", Address: conn.LocalAddr().String(), Facility: "local0", Tag: "otterserve"})
+	if err != nil {
+		t.Fatalf("NewSinkLogger failed: %v", err)
+	}
+	l.Info("below the sink's level")
+	l.Warn("Login failed", Fields{"user": "alice"})
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Expected a datagram: %v", err)
+	}
+	checkSyslogMessage(t, string(buf[:n]))
+}
+
+func TestSyslogSink_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	h, err := NewSyslogHandler("tcp", ln.Addr().String(), "local0", "otterserve")
+	if err != nil {
+		t.Fatalf("NewSyslogHandler failed: %v", err)
+	}
+	defer h.Close()
+	l := NewSlogLogger(h, InfoLevel)
+	l.Warn("Login failed", Fields{"user": "alice"})
+	l.Warn("Login failed", Fields{"user": "alice"})
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	// Messages are framed by their length in octets
+	for i := 0; i < 2; i++ {
+		prefix, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatalf("Expected a message length: %v", err)
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+		if err != nil {
+			t.Fatalf("Invalid message length %q", prefix)
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatal(err)
+		}
+		checkSyslogMessage(t, string(msg))
+	}
+}
+
+func TestSyslogSink_Unix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix datagram sockets are not available on Windows")
+	}
+	socket := filepath.Join(t.TempDir(), "log")
+	conn, err := net.ListenPacket("unixgram", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	l, err := NewSinkLogger(SinkOptions{Type: SinkSyslog, Level: "debug", Network: "unix", Address: socket, Facility: "local0", Tag: "otterserve"})
+	if err != nil {
+		t.Fatalf("NewSinkLogger failed: %v", err)
+	}
+	l.Warn("Login failed", Fields{"user": "alice"})
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Expected a datagram: %v", err)
+	}
+	checkSyslogMessage(t, string(buf[:n]))
+}
@@ -0,0 +1,411 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are histogram bounds in seconds suited to request latency
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is the registry the server's metrics are registered with
+var Default = NewRegistry()
+
+// family is a named metric with all its label combinations
+type family interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metric families and writes them in the Prometheus text
+// exposition format
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]family
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]family)}
+}
+
+// register adds a family; names must be unique within a registry
+func (r *Registry) register(name string, f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.families[name]; exists {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.families[name] = f
+}
+
+// NewCounterVec registers a counter with the given label names
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, "counter", labels)}
+	r.register(name, c)
+	return c
+}
+
+// NewCounterFunc registers a counter whose value is read from fn
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, typ: "counter", fn: fn})
+}
+
+// NewGauge registers a gauge without labels
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.NewGaugeVec(name, help).WithLabelValues()
+}
+
+// NewGaugeVec registers a gauge with the given label names
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec: newVec(name, help, "gauge", labels)}
+	r.register(name, g)
+	return g
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, typ: "gauge", fn: fn})
+}
+
+// NewHistogramVec registers a histogram with the given upper bounds, in
+// increasing order, and label names
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{vec: newVec(name, help, "histogram", labels), buckets: buckets}
+	r.register(name, h)
+	return h
+}
+
+// Write writes every metric in the text exposition format, sorted by name
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	families := make([]family, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		families = append(families, r.families[name])
+	}
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry's metrics to Prometheus
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		r.Write(w)
+	})
+}
+
+// vec keeps the children of a family by their label values
+type vec struct {
+	name     string
+	help     string
+	typ      string
+	labels   []string
+	mu       sync.RWMutex
+	children map[string]*child
+}
+
+// child is one label combination of a family
+type child struct {
+	values []string
+	metric interface{}
+}
+
+func newVec(name, help, typ string, labels []string) vec {
+	return vec{name: name, help: help, typ: typ, labels: labels, children: make(map[string]*child)}
+}
+
+// get returns the metric for the label values, creating it with create
+func (v *vec) get(values []string, create func() interface{}) interface{} {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.RLock()
+	c, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return c.metric
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok := v.children[key]; ok {
+		return c.metric
+	}
+	c = &child{values: append([]string(nil), values...), metric: create()}
+	v.children[key] = c
+	return c.metric
+}
+
+// sorted returns the children ordered by label values
+func (v *vec) sorted() []*child {
+	v.mu.RLock()
+	children := make([]*child, 0, len(v.children))
+	for _, c := range v.children {
+		children = append(children, c)
+	}
+	v.mu.RUnlock()
+	sort.Slice(children, func(i, j int) bool {
+		a, b := children[i].values, children[j].values
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return children
+}
+
+// writeHeader writes the HELP and TYPE lines of the family
+func (v *vec) writeHeader(w *bufio.Writer) {
+	writeHeader(w, v.name, v.help, v.typ)
+}
+
+// labelPairs formats the labels of a child, with extra appended, as
+// {name="value",...}, or nothing when there are none
+func (v *vec) labelPairs(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(v.labels[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(value))
+		b.WriteByte('"')
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extra[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(extra[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	vec
+}
+
+// WithLabelValues returns the counter for the label values, in the order
+// the label names were registered
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return c.get(values, func() interface{} { return &Counter{} }).(*Counter)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	for _, ch := range c.sorted() {
+		writeSample(w, c.name, c.labelPairs(ch.values), ch.metric.(*Counter).Value())
+	}
+}
+
+// Counter is a value that only increases
+type Counter struct {
+	bits atomic.Uint64
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds a non-negative value to the counter
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	addFloat(&c.bits, v)
+}
+
+// Value returns the current count
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct {
+	vec
+}
+
+// WithLabelValues returns the gauge for the label values
+func (g *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return g.get(values, func() interface{} { return &Gauge{} }).(*Gauge)
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	for _, ch := range g.sorted() {
+		writeSample(w, g.name, g.labelPairs(ch.values), ch.metric.(*Gauge).Value())
+	}
+}
+
+// Gauge is a value that goes up and down
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set sets the gauge to v
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+// Inc adds one to the gauge
+func (g *Gauge) Inc() {
+	addFloat(&g.bits, 1)
+}
+
+// Dec subtracts one from the gauge
+func (g *Gauge) Dec() {
+	addFloat(&g.bits, -1)
+}
+
+// Value returns the current value
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+// WithLabelValues returns the histogram for the label values
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return h.get(values, func() interface{} {
+		return &Histogram{bounds: h.buckets, counts: make([]uint64, len(h.buckets))}
+	}).(*Histogram)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	for _, ch := range h.sorted() {
+		hist := ch.metric.(*Histogram)
+		hist.mu.Lock()
+		counts := append([]uint64(nil), hist.counts...)
+		count, sum := hist.count, hist.sum
+		hist.mu.Unlock()
+
+		var cumulative uint64
+		for i, bound := range hist.bounds {
+			cumulative += counts[i]
+			writeSample(w, h.name+"_bucket", h.labelPairs(ch.values, "le", formatFloat(bound)), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", h.labelPairs(ch.values, "le", "+Inf"), float64(count))
+		writeSample(w, h.name+"_sum", h.labelPairs(ch.values), sum)
+		writeSample(w, h.name+"_count", h.labelPairs(ch.values), float64(count))
+	}
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	bounds []float64
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Observe adds a single observation
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.mu.Lock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+// funcMetric is a single value read when metrics are written
+type funcMetric struct {
+	name string
+	help string
+	typ  string
+	fn   func() float64
+}
+
+func (f *funcMetric) write(w *bufio.Writer) {
+	writeHeader(w, f.name, f.help, f.typ)
+	writeSample(w, f.name, "", f.fn())
+}
+
+// addFloat atomically adds v to the float64 stored in bits
+func addFloat(bits *atomic.Uint64, v float64) {
+	for {
+		old := bits.Load()
+		if bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, typ)
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name)
+	w.WriteString(labels)
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// formatFloat formats a sample value or bucket bound
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
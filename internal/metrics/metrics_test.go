@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_requests_total", "Requests.", "route", "code")
+	inFlight := r.NewGauge("test_in_flight", "In flight.")
+	latency := r.NewHistogramVec("test_latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	r.NewGaugeFunc("test_answer", "The answer.", func() float64 { return 42 })
+
+	requests.WithLabelValues("/b", "2xx").Inc()
+	requests.WithLabelValues("/a", "2xx").Add(2)
+	requests.WithLabelValues("/a", "2xx").Add(-5) // counters never go down
+	requests.WithLabelValues(`"quoted"\path`, "4xx").Inc()
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+	latency.WithLabelValues("/a").Observe(0.05)
+	latency.WithLabelValues("/a").Observe(0.5)
+	latency.WithLabelValues("/a").Observe(3)
+
+	var out strings.Builder
+	if err := r.Write(&out); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	expected := `# HELP test_answer The answer.
+# TYPE test_answer gauge
+test_answer 42
+# HELP test_in_flight In flight.
+# TYPE test_in_flight gauge
+test_in_flight 1
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{route="/a",le="0.1"} 1
+test_latency_seconds_bucket{route="/a",le="1"} 2
+test_latency_seconds_bucket{route="/a",le="+Inf"} 3
+test_latency_seconds_sum{route="/a"} 3.55
+test_latency_seconds_count{route="/a"} 3
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{route="\"quoted\"\\path",code="4xx"} 1
+test_requests_total{route="/a",code="2xx"} 2
+test_requests_total{route="/b",code="2xx"} 1
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestRegistry_DuplicateName(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("test_gauge", "A gauge.")
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a name twice to panic")
+		}
+	}()
+	r.NewCounterVec("test_gauge", "A counter.")
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Default.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", rr.Header().Get("Content-Type"))
+	}
+	expected := []string{"go_goroutines ", "go_memstats_alloc_bytes ", `go_info{version="` + runtime.Version() + `"} 1`, "process_start_time_seconds "}
+	if runtime.GOOS == "linux" {
+		expected = append(expected, "process_open_fds ")
+	}
+	for _, metric := range expected {
+		if !strings.Contains(rr.Body.String(), "\n"+metric) {
+			t.Errorf("Expected %q in the default registry:\n%s", metric, rr.Body.String())
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	Default.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package metrics
+
+import (
+	"os"
+	"syscall"
+)
+
+// registerProcess registers the file descriptor metrics, read from /proc
+func registerProcess(r *Registry) {
+	r.NewGaugeFunc("process_open_fds", "Number of open file descriptors.", func() float64 {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			return 0
+		}
+		// One of the entries is the directory being read
+		return float64(len(entries) - 1)
+	})
+	r.NewGaugeFunc("process_max_fds", "Maximum number of open file descriptors.", func() float64 {
+		var limit syscall.Rlimit
+		if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+			return 0
+		}
+		return float64(limit.Cur)
+	})
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package metrics
+
+// registerProcess does nothing; file descriptor counts are only read on
+// Linux
+func registerProcess(r *Registry) {}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// memStatsMaxAge bounds how often a scrape stops the world to read memory
+// statistics
+const memStatsMaxAge = time.Second
+
+// memStatsCache shares one runtime.ReadMemStats between the metrics read
+// during a scrape
+type memStatsCache struct {
+	mu    sync.Mutex
+	read  time.Time
+	stats runtime.MemStats
+}
+
+// get returns memory statistics no older than memStatsMaxAge
+func (c *memStatsCache) get() *runtime.MemStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.read) > memStatsMaxAge {
+		runtime.ReadMemStats(&c.stats)
+		c.read = time.Now()
+	}
+	return &c.stats
+}
+
+// RegisterRuntime registers Go runtime and process metrics: goroutines,
+// memory, garbage collection, start time and, where the platform reports
+// them, open file descriptors
+func RegisterRuntime(r *Registry) {
+	r.NewGaugeVec("go_info", "Information about the Go environment.", "version").
+		WithLabelValues(runtime.Version()).Set(1)
+	r.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	r.NewGaugeFunc("go_threads", "Number of OS threads created.", func() float64 {
+		n, _ := runtime.ThreadCreateProfile(nil)
+		return float64(n)
+	})
+
+	cache := &memStatsCache{}
+	r.NewGaugeFunc("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", func() float64 {
+		return float64(cache.get().Alloc)
+	})
+	r.NewCounterFunc("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", func() float64 {
+		return float64(cache.get().TotalAlloc)
+	})
+	r.NewGaugeFunc("go_memstats_sys_bytes", "Number of bytes obtained from system.", func() float64 {
+		return float64(cache.get().Sys)
+	})
+	r.NewGaugeFunc("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", func() float64 {
+		return float64(cache.get().HeapInuse)
+	})
+	r.NewGaugeFunc("go_memstats_heap_objects", "Number of allocated objects.", func() float64 {
+		return float64(cache.get().HeapObjects)
+	})
+	r.NewCounterFunc("go_gc_cycles_total", "Number of completed GC cycles.", func() float64 {
+		return float64(cache.get().NumGC)
+	})
+	r.NewCounterFunc("go_gc_pause_seconds_total", "Total time the world was stopped for garbage collection.", func() float64 {
+		return float64(cache.get().PauseTotalNs) / 1e9
+	})
+	r.NewGaugeFunc("go_memstats_last_gc_time_seconds", "Number of seconds since 1970 of last garbage collection.", func() float64 {
+		return float64(cache.get().LastGC) / 1e9
+	})
+
+	start := float64(time.Now().UnixNano()) / 1e9
+	r.NewGaugeFunc("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", func() float64 {
+		return start
+	})
+	registerProcess(r)
+}
+
+func init() {
+	RegisterRuntime(Default)
+}
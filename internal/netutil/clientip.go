@@ -0,0 +1,109 @@
+package netutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPResolver determines the originating client address of a request,
+// honouring X-Forwarded-For only when it was added by a trusted proxy
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+}
+
+// NewClientIPResolver creates a resolver trusting the given proxy ranges
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	trusted, err := ParsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+	return &ClientIPResolver{trusted: trusted}, nil
+}
+
+// Resolve returns the client address. X-Forwarded-For is walked from the
+// right, skipping trusted proxies, so entries a client forged before the
+// first trusted hop are never used.
+func (cr *ClientIPResolver) Resolve(r *http.Request) netip.Addr {
+	addr := RemoteAddr(r)
+	if cr == nil || !addr.IsValid() || !cr.isTrusted(addr) {
+		return addr
+	}
+
+	hops := forwardedFor(r)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			// A trusted proxy passed on garbage; the last trusted hop is
+			// the best we know
+			return addr
+		}
+		addr = hop.Unmap()
+		if !cr.isTrusted(addr) {
+			return addr
+		}
+	}
+	return addr
+}
+
+// FromTrustedProxy reports whether the request came from a trusted proxy,
+// so headers the proxy sets can be believed
+func (cr *ClientIPResolver) FromTrustedProxy(r *http.Request) bool {
+	addr := RemoteAddr(r)
+	return cr != nil && addr.IsValid() && cr.isTrusted(addr)
+}
+
+// isTrusted reports whether the address belongs to a trusted proxy
+func (cr *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, p := range cr.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the X-Forwarded-For entries of all header lines in order
+func forwardedFor(r *http.Request) []string {
+	var hops []string
+	for _, line := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(line, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+// RemoteAddr returns the address of the connection's peer
+func RemoteAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of the request carrying the resolved client address
+func WithClientIP(r *http.Request, addr netip.Addr) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), clientIPKey{}, addr))
+}
+
+// ClientIP returns the resolved client address of the request, falling back
+// to the connection's peer address when none was resolved
+func ClientIP(r *http.Request) netip.Addr {
+	if addr, ok := r.Context().Value(clientIPKey{}).(netip.Addr); ok {
+		return addr
+	}
+	return RemoteAddr(r)
+}
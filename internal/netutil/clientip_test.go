@@ -0,0 +1,81 @@
+package netutil
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPResolver_Resolve(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		expected   string
+	}{
+		{"direct client", "203.0.113.9:1234", nil, "203.0.113.9"},
+		{"header from untrusted peer ignored", "203.0.113.9:1234", []string{"192.0.2.1"}, "203.0.113.9"},
+		{"single trusted proxy", "127.0.0.1:1234", []string{"192.0.2.1"}, "192.0.2.1"},
+		{"proxy chain", "127.0.0.1:1234", []string{"192.0.2.1, 10.1.1.1"}, "192.0.2.1"},
+		{"forged entries left of client", "127.0.0.1:1234", []string{"198.51.100.1, 192.0.2.1"}, "192.0.2.1"},
+		{"multiple header lines", "127.0.0.1:1234", []string{"192.0.2.1", "10.1.1.1"}, "192.0.2.1"},
+		{"only trusted hops", "127.0.0.1:1234", []string{"10.2.2.2"}, "10.2.2.2"},
+		{"garbage from trusted proxy", "127.0.0.1:1234", []string{"bogus"}, "127.0.0.1"},
+		{"ipv6 peer", "[2001:db8::5]:1234", nil, "2001:db8::5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if got := resolver.Resolve(req).String(); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestClientIPResolver_FromTrustedProxy(t *testing.T) {
+	resolver, _ := NewClientIPResolver([]string{"10.0.0.0/8"})
+	req := httptest.NewRequest("GET", "/", nil)
+
+	req.RemoteAddr = "10.1.2.3:1234"
+	if !resolver.FromTrustedProxy(req) {
+		t.Error("Expected a peer in a trusted range to be a trusted proxy")
+	}
+	req.RemoteAddr = "203.0.113.9:1234"
+	if resolver.FromTrustedProxy(req) {
+		t.Error("Expected an untrusted peer not to be a trusted proxy")
+	}
+	var nilResolver *ClientIPResolver
+	if nilResolver.FromTrustedProxy(req) {
+		t.Error("Expected a nil resolver to trust no one")
+	}
+}
+
+func TestClientIP_Context(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	if got := ClientIP(req).String(); got != "127.0.0.1" {
+		t.Errorf("Expected fallback to peer address, got %s", got)
+	}
+
+	resolver, _ := NewClientIPResolver([]string{"127.0.0.1"})
+	req.Header.Set("X-Forwarded-For", "192.0.2.7")
+	req = WithClientIP(req, resolver.Resolve(req))
+	if got := ClientIP(req).String(); got != "192.0.2.7" {
+		t.Errorf("Expected resolved address, got %s", got)
+	}
+
+	var nilResolver *ClientIPResolver
+	if got := nilResolver.Resolve(req).String(); got != "127.0.0.1" {
+		t.Errorf("Expected nil resolver to use peer address, got %s", got)
+	}
+}
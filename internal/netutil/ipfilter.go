@@ -0,0 +1,93 @@
+package netutil
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// IPFilter decides whether a client address may access a resource based on
+// allow and deny lists of CIDR ranges or single addresses
+type IPFilter struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// NewIPFilter creates a filter from allow and deny lists. Deny rules take
+// precedence; when the allow list is non-empty an address must match it.
+func NewIPFilter(allow, deny []string) (*IPFilter, error) {
+	allowPrefixes, err := ParsePrefixes(allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allow rule: %w", err)
+	}
+	denyPrefixes, err := ParsePrefixes(deny)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deny rule: %w", err)
+	}
+	return &IPFilter{allow: allowPrefixes, deny: denyPrefixes}, nil
+}
+
+// Empty reports whether the filter has no rules and therefore admits everyone
+func (f *IPFilter) Empty() bool {
+	return f == nil || (len(f.allow) == 0 && len(f.deny) == 0)
+}
+
+// Check reports whether the address is admitted and, when it is not, the
+// rule responsible for the decision
+func (f *IPFilter) Check(addr netip.Addr) (bool, string) {
+	if f.Empty() {
+		return true, ""
+	}
+	if !addr.IsValid() {
+		return false, "unknown client address"
+	}
+
+	addr = addr.Unmap()
+	for _, p := range f.deny {
+		if p.Contains(addr) {
+			return false, "deny " + p.String()
+		}
+	}
+
+	if len(f.allow) == 0 {
+		return true, ""
+	}
+	for _, p := range f.allow {
+		if p.Contains(addr) {
+			return true, ""
+		}
+	}
+	return false, "not in allow list"
+}
+
+// ParsePrefixes parses CIDR ranges, accepting single addresses as host ranges
+func ParsePrefixes(rules []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if strings.Contains(rule, "/") {
+			p, err := netip.ParsePrefix(rule)
+			if err != nil {
+				return nil, err
+			}
+			addr := p.Addr()
+			if addr.Is4In6() {
+				// ::ffff:10.0.0.0/104 is the IPv4 range 10.0.0.0/8
+				if p.Bits() < 96 {
+					return nil, fmt.Errorf("%q: mapped IPv4 prefix shorter than /96", rule)
+				}
+				p = netip.PrefixFrom(addr.Unmap(), p.Bits()-96)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(rule)
+		if err != nil {
+			return nil, err
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,79 @@
+package netutil
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIPFilter_Check(t *testing.T) {
+	filter, err := NewIPFilter(
+		[]string{"192.168.0.0/16", "2001:db8::/32"},
+		[]string{"192.168.99.0/24", "192.168.1.1"},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	tests := []struct {
+		addr    string
+		allowed bool
+		rule    string
+	}{
+		{"192.168.1.20", true, ""},
+		{"::ffff:192.168.1.20", true, ""},
+		{"192.168.99.3", false, "deny 192.168.99.0/24"},
+		{"192.168.1.1", false, "deny 192.168.1.1/32"},
+		{"10.0.0.1", false, "not in allow list"},
+		{"2001:db8::1", true, ""},
+		{"2001:db9::1", false, "not in allow list"},
+	}
+
+	for _, tt := range tests {
+		allowed, rule := filter.Check(netip.MustParseAddr(tt.addr))
+		if allowed != tt.allowed || rule != tt.rule {
+			t.Errorf("Check(%s) = %v, %q; expected %v, %q", tt.addr, allowed, rule, tt.allowed, tt.rule)
+		}
+	}
+
+	if allowed, _ := filter.Check(netip.Addr{}); allowed {
+		t.Error("Expected unknown address to be refused")
+	}
+}
+
+func TestIPFilter_Empty(t *testing.T) {
+	filter, err := NewIPFilter(nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+	if !filter.Empty() {
+		t.Error("Expected filter without rules to be empty")
+	}
+	if allowed, _ := filter.Check(netip.Addr{}); !allowed {
+		t.Error("Expected empty filter to admit everyone")
+	}
+
+	// Deny-only filters admit everything not denied
+	filter, _ = NewIPFilter(nil, []string{"10.0.0.0/8"})
+	if allowed, _ := filter.Check(netip.MustParseAddr("172.16.0.1")); !allowed {
+		t.Error("Expected address outside the deny list to be admitted")
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := ParsePrefixes([]string{"10.1.2.3/8", " 192.0.2.1 ", "::ffff:10.0.0.0/104", "2001:db8::1"})
+	if err != nil {
+		t.Fatalf("ParsePrefixes failed: %v", err)
+	}
+	expected := []string{"10.0.0.0/8", "192.0.2.1/32", "10.0.0.0/8", "2001:db8::1/128"}
+	for i, p := range prefixes {
+		if p.String() != expected[i] {
+			t.Errorf("Prefix %d: expected %s, got %s", i, expected[i], p)
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "not-an-ip", "::ffff:0:0/80"} {
+		if _, err := ParsePrefixes([]string{bad}); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"net/http"
+	"time"
+)
+
+// DefaultHeader is the header request IDs are read from and echoed in
+const DefaultHeader = "X-Request-ID"
+
+// maxLength bounds IDs accepted from proxies, which end up in every log line
+const maxLength = 128
+
+// crockford is the Crockford base32 alphabet ULIDs are written in
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// New returns a ULID: 26 characters encoding the time in milliseconds
+// followed by 80 random bits, so IDs sort by creation time and do not
+// collide between concurrent requests
+func New() string {
+	return newAt(time.Now())
+}
+
+// newAt returns a ULID for time t
+func newAt(t time.Time) string {
+	var id [16]byte
+	ms := uint64(t.UnixMilli())
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	rand.Read(id[6:])
+	return encode(id)
+}
+
+// encode writes the 128 bits of id as 26 base32 characters, the first of
+// which carries only the top 3 bits
+func encode(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// Valid reports whether an ID sent by a client can be used: at most 128
+// visible ASCII characters, so it cannot break log lines or headers
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+type contextKey struct{}
+
+// With returns a copy of the request carrying id
+func With(r *http.Request, id string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, id))
+}
+
+// FromContext returns the request ID carried by ctx, or ""
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// FromRequest returns the ID of the request, or ""
+func FromRequest(r *http.Request) string {
+	return FromContext(r.Context())
+}
+
+// Error replies like http.Error, adding the request ID to the body so users
+// can quote it when reporting a problem
+func Error(w http.ResponseWriter, r *http.Request, message string, code int) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	body := message + "\n"
+	if id := FromRequest(r); id != "" {
+		body += "Request ID: " + id + "\n"
+	}
+	w.Write([]byte(body))
+}
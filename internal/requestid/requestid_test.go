@@ -0,0 +1,107 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	id := New()
+	if len(id) != 26 {
+		t.Fatalf("Expected 26 characters, got %q", id)
+	}
+	for _, c := range id {
+		if !strings.ContainsRune(crockford, c) {
+			t.Fatalf("Unexpected character %q in %q", c, id)
+		}
+	}
+
+	// IDs sort by time
+	earlier := newAt(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	later := newAt(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if earlier >= later {
+		t.Errorf("Expected %s < %s", earlier, later)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	if got := encode(max); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("Expected the largest ULID, got %s", got)
+	}
+	// 2016-07-30T23:54:10.259Z, the time of the example ULID in the spec
+	id := newAt(time.UnixMilli(1469922850259))
+	if !strings.HasPrefix(id, "01ARZ3NDEK") {
+		t.Errorf("Expected timestamp 01ARZ3NDEK, got %s", id[:10])
+	}
+}
+
+func TestNew_Concurrent(t *testing.T) {
+	const workers, perWorker = 8, 1000
+	var mu sync.Mutex
+	seen := make(map[string]bool, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]string, perWorker)
+			for i := range ids {
+				ids[i] = New()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range ids {
+				if seen[id] {
+					t.Errorf("Duplicate ID %s", id)
+				}
+				seen[id] = true
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"lb-1234/abc", true},
+		{"", false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.id); got != tt.valid {
+			t.Errorf("Valid(%q) = %v, want %v", tt.id, got, tt.valid)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	r := With(httptest.NewRequest(http.MethodGet, "/", nil), "01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	rr := httptest.NewRecorder()
+	Error(rr, r, "404 Not Found", http.StatusNotFound)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rr.Code)
+	}
+	if rr.Body.String() != "404 Not Found\nRequest ID: 01ARZ3NDEKTSV4RRFFQ69G5FAV\n" {
+		t.Errorf("Unexpected body %q", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	Error(rr, httptest.NewRequest(http.MethodGet, "/", nil), "404 Not Found", http.StatusNotFound)
+	if rr.Body.String() != "404 Not Found\n" {
+		t.Errorf("Expected no ID line without an ID, got %q", rr.Body.String())
+	}
+}
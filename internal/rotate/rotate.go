@@ -0,0 +1,341 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp in the names of rotated files. It sorts
+// chronologically and avoids colons, which Windows does not allow.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Options controls when files are rotated and how many are kept. Zero
+// values disable the corresponding limit.
+type Options struct {
+	MaxSize    int64         // bytes written before the file is rotated
+	MaxBackups int           // rotated files kept
+	MaxAge     time.Duration // age after which rotated files are removed
+	Compress   bool          // gzip rotated files
+}
+
+// Writer appends to a file, renaming it with a timestamp and starting a new
+// one once it reaches the maximum size. Old files are compressed and removed
+// in the background so writes are not held up.
+type Writer struct {
+	path string
+	perm os.FileMode
+	opts Options
+	now  func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+
+	mill chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// writers holds the writers that have not been closed, for ReopenAll
+var (
+	openMu  sync.Mutex
+	writers = make(map[*Writer]struct{})
+)
+
+// Open opens path for appending, creating it with perm if needed
+func Open(path string, perm os.FileMode, opts Options) (*Writer, error) {
+	w := &Writer{
+		path: path,
+		perm: perm,
+		opts: opts,
+		now:  time.Now,
+		mill: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	go w.run()
+
+	openMu.Lock()
+	writers[w] = struct{}{}
+	openMu.Unlock()
+	return w, nil
+}
+
+// ReopenAll reopens the files of all open writers, for use after an
+// external tool such as logrotate renamed them
+func ReopenAll() error {
+	openMu.Lock()
+	open := make([]*Writer, 0, len(writers))
+	for w := range writers {
+		open = append(open, w)
+	}
+	openMu.Unlock()
+
+	var errs []error
+	for _, w := range open {
+		if err := w.Reopen(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetOptions changes when the file is rotated and how many rotated files
+// are kept, starting with the next write
+func (w *Writer) SetOptions(opts Options) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.opts = opts
+}
+
+// open opens the file at the writer's path, the caller holding mu
+func (w *Writer) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, w.perm)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file, w.size = file, info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if p would take the file past the
+// maximum size. A single write larger than the maximum is never split.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate renames the current file and starts a new one
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes the file and opens the file at the writer's path again.
+// After an external tool renamed the file, this starts a new one.
+func (w *Writer) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	return w.open()
+}
+
+// rotate renames the current file, the caller holding mu
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	// Rotating twice within a millisecond must not overwrite a backup
+	t := w.now()
+	name := backupName(w.path, t)
+	for exists(name) || exists(name+".gz") {
+		t = t.Add(time.Millisecond)
+		name = backupName(w.path, t)
+	}
+	if err := os.Rename(w.path, name); err != nil && !os.IsNotExist(err) {
+		// Keep writing to the old file rather than losing entries
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("failed to rotate %s: %w", w.path, err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close closes the file and waits for background compression and removal
+// of old files to finish
+func (w *Writer) Close() error {
+	var err error
+	w.once.Do(func() {
+		openMu.Lock()
+		delete(writers, w)
+		openMu.Unlock()
+
+		w.mu.Lock()
+		if w.file != nil {
+			err = w.file.Close()
+			w.file = nil
+		}
+		w.mu.Unlock()
+		close(w.mill)
+		<-w.done
+	})
+	return err
+}
+
+// run compresses and removes rotated files after each rotation
+func (w *Writer) run() {
+	defer close(w.done)
+	for range w.mill {
+		w.millBackups()
+	}
+}
+
+// exists reports whether a file exists at path
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// backupName returns the name a file is rotated to: the timestamp goes
+// before the extension so tools keep recognizing the file type
+func backupName(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + t.Format(backupTimeFormat) + ext
+}
+
+// backup is a rotated file
+type backup struct {
+	path string
+	time time.Time
+}
+
+// Backups returns the rotated files of path, newest first. Compressed
+// files end in .gz.
+func Backups(path string) ([]string, error) {
+	found, err := backups(path)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(found))
+	for i, b := range found {
+		paths[i] = b.path
+	}
+	return paths, nil
+}
+
+// backups lists the rotated files of path, newest first
+func backups(path string) ([]backup, error) {
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var found []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimPrefix(name, prefix)
+		stamp = strings.TrimSuffix(stamp, ".gz")
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(stamp, ext), time.Local)
+		if err != nil {
+			continue
+		}
+		found = append(found, backup{path: filepath.Join(dir, name), time: t})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].time.After(found[j].time) })
+	return found, nil
+}
+
+// millBackups removes rotated files beyond the limits and compresses the
+// remaining ones. Failures are left for the next rotation to retry.
+func (w *Writer) millBackups() {
+	found, err := backups(w.path)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	opts := w.opts
+	w.mu.Unlock()
+
+	cutoff := time.Time{}
+	if opts.MaxAge > 0 {
+		cutoff = w.now().Add(-opts.MaxAge)
+	}
+	for i, b := range found {
+		if (opts.MaxBackups > 0 && i >= opts.MaxBackups) || b.time.Before(cutoff) {
+			os.Remove(b.path)
+			continue
+		}
+		if opts.Compress && !strings.HasSuffix(b.path, ".gz") {
+			compress(b.path, w.perm)
+		}
+	}
+}
+
+// compress gzips path next to it and removes the original
+func compress(path string, perm os.FileMode) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := path + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	in.Close()
+	return os.Remove(path)
+}
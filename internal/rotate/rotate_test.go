@@ -0,0 +1,250 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clock returns a now function advancing a second on every call
+func clock(start time.Time) func() time.Time {
+	t := start
+	return func() time.Time {
+		t = t.Add(time.Second)
+		return t
+	}
+}
+
+func openTest(t *testing.T, opts Options) (*Writer, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "access.log")
+	w, err := Open(path, 0644, opts)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	w.now = clock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local))
+	return w, path
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestWriterRotatesAtMaxSize(t *testing.T) {
+	w, path := openTest(t, Options{MaxSize: 10})
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	want := []string{
+		"access-2024-05-01T10-00-01.000.log",
+		"access-2024-05-01T10-00-02.000.log",
+		"access.log",
+	}
+	got := dirNames(t, filepath.Dir(path))
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("Expected files %v, got %v", want, got)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "third\n" {
+		t.Errorf("Expected the current file to hold the last write, got %q", data)
+	}
+	data, _ = os.ReadFile(filepath.Join(filepath.Dir(path), want[0]))
+	if string(data) != "first\n" {
+		t.Errorf("Expected the oldest backup to hold the first write, got %q", data)
+	}
+}
+
+func TestWriterContinuesExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, []byte("12345678\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := Open(path, 0644, Options{MaxSize: 10})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	w.Write([]byte("more\n"))
+	w.Close()
+
+	if names := dirNames(t, filepath.Dir(path)); len(names) != 2 {
+		t.Errorf("Expected the existing size to count towards the limit, got files %v", names)
+	}
+}
+
+func TestWriterKeepsMaxBackups(t *testing.T) {
+	w, path := openTest(t, Options{MaxBackups: 2})
+	for i := 0; i < 4; i++ {
+		w.Write([]byte("entry\n"))
+		if err := w.Rotate(); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+	}
+	w.Close()
+
+	want := []string{
+		"access-2024-05-01T10-00-03.000.log",
+		"access-2024-05-01T10-00-04.000.log",
+		"access.log",
+	}
+	got := dirNames(t, filepath.Dir(path))
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected files %v, got %v", want, got)
+	}
+}
+
+func TestWriterRemovesOldBackups(t *testing.T) {
+	w, path := openTest(t, Options{MaxAge: time.Hour})
+	dir := filepath.Dir(path)
+	old := filepath.Join(dir, "access-2024-04-01T10-00-00.000.log")
+	unrelated := filepath.Join(dir, "access-notes.log")
+	for _, name := range []string{old, unrelated} {
+		if err := os.WriteFile(name, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Rotate()
+	w.Close()
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected the backup older than the maximum age to be removed")
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Error("Expected files that are not backups to be left alone")
+	}
+}
+
+func TestWriterCompressesBackups(t *testing.T) {
+	w, path := openTest(t, Options{Compress: true})
+	w.Write([]byte("compressed entry\n"))
+	w.Rotate()
+	w.Close()
+
+	backup := filepath.Join(filepath.Dir(path), "access-2024-05-01T10-00-01.000.log.gz")
+	file, err := os.Open(backup)
+	if err != nil {
+		t.Fatalf("Expected a compressed backup: %v (files %v)", err, dirNames(t, filepath.Dir(path)))
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(gz)
+	if string(data) != "compressed entry\n" {
+		t.Errorf("Unexpected backup content %q", data)
+	}
+	if names := dirNames(t, filepath.Dir(path)); len(names) != 2 {
+		t.Errorf("Expected the uncompressed backup to be removed, got files %v", names)
+	}
+}
+
+func TestWriterClosed(t *testing.T) {
+	w, _ := openTest(t, Options{})
+	w.Close()
+	if _, err := w.Write([]byte("late\n")); err == nil {
+		t.Error("Expected writes after Close to fail")
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Expected a second Close to succeed, got %v", err)
+	}
+}
+
+func TestWriterReopen(t *testing.T) {
+	w, path := openTest(t, Options{})
+	defer w.Close()
+	w.Write([]byte("before\n"))
+
+	// An external tool renames the file, then asks for it to be reopened
+	moved := path + ".1"
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("still old\n"))
+	if err := ReopenAll(); err != nil {
+		t.Fatalf("ReopenAll failed: %v", err)
+	}
+	w.Write([]byte("after\n"))
+
+	data, _ := os.ReadFile(moved)
+	if string(data) != "before\nstill old\n" {
+		t.Errorf("Expected writes before reopening in the renamed file, got %q", data)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != "after\n" {
+		t.Errorf("Expected writes after reopening in a new file, got %q", data)
+	}
+}
+
+func TestReopenAllSkipsClosedWriters(t *testing.T) {
+	w, _ := openTest(t, Options{})
+	w.Close()
+	if err := ReopenAll(); err != nil {
+		t.Errorf("Expected closed writers to be forgotten, got %v", err)
+	}
+}
+
+func TestBackups(t *testing.T) {
+	w, path := openTest(t, Options{})
+	w.Rotate()
+	w.Rotate()
+	w.Close()
+
+	found, err := Backups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(path)
+	want := []string{
+		filepath.Join(dir, "access-2024-05-01T10-00-02.000.log"),
+		filepath.Join(dir, "access-2024-05-01T10-00-01.000.log"),
+	}
+	if strings.Join(found, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected backups newest first %v, got %v", want, found)
+	}
+}
+
+func TestWriterSetOptions(t *testing.T) {
+	w, path := openTest(t, Options{})
+	w.Write([]byte("first\n"))
+	w.SetOptions(Options{MaxSize: 8})
+	w.Write([]byte("second\n"))
+	w.Close()
+
+	if names := dirNames(t, filepath.Dir(path)); len(names) != 2 {
+		t.Errorf("Expected the new maximum size to rotate the file, got files %v", names)
+	}
+}
+
+func TestWriterRotatesWithinMillisecond(t *testing.T) {
+	w, path := openTest(t, Options{})
+	fixed := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	w.now = func() time.Time { return fixed }
+	for i := 0; i < 3; i++ {
+		w.Write([]byte("entry\n"))
+		w.Rotate()
+	}
+	w.Close()
+
+	if found, _ := Backups(path); len(found) != 3 {
+		t.Errorf("Expected every rotation to keep its file, got %v", found)
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net"
+	"net/http"
+
+	"otterserve/internal/config"
+)
+
+// AdminHost is implemented by servers that serve the admin API on a
+// listener of its own
+type AdminHost interface {
+	SetAdminHandler(handler http.Handler)
+	AdminAddr() net.Addr
+}
+
+// SetAdminHandler sets the handler of the admin listener. It has no effect
+// unless the admin API is enabled in the configuration.
+func (s *HTTPServer) SetAdminHandler(handler http.Handler) {
+	if s.admin == nil {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+	s.admin.routes.current.Store(mux)
+}
+
+// AdminAddr returns the bound address of the admin listener, or nil if it
+// is not running
+func (s *HTTPServer) AdminAddr() net.Addr {
+	if s.admin == nil {
+		return nil
+	}
+	s.addrMu.RLock()
+	defer s.addrMu.RUnlock()
+	return s.admin.addr
+}
+
+// Config returns the configuration in effect, including routes changed at
+// runtime. It must not be modified.
+func (s *HTTPServer) Config() *config.Config {
+	return s.currentConfig()
+}
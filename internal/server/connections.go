@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConnectionInfo describes an open client connection
+type ConnectionInfo struct {
+	Listener string    `json:"listener"`
+	Remote   string    `json:"remote"`
+	State    string    `json:"state"`
+	Since    time.Time `json:"since"`
+}
+
+// connTracker keeps the open connections of all listeners
+type connTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]*ConnectionInfo
+}
+
+// track is the ConnState hook of the listeners; connections are reported
+// with the local address they were accepted on
+func (ct *connTracker) track(conn net.Conn, state http.ConnState) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	switch state {
+	case http.StateNew:
+		if ct.conns == nil {
+			ct.conns = make(map[net.Conn]*ConnectionInfo)
+		}
+		ct.conns[conn] = &ConnectionInfo{
+			Listener: conn.LocalAddr().String(),
+			Remote:   conn.RemoteAddr().String(),
+			State:    state.String(),
+			Since:    time.Now(),
+		}
+	case http.StateClosed, http.StateHijacked:
+		delete(ct.conns, conn)
+	default:
+		if info, ok := ct.conns[conn]; ok {
+			info.State = state.String()
+		}
+	}
+}
+
+// list returns the open connections, oldest first
+func (ct *connTracker) list() []ConnectionInfo {
+	ct.mu.Lock()
+	conns := make([]ConnectionInfo, 0, len(ct.conns))
+	for _, info := range ct.conns {
+		conns = append(conns, *info)
+	}
+	ct.mu.Unlock()
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].Since.Before(conns[j].Since)
+	})
+	return conns
+}
+
+// Connections returns the client connections open on the server's
+// listeners, oldest first
+func (s *HTTPServer) Connections() []ConnectionInfo {
+	return s.conns.list()
+}
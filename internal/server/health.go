@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"otterserve/internal/config"
+)
+
+// healthCheck is the outcome of one readiness check
+type healthCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// healthReport is the body of the health endpoints
+type healthReport struct {
+	Status string        `json:"status"`
+	Uptime string        `json:"uptime,omitempty"`
+	Checks []healthCheck `json:"checks,omitempty"`
+}
+
+// livenessHandler reports that the process is serving requests
+func (s *HTTPServer) livenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, r, healthReport{
+			Status: "ok",
+			Uptime: time.Since(s.started).Round(time.Second).String(),
+		})
+	})
+}
+
+// readinessHandler reports whether the server can do its job: the route
+// directories are readable, the log files writable and no certificate is
+// about to expire. Checks use route paths and certificate names, not file
+// paths, since the endpoint needs no authentication. The checks follow the
+// configuration in effect, whose routes the admin API may have changed.
+func (s *HTTPServer) readinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := healthReport{Status: "ok", Checks: s.readinessChecks(s.currentConfig())}
+		for _, check := range report.Checks {
+			if check.Status != "ok" {
+				report.Status = "fail"
+			}
+		}
+		writeHealth(w, r, report)
+	})
+}
+
+// readinessChecks runs the readiness checks for cfg
+func (s *HTTPServer) readinessChecks(cfg *config.Config) []healthCheck {
+	var checks []healthCheck
+	for _, route := range cfg.Routes {
+		checks = append(checks, result("route "+route.Path, readableDir(route.Directory)))
+	}
+	if cfg.Logging.File != "" {
+		checks = append(checks, result("log file", writableFile(cfg.Logging.File)))
+	}
+	if cfg.Logging.Audit.Enabled {
+		checks = append(checks, result("audit log", writableFile(cfg.Logging.Audit.File)))
+	}
+
+	threshold := cfg.Health.CertExpiryThreshold()
+	now := time.Now()
+	s.addrMu.RLock()
+	stores := s.certStores
+	s.addrMu.RUnlock()
+	for _, store := range stores {
+		for _, leaf := range store.Leaves() {
+			name := leaf.Subject.CommonName
+			if len(leaf.DNSNames) > 0 {
+				name = leaf.DNSNames[0]
+			}
+			checks = append(checks, result("certificate "+name, notExpiring(leaf.NotAfter, now, threshold)))
+		}
+	}
+	if s.acme != nil {
+		status := s.acme.Status()
+		check := healthCheck{Name: "acme certificate", Status: "ok"}
+		if status.NotAfter.IsZero() {
+			check.Status, check.Message = "fail", "no certificate obtained yet"
+		} else if err := notExpiring(status.NotAfter, now, threshold); err != nil {
+			check.Status, check.Message = "fail", err.Error()
+		}
+		if status.LastError != "" {
+			check.Message = joinMessage(check.Message, "last renewal failed: "+status.LastError)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// result turns the error of a check into its outcome
+func result(name string, err error) healthCheck {
+	if err != nil {
+		return healthCheck{Name: name, Status: "fail", Message: err.Error()}
+	}
+	return healthCheck{Name: name, Status: "ok"}
+}
+
+// readableDir checks that the directory can be listed
+func readableDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("directory cannot be opened")
+	}
+	defer dir.Close()
+	if _, err := dir.Readdirnames(1); err != nil && err != io.EOF {
+		return fmt.Errorf("directory cannot be read")
+	}
+	return nil
+}
+
+// writableFile checks that the file exists and can be opened for appending
+func writableFile(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("file is missing")
+		}
+		return fmt.Errorf("file is not writable")
+	}
+	return file.Close()
+}
+
+// notExpiring checks that notAfter is more than threshold away
+func notExpiring(notAfter, now time.Time, threshold time.Duration) error {
+	left := notAfter.Sub(now)
+	if left <= 0 {
+		return fmt.Errorf("expired %s", notAfter.UTC().Format(time.RFC3339))
+	}
+	if left < threshold {
+		return fmt.Errorf("expires %s", notAfter.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// joinMessage appends b to the message a
+func joinMessage(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "; " + b
+}
+
+// writeHealth writes a health report, with status 503 unless it is ok
+func writeHealth(w http.ResponseWriter, r *http.Request, report healthReport) {
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/fileserver"
+	"otterserve/internal/logger"
+)
+
+// getHealth fetches a health endpoint and decodes its report
+func getHealth(t *testing.T, client *http.Client, url string) (int, healthReport) {
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var report healthReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	return resp.StatusCode, report
+}
+
+func TestHTTPServer_Health(t *testing.T) {
+	tempDir := t.TempDir()
+	routeDir := t.TempDir()
+	logFile := tempDir + "/server.log"
+	os.WriteFile(logFile, nil, 0644)
+
+	cfg := &config.Config{
+		Server:  config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Routes:  []config.RouteConfig{{Path: "/static", Directory: routeDir}},
+		Logging: config.LoggingConfig{File: logFile},
+	}
+	buf := &safeBuffer{}
+	log := logger.NewLogger(logger.InfoLevel, buf)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	}()
+	base := "http://" + server.GetAddr()
+
+	code, report := getHealth(t, http.DefaultClient, base+"/healthz")
+	if code != http.StatusOK || report.Status != "ok" {
+		t.Errorf("Expected live, got %d %q", code, report.Status)
+	}
+
+	code, report = getHealth(t, http.DefaultClient, base+"/readyz")
+	if code != http.StatusOK || report.Status != "ok" {
+		t.Errorf("Expected ready, got %d %+v", code, report)
+	}
+	if len(report.Checks) != 2 {
+		t.Errorf("Expected route and log file checks, got %+v", report.Checks)
+	}
+
+	os.RemoveAll(routeDir)
+	code, report = getHealth(t, http.DefaultClient, base+"/readyz")
+	if code != http.StatusServiceUnavailable || report.Status != "fail" {
+		t.Fatalf("Expected not ready after removing the route directory, got %d", code)
+	}
+	for _, check := range report.Checks {
+		if check.Name == "route /static" && check.Status != "fail" {
+			t.Errorf("Expected the route check to fail, got %+v", check)
+		}
+		if strings.Contains(check.Message, routeDir) {
+			t.Errorf("Expected file paths not to be reported, got %q", check.Message)
+		}
+	}
+
+	// Liveness does not depend on the readiness checks
+	if code, _ := getHealth(t, http.DefaultClient, base+"/healthz"); code != http.StatusOK {
+		t.Errorf("Expected still live, got %d", code)
+	}
+
+	if strings.Contains(buf.String(), "/healthz") || strings.Contains(buf.String(), "/readyz") {
+		t.Error("Expected health checks to be left out of the access log")
+	}
+}
+
+func TestHTTPServer_HealthAfterRegisterRoutes(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	cfg := &config.Config{
+		Server: config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Routes: []config.RouteConfig{{Path: "/a", Directory: oldDir}},
+	}
+	log := logger.NewLogger(logger.InfoLevel, &safeBuffer{})
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	}()
+
+	if err := server.RegisterRoutes([]config.RouteConfig{{Path: "/b", Directory: newDir}}); err != nil {
+		t.Fatalf("RegisterRoutes failed: %v", err)
+	}
+	os.RemoveAll(oldDir)
+
+	code, report := getHealth(t, http.DefaultClient, "http://"+server.GetAddr()+"/readyz")
+	if code != http.StatusOK || len(report.Checks) != 1 || report.Checks[0].Name != "route /b" {
+		t.Errorf("Expected only the registered route to be checked, got %d %+v", code, report)
+	}
+}
+
+func TestHTTPServer_HealthCertificateExpiry(t *testing.T) {
+	tempDir := t.TempDir()
+	// The test certificate expires within the hour
+	certFile, keyFile, pool := writeServerCertificate(t, tempDir)
+
+	tests := []struct {
+		name       string
+		threshold  time.Duration
+		wantStatus int
+	}{
+		{"expires after threshold", time.Minute, http.StatusOK},
+		{"expires within threshold", 0, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Server: config.ServerConfig{
+					Host: "127.0.0.1",
+					Port: 0,
+					TLS:  config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile},
+				},
+				Routes: []config.RouteConfig{{Path: "/static", Directory: tempDir}},
+				Health: config.HealthConfig{
+					ReadinessPath: "/ready",
+					CertExpiry:    tt.threshold,
+					LogRequests:   true,
+				},
+			}
+			buf := &safeBuffer{}
+			log := logger.NewLogger(logger.InfoLevel, buf)
+			server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+			if err := server.Start(); err != nil {
+				t.Fatalf("Failed to start server: %v", err)
+			}
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				server.Stop(ctx)
+			}()
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+			code, report := getHealth(t, client, "https://"+server.GetAddr()+"/ready")
+			if code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d %+v", tt.wantStatus, code, report)
+			}
+			found := false
+			for _, check := range report.Checks {
+				found = found || check.Name == "certificate localhost"
+			}
+			if !found {
+				t.Errorf("Expected a certificate check, got %+v", report.Checks)
+			}
+			if !strings.Contains(buf.String(), "/ready") {
+				t.Error("Expected health checks in the access log when log_requests is set")
+			}
+		})
+	}
+}
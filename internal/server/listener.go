@@ -0,0 +1,245 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"otterserve/internal/acme"
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+	"otterserve/internal/systemd"
+	"otterserve/internal/upgrade"
+)
+
+// listener is one address the server accepts connections on together with
+// the HTTP server answering them
+type listener struct {
+	cfg      config.ListenerConfig
+	server   *http.Server
+	routes   *handlerSwitch // routes exposed on this listener
+	redirect *http.Server
+	addr     net.Addr
+	sockets  []net.Listener // bound sockets, including the redirect one
+}
+
+// newListener creates a listener; routes are registered later
+func newListener(cfg config.ListenerConfig) *listener {
+	routes := newHandlerSwitch()
+	var handler http.Handler = routes
+	if cfg.NetworkName() == "unix" {
+		handler = localPeer(routes)
+	}
+	return &listener{
+		cfg:    cfg,
+		routes: routes,
+		server: &http.Server{
+			Addr:         cfg.Address,
+			Handler:      handler,
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			IdleTimeout:  120 * time.Second,
+		},
+	}
+}
+
+// startListener binds the listener and serves it in the background
+func (s *HTTPServer) startListener(l *listener) error {
+	tlsEnabled := l.cfg.TLS.Enabled
+	if tlsEnabled {
+		getCertificate, err := s.certificateSource(l.cfg.TLS)
+		if err != nil {
+			return fmt.Errorf("failed to configure tls: %w", err)
+		}
+		tlsConfig, err := newTLSConfig(l.cfg.TLS, getCertificate, s.authenticator)
+		if err != nil {
+			return fmt.Errorf("failed to configure tls: %w", err)
+		}
+		if l.cfg.TLS.ACME.Enabled && s.acme.OffersTLSALPN() {
+			tlsConfig.NextProtos = append(tlsConfig.NextProtos, acme.ALPNProto)
+		}
+		l.server.TLSConfig = tlsConfig
+		if !offersHTTP2(tlsConfig) {
+			// A non-nil map keeps net/http from adding h2 on its own
+			l.server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+	}
+
+	if l == s.admin {
+		s.logger.Info("Starting admin API", logger.Fields{
+			"network": l.cfg.NetworkName(),
+			"address": l.cfg.Address,
+		})
+	} else {
+		s.logger.Info("Starting HTTP server", logger.Fields{
+			"network":      l.cfg.NetworkName(),
+			"address":      l.cfg.Address,
+			"routes":       len(s.config.Routes),
+			"auth_enabled": s.authenticator.IsEnabled(),
+			"tls":          tlsEnabled,
+		})
+		l.server.ConnState = s.conns.track
+	}
+
+	netListener := s.takeInherited(l.cfg)
+	if netListener != nil {
+		s.logger.Info("Using socket passed by systemd", logger.Fields{
+			"address": netListener.Addr().String(),
+		})
+	} else {
+		var err error
+		netListener, err = listen(l.cfg)
+		if err != nil {
+			return fmt.Errorf("failed to create listener: %w", err)
+		}
+	}
+
+	s.addrMu.Lock()
+	l.addr = netListener.Addr()
+	l.sockets = append(l.sockets, netListener)
+	s.addrMu.Unlock()
+
+	go func() {
+		var err error
+		if tlsEnabled {
+			err = l.server.ServeTLS(netListener, "", "")
+		} else {
+			err = l.server.Serve(netListener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Server failed to start", logger.Fields{
+				"address": l.cfg.Address,
+				"error":   err.Error(),
+			})
+		}
+	}()
+
+	if tcpAddr, ok := netListener.Addr().(*net.TCPAddr); ok && tlsEnabled && l.cfg.TLS.RedirectPort != 0 {
+		if err := s.startRedirect(l, tcpAddr); err != nil {
+			l.server.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// inheritListeners returns the sockets handed over by the previous process
+// during an upgrade, or else those passed by the service manager
+var inheritListeners = func() ([]net.Listener, error) {
+	listeners, err := upgrade.Listeners()
+	if err != nil || len(listeners) > 0 {
+		return listeners, err
+	}
+	return systemd.Listeners()
+}
+
+// takeInherited removes and returns the inherited socket bound to the
+// configured address. A single inherited socket is used for a single
+// listener whatever its address, as with a plain ListenStream=443.
+func (s *HTTPServer) takeInherited(cfg config.ListenerConfig) net.Listener {
+	for i, inherited := range s.inherited {
+		if boundTo(cfg, inherited.Addr()) || (len(s.listeners) == 1 && len(s.inherited) == 1) {
+			s.inherited = append(s.inherited[:i], s.inherited[i+1:]...)
+			return inherited
+		}
+	}
+	return nil
+}
+
+// closeInherited closes the inherited sockets no listener claimed
+func (s *HTTPServer) closeInherited() {
+	for _, inherited := range s.inherited {
+		s.logger.Warn("Closing socket passed by systemd that matches no listener", logger.Fields{
+			"address": inherited.Addr().String(),
+		})
+		inherited.Close()
+	}
+	s.inherited = nil
+}
+
+// boundTo reports whether addr is the address a listener is configured for.
+// Wildcard hosts match any wildcard address, since systemd binds [::] for
+// both IPv4 and IPv6.
+func boundTo(cfg config.ListenerConfig, addr net.Addr) bool {
+	if cfg.NetworkName() == "unix" {
+		return addr.Network() == "unix" && addr.String() == cfg.Address
+	}
+
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	host, port, err := net.SplitHostPort(cfg.Address)
+	if err != nil || port != strconv.Itoa(tcpAddr.Port) {
+		return false
+	}
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		return tcpAddr.IP.IsUnspecified()
+	}
+	resolved, err := net.ResolveTCPAddr(cfg.NetworkName(), cfg.Address)
+	return err == nil && resolved.IP.Equal(tcpAddr.IP)
+}
+
+// listen binds the configured address. A unix socket file left behind by a
+// previous run is replaced, but one that still accepts connections is not.
+func listen(cfg config.ListenerConfig) (net.Listener, error) {
+	network := cfg.NetworkName()
+	if network != "unix" {
+		return net.Listen(network, cfg.Address)
+	}
+
+	mode, err := cfg.SocketMode()
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(cfg.Address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", cfg.Address)
+		}
+		if conn, err := net.Dial("unix", cfg.Address); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another process", cfg.Address)
+		}
+		if err := os.Remove(cfg.Address); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	netListener, err := net.Listen("unix", cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(cfg.Address, mode); err != nil {
+			netListener.Close()
+			return nil, fmt.Errorf("failed to set socket mode: %w", err)
+		}
+	}
+	return netListener, nil
+}
+
+// close stops serving immediately, for listeners started before a later one
+// failed
+func (l *listener) close() {
+	if l.redirect != nil {
+		l.redirect.Close()
+	}
+	l.server.Close()
+}
+
+// localPeer reports connections over a unix socket as coming from the
+// loopback address, since only local processes can reach the socket. IP
+// filters and trusted_proxies then apply as for a proxy on 127.0.0.1.
+func localPeer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.RemoteAddr = "127.0.0.1:0"
+		next.ServeHTTP(w, r)
+	})
+}
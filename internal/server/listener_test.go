@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/fileserver"
+	"otterserve/internal/logger"
+)
+
+// unixClient returns a client that sends every request to a unix socket
+func unixClient(socket string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}
+}
+
+func TestHTTPServer_Listeners(t *testing.T) {
+	tempDir := t.TempDir()
+	publicDir := filepath.Join(tempDir, "public")
+	privateDir := filepath.Join(tempDir, "private")
+	os.MkdirAll(publicDir, 0755)
+	os.MkdirAll(privateDir, 0755)
+	os.WriteFile(filepath.Join(publicDir, "a.txt"), []byte("public"), 0644)
+	os.WriteFile(filepath.Join(privateDir, "b.txt"), []byte("private"), 0644)
+
+	// Short path: unix socket paths are limited to about 100 bytes
+	socketDir, err := os.MkdirTemp("", "otter")
+	if err != nil {
+		t.Fatalf("Failed to create socket directory: %v", err)
+	}
+	defer os.RemoveAll(socketDir)
+	socket := filepath.Join(socketDir, "otter.sock")
+
+	// A socket file left behind by a crashed process is replaced
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Listeners: []config.ListenerConfig{
+				{Address: "127.0.0.1:0"},
+				{Network: "unix", Address: socket, Mode: "0660", Routes: []string{"public"}},
+			},
+		},
+		Routes: []config.RouteConfig{
+			{Path: "/public", Directory: publicDir},
+			{Path: "/private", Directory: privateDir},
+		},
+	}
+	log := logger.NewLogger(logger.InfoLevel, nil)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	stopped := false
+	stop := func() {
+		if stopped {
+			return
+		}
+		stopped = true
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	}
+	defer stop()
+
+	addrs := server.GetAddrs()
+	if len(addrs) != 2 {
+		t.Fatalf("Expected 2 bound addresses, got %v", addrs)
+	}
+	if addrs[0].Network() != "tcp" || addrs[0].String() != server.GetAddr() {
+		t.Errorf("Expected the tcp listener first, got %s %s", addrs[0].Network(), addrs[0])
+	}
+	if addrs[1].Network() != "unix" || addrs[1].String() != socket {
+		t.Errorf("Expected the unix socket second, got %s %s", addrs[1].Network(), addrs[1])
+	}
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatalf("Expected socket file: %v", err)
+	}
+	if info.Mode().Perm() != 0660 {
+		t.Errorf("Expected socket mode 0660, got %v", info.Mode().Perm())
+	}
+
+	tests := []struct {
+		name     string
+		client   *http.Client
+		base     string
+		path     string
+		expected int
+	}{
+		{"tcp public", http.DefaultClient, "http://" + server.GetAddr(), "/public/a.txt", http.StatusOK},
+		{"tcp private", http.DefaultClient, "http://" + server.GetAddr(), "/private/b.txt", http.StatusOK},
+		{"unix public", unixClient(socket), "http://otterserve", "/public/a.txt", http.StatusOK},
+		{"unix private", unixClient(socket), "http://otterserve", "/private/b.txt", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		resp, err := tt.client.Get(tt.base + tt.path)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.name, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.expected, resp.StatusCode)
+		}
+	}
+
+	// The socket file is removed on shutdown
+	stop()
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("Expected socket file to be removed, got %v", err)
+	}
+}
+
+func TestListen_SocketInUse(t *testing.T) {
+	socketDir, err := os.MkdirTemp("", "otter")
+	if err != nil {
+		t.Fatalf("Failed to create socket directory: %v", err)
+	}
+	defer os.RemoveAll(socketDir)
+	socket := filepath.Join(socketDir, "otter.sock")
+
+	active, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	defer active.Close()
+
+	if _, err := listen(config.ListenerConfig{Network: "unix", Address: socket}); err == nil {
+		t.Error("Expected a socket in use to be left alone")
+	}
+
+	regular := filepath.Join(socketDir, "file")
+	os.WriteFile(regular, nil, 0644)
+	if _, err := listen(config.ListenerConfig{Network: "unix", Address: regular}); err == nil {
+		t.Error("Expected a regular file not to be replaced")
+	}
+}
+
+func TestHTTPServer_RootRoute(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "index.txt"), []byte("root"), 0644)
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Routes: []config.RouteConfig{{Path: "/", Directory: tempDir}},
+	}
+	log := logger.NewLogger(logger.InfoLevel, nil)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	}()
+
+	resp, err := http.Get("http://" + server.GetAddr() + "/index.txt")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "root" {
+		t.Errorf("Expected file from the root route, got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestHTTPServer_HandoffSockets(t *testing.T) {
+	tempDir := t.TempDir()
+	certFile, keyFile, _ := writeServerCertificate(t, tempDir)
+	pidFile := filepath.Join(tempDir, "otterserve.pid")
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			PIDFile: pidFile,
+			Listeners: []config.ListenerConfig{
+				{Address: "127.0.0.1:0", TLS: config.TLSConfig{
+					Enabled: true, CertFile: certFile, KeyFile: keyFile, RedirectPort: freePort(t),
+				}},
+				{Address: "127.0.0.1:0"},
+			},
+		},
+		Routes: []config.RouteConfig{{Path: "/static", Directory: tempDir}},
+	}
+	log := logger.NewLogger(logger.InfoLevel, nil)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	// Both listeners and the redirect listener are handed over
+	if sockets := server.Listeners(); len(sockets) != 3 {
+		t.Errorf("Expected 3 sockets to hand over, got %d", len(sockets))
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil || strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected PID file with own PID, got %q (%v)", data, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Stop(ctx)
+	if _, err := os.Stat(pidFile); !os.IsNotExist(err) {
+		t.Errorf("Expected PID file to be removed on stop, got %v", err)
+	}
+}
+
+func TestHTTPServer_StopCleansUpAfterTimeout(t *testing.T) {
+	tempDir := t.TempDir()
+	pidFile := filepath.Join(tempDir, "otterserve.pid")
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			PIDFile:   pidFile,
+			Listeners: []config.ListenerConfig{{Address: "127.0.0.1:0"}},
+		},
+		Routes: []config.RouteConfig{{Path: "/static", Directory: tempDir}},
+	}
+	log := logger.NewLogger(logger.InfoLevel, nil)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	// A connection that has not sent its request keeps Shutdown waiting
+	conn, err := net.Dial("tcp", server.GetAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := server.Stop(ctx); err == nil {
+		t.Error("Expected Stop to report that connections did not finish")
+	}
+	if _, err := os.Stat(pidFile); !os.IsNotExist(err) {
+		t.Errorf("Expected PID file to be removed on stop, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"otterserve/internal/metrics"
+)
+
+// unmatchedRoute labels requests that match no configured route
+const unmatchedRoute = "none"
+
+var (
+	requestsTotal = metrics.Default.NewCounterVec("otterserve_http_requests_total",
+		"HTTP requests completed, by route, method and status class.", "route", "method", "code")
+	requestDuration = metrics.Default.NewHistogramVec("otterserve_http_request_duration_seconds",
+		"Time to serve HTTP requests, by route, method and status class.", metrics.DefaultBuckets, "route", "method", "code")
+	responseBytes = metrics.Default.NewCounterVec("otterserve_http_response_bytes_total",
+		"Bytes of response bodies written, by route.", "route")
+	requestsInFlight = metrics.Default.NewGauge("otterserve_http_requests_in_flight",
+		"HTTP requests currently being served.")
+)
+
+// observeRequest records a completed request. route is the configured
+// route path, never the request URL, so the number of series stays bounded.
+func observeRequest(route, method string, status int, duration time.Duration, bytes int64) {
+	method, code := methodLabel(method), statusClass(status)
+	requestsTotal.WithLabelValues(route, method, code).Inc()
+	requestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+	responseBytes.WithLabelValues(route).Add(float64(bytes))
+}
+
+// methodLabel returns the method, or "other" for methods clients may make up
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// statusClass returns the class of a status code such as 2xx
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "other"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/fileserver"
+	"otterserve/internal/logger"
+)
+
+func TestHTTPServer_Metrics(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "file.txt"), []byte("hello"), 0644)
+
+	cfg := &config.Config{
+		Server:  config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Routes:  []config.RouteConfig{{Path: "/metrics-test", Directory: tempDir}},
+		Metrics: config.MetricsConfig{Enabled: true},
+	}
+	log := logger.NewLogger(logger.InfoLevel, io.Discard)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	}()
+	base := "http://" + server.GetAddr()
+
+	for _, path := range []string{"/metrics-test/file.txt", "/metrics-test/file.txt", "/random/1", "/random/2"} {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	req, _ := http.NewRequest("BREW", base+"/metrics-test/file.txt", nil)
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(base + "/metrics")
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 from /metrics, got %d", resp.StatusCode)
+	}
+
+	for _, line := range []string{
+		`otterserve_http_requests_total{route="/metrics-test",method="GET",code="2xx"} 2`,
+		`otterserve_http_requests_total{route="none",method="GET",code="4xx"}`,
+		`otterserve_http_requests_total{route="/metrics-test",method="other",code=`,
+		`otterserve_http_request_duration_seconds_count{route="/metrics-test",method="GET",code="2xx"} 2`,
+		`otterserve_http_response_bytes_total{route="/metrics-test"} 15`,
+		"otterserve_http_requests_in_flight ",
+		"go_goroutines ",
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("Expected %q in metrics", line)
+		}
+	}
+
+	// Series are labelled with route paths, never request paths
+	if strings.Contains(string(body), "/random/") || strings.Contains(string(body), "file.txt") {
+		t.Error("Expected request paths not to appear in labels")
+	}
+}
+
+func TestHTTPServer_MetricsAllowList(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Server:  config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Routes:  []config.RouteConfig{{Path: "/files", Directory: tempDir}},
+		Metrics: config.MetricsConfig{Enabled: true, Path: "/internal/metrics", Allow: []string{"10.0.0.0/8"}},
+	}
+	server := NewHTTPServer(cfg, logger.NewLogger(logger.InfoLevel, io.Discard), auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	}()
+
+	resp, err := http.Get("http://" + server.GetAddr() + "/internal/metrics")
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 outside the metrics allow list, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+)
+
+// defaultWatchInterval is how often the configuration file is checked for
+// changes when no interval is configured
+const defaultWatchInterval = 2 * time.Second
+
+// Reconfigurable is implemented by servers that can switch to a new
+// configuration while running
+type Reconfigurable interface {
+	Apply(cfg *config.Config) error
+}
+
+// Apply switches the running server to cfg. Routes, IP filters, trusted
+// proxies, authentication and the log level take effect with the next
+// request; requests in flight finish on the handlers they started with.
+// Settings that need new sockets or files keep their current values and
+// are reported in the log. On error nothing changes.
+func (s *HTTPServer) Apply(cfg *config.Config) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	current := s.currentConfig()
+	applied, kept := keepRestartSettings(current, cfg)
+
+	level, err := logger.ParseLogLevel(applied.Logging.Level)
+	if err != nil {
+		return err
+	}
+
+	// A new authenticator starts without sessions and lockout state, so
+	// the running one is kept unless its settings changed
+	s.configMu.RLock()
+	authenticator := s.authenticator
+	s.configMu.RUnlock()
+	if !reflect.DeepEqual(current.Auth, applied.Auth) {
+		manager, err := auth.NewAuthenticatorFromConfig(applied.Auth, s.logger)
+		if err != nil {
+			return fmt.Errorf("failed to create authenticator: %w", err)
+		}
+		authenticator = manager
+	}
+
+	set, err := s.buildRoutes(applied, applied.Routes, authenticator)
+	if err != nil {
+		return err
+	}
+
+	for _, setting := range kept {
+		s.logger.Warn("Configuration change requires a restart", logger.Fields{
+			"setting": setting,
+		})
+	}
+
+	s.configMu.Lock()
+	s.config = applied
+	s.authenticator = authenticator
+	s.configMu.Unlock()
+	s.swapRoutes(set)
+	s.logger.SetLevel(level)
+	return nil
+}
+
+// currentConfig returns the configuration in effect
+func (s *HTTPServer) currentConfig() *config.Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config
+}
+
+// keepRestartSettings returns a copy of next with the settings that cannot
+// change while running taken from current, and the names of those that
+// differed
+func keepRestartSettings(current, next *config.Config) (*config.Config, []string) {
+	applied := *next
+	var kept []string
+	keep := func(name string, cur, nxt interface{}, restore func()) {
+		if !reflect.DeepEqual(cur, nxt) {
+			kept = append(kept, name)
+			restore()
+		}
+	}
+
+	// Listeners may change the routes they expose, nothing else
+	curListeners := current.Server.ListenerConfigs()
+	nextListeners := next.Server.ListenerConfigs()
+	if !sameListeners(curListeners, nextListeners) {
+		kept = append(kept, "server.listeners")
+		applied.Server.Host, applied.Server.Port = current.Server.Host, current.Server.Port
+		applied.Server.TLS = current.Server.TLS
+		applied.Server.Listeners = current.Server.Listeners
+	}
+
+	keep("server.pid_file", current.Server.PIDFile, next.Server.PIDFile, func() {
+		applied.Server.PIDFile = current.Server.PIDFile
+	})
+	keep("server.reload", current.Server.Reload, next.Server.Reload, func() {
+		applied.Server.Reload = current.Server.Reload
+	})
+	keep("logging.file", current.Logging.File, next.Logging.File, func() {
+		applied.Logging.File = current.Logging.File
+	})
+	keep("logging.format", current.Logging.Format, next.Logging.Format, func() {
+		applied.Logging.Format = current.Logging.Format
+	})
+	keep("logging.time_zone", current.Logging.TimeZone, next.Logging.TimeZone, func() {
+		applied.Logging.TimeZone = current.Logging.TimeZone
+	})
+	keep("logging.time_precision", current.Logging.TimePrecision, next.Logging.TimePrecision, func() {
+		applied.Logging.TimePrecision = current.Logging.TimePrecision
+	})
+	keep("logging.rotation", current.Logging.Rotation, next.Logging.Rotation, func() {
+		applied.Logging.Rotation = current.Logging.Rotation
+	})
+	keep("logging.audit", current.Logging.Audit, next.Logging.Audit, func() {
+		applied.Logging.Audit = current.Logging.Audit
+	})
+	keep("logging.access", current.Logging.Access, next.Logging.Access, func() {
+		applied.Logging.Access = current.Logging.Access
+	})
+	keep("logging.sinks", current.Logging.Sinks, next.Logging.Sinks, func() {
+		applied.Logging.Sinks = current.Logging.Sinks
+	})
+	keep("logging.async", current.Logging.Async, next.Logging.Async, func() {
+		applied.Logging.Async = current.Logging.Async
+	})
+	keep("logging.redact", current.Logging.Redact, next.Logging.Redact, func() {
+		applied.Logging.Redact = current.Logging.Redact
+	})
+	keep("admin", current.Admin, next.Admin, func() {
+		applied.Admin = current.Admin
+	})
+	keep("tracing", current.Tracing, next.Tracing, func() {
+		applied.Tracing = current.Tracing
+	})
+	keep("metrics.admin", current.Metrics.Admin, next.Metrics.Admin, func() {
+		applied.Metrics.Admin = current.Metrics.Admin
+	})
+	// Client certificates are requested during the TLS handshake
+	keep("auth.client_cert", current.Auth.ClientCert, next.Auth.ClientCert, func() {
+		applied.Auth.ClientCert = current.Auth.ClientCert
+	})
+	return &applied, kept
+}
+
+// sameListeners reports whether two listener lists differ at most in the
+// routes they expose
+func sameListeners(a, b []config.ListenerConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := a[i], b[i]
+		x.Routes, y.Routes = nil, nil
+		if !reflect.DeepEqual(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+// Reloader applies changes to the configuration file to a running server.
+// Reloads are triggered by SIGHUP, by Watch or by calling Reload.
+type Reloader struct {
+	path    string
+	manager config.ConfigManager
+	server  Reconfigurable
+	prepare func(*config.Config)
+	logger  logger.Logger
+	mu      sync.Mutex
+}
+
+// NewReloader creates a reloader for the configuration file at path. prepare,
+// if not nil, adjusts each loaded configuration the way the initial one was
+// adjusted, such as making relative paths absolute.
+func NewReloader(path string, manager config.ConfigManager, server Reconfigurable, prepare func(*config.Config), log logger.Logger) *Reloader {
+	if log == nil {
+		log = logger.NewLogger(logger.ErrorLevel, nil)
+	}
+	return &Reloader{
+		path:    path,
+		manager: manager,
+		server:  server,
+		prepare: prepare,
+		logger:  log,
+	}
+}
+
+// Reload loads and validates the configuration file and applies it. An
+// invalid configuration is logged and rejected, and the server keeps
+// running with the one it has.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.reload()
+	if err != nil {
+		r.logger.Error("Configuration reload failed, keeping current configuration", logger.Fields{
+			"path":  r.path,
+			"error": err.Error(),
+		})
+		return err
+	}
+	r.logger.Info("Configuration reloaded", logger.Fields{
+		"path": r.path,
+	})
+	return nil
+}
+
+// reload does the work of Reload
+func (r *Reloader) reload() error {
+	cfg, err := r.manager.Load(r.path)
+	if err != nil {
+		return err
+	}
+	config.ApplyDefaults(cfg)
+	if r.prepare != nil {
+		r.prepare(cfg)
+	}
+	if err := r.manager.Validate(cfg); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	return r.server.Apply(cfg)
+}
+
+// Watch reloads the configuration whenever the file's modification time
+// changes, checking every interval until ctx is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	lastMod := r.modTime()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if mod := r.modTime(); !mod.Equal(lastMod) {
+				lastMod = mod
+				r.Reload()
+			}
+		}
+	}
+}
+
+// modTime returns the modification time of the configuration file, or the
+// zero time if it cannot be read
+func (r *Reloader) modTime() time.Time {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
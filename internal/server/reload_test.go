@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/fileserver"
+	"otterserve/internal/logger"
+)
+
+// startReloadServer starts a server on a free port with routes /a and /b
+// served from their own directories
+func startReloadServer(t *testing.T, log logger.Logger) (*HTTPServer, *config.Config, string) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		os.MkdirAll(filepath.Join(tempDir, name), 0755)
+		os.WriteFile(filepath.Join(tempDir, name, "file.txt"), []byte(name), 0644)
+	}
+
+	cfg := &config.Config{
+		Server:  config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Routes:  []config.RouteConfig{{Path: "/a", Directory: filepath.Join(tempDir, "a")}},
+		Logging: config.LoggingConfig{Level: "info"},
+	}
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	})
+	return server, cfg, tempDir
+}
+
+// status returns the status code of a GET request
+func status(t *testing.T, url string) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestHTTPServer_Apply(t *testing.T) {
+	var logBuffer safeBuffer
+	log := logger.NewLogger(logger.InfoLevel, &logBuffer)
+	server, cfg, tempDir := startReloadServer(t, log)
+	base := "http://" + server.GetAddr()
+
+	if code := status(t, base+"/a/file.txt"); code != http.StatusOK {
+		t.Fatalf("Expected /a to be served, got %d", code)
+	}
+
+	// Keep requests running while the configuration changes
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if resp, err := http.Get(base + "/a/file.txt"); err == nil {
+					resp.Body.Close()
+				}
+			}
+		}
+	}()
+
+	next := *cfg
+	next.Routes = []config.RouteConfig{{Path: "/b", Directory: filepath.Join(tempDir, "b")}}
+	next.Server.Allow = []string{"127.0.0.0/8"}
+	next.Logging.Level = "warn"
+	err := server.Apply(&next)
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if code := status(t, base+"/a/file.txt"); code != http.StatusNotFound {
+		t.Errorf("Expected /a to be gone, got %d", code)
+	}
+	if code := status(t, base+"/b/file.txt"); code != http.StatusOK {
+		t.Errorf("Expected /b to be served, got %d", code)
+	}
+	if log.GetLevel() != logger.WarnLevel {
+		t.Errorf("Expected log level warn, got %v", log.GetLevel())
+	}
+	if server.currentConfig().Routes[0].Path != "/b" {
+		t.Error("Expected the applied configuration to be current")
+	}
+
+	// A configuration that cannot be applied leaves the running one alone
+	broken := next
+	broken.Routes = []config.RouteConfig{{Path: "/a", Directory: filepath.Join(tempDir, "a"), Allow: []string{"not-a-network"}}}
+	if err := server.Apply(&broken); err == nil {
+		t.Error("Expected an error for an invalid allow list")
+	}
+	if code := status(t, base+"/b/file.txt"); code != http.StatusOK {
+		t.Errorf("Expected /b to be served after a failed reload, got %d", code)
+	}
+}
+
+func TestHTTPServer_ApplyKeepsAuthenticator(t *testing.T) {
+	server, cfg, _ := startReloadServer(t, logger.NewLogger(logger.InfoLevel, nil))
+	before := server.authenticator
+
+	next := *cfg
+	next.Server.Deny = []string{"192.0.2.0/24"}
+	if err := server.Apply(&next); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if server.authenticator != before {
+		t.Error("Expected the authenticator to be kept when auth settings are unchanged")
+	}
+
+	next.Auth = config.AuthConfig{Enabled: true, Username: "admin", Password: "secret"}
+	if err := server.Apply(&next); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if server.authenticator == before {
+		t.Error("Expected a new authenticator for changed auth settings")
+	}
+	if code := status(t, "http://"+server.GetAddr()+"/a/file.txt"); code != http.StatusUnauthorized {
+		t.Errorf("Expected authentication to be required, got %d", code)
+	}
+}
+
+func TestKeepRestartSettings(t *testing.T) {
+	current := &config.Config{
+		Server: config.ServerConfig{Listeners: []config.ListenerConfig{
+			{Address: "127.0.0.1:8080"},
+			{Network: "unix", Address: "/run/otter.sock", Routes: []string{"/a"}},
+		}},
+		Logging: config.LoggingConfig{Level: "info", File: "/var/log/otter.log"},
+	}
+
+	next := *current
+	next.Server.Listeners = []config.ListenerConfig{
+		{Address: "127.0.0.1:8080", Routes: []string{"/b"}},
+		{Network: "unix", Address: "/run/otter.sock"},
+	}
+	next.Logging.Level = "debug"
+	applied, kept := keepRestartSettings(current, &next)
+	if len(kept) != 0 {
+		t.Errorf("Expected route restrictions and level to apply, kept %v", kept)
+	}
+	if applied.Server.Listeners[0].Routes[0] != "/b" || applied.Logging.Level != "debug" {
+		t.Errorf("Unexpected applied configuration: %+v", applied)
+	}
+
+	next.Server.Listeners = []config.ListenerConfig{{Address: "127.0.0.1:9090"}}
+	next.Logging.File = "/tmp/otter.log"
+	applied, kept = keepRestartSettings(current, &next)
+	if strings.Join(kept, ",") != "server.listeners,logging.file" {
+		t.Errorf("Expected listeners and log file to need a restart, got %v", kept)
+	}
+	if len(applied.Server.Listeners) != 2 || applied.Logging.File != "/var/log/otter.log" {
+		t.Errorf("Expected the current settings to be kept, got %+v", applied)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	var logBuffer safeBuffer
+	log := logger.NewLogger(logger.InfoLevel, &logBuffer)
+	server, _, tempDir := startReloadServer(t, log)
+	base := "http://" + server.GetAddr()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	write := func(routePath string) {
+		data := "server:\n  host: 127.0.0.1\n  port: 0\nroutes:\n  - path: " + routePath +
+			"\n    directory: " + filepath.Join(tempDir, strings.Trim(routePath, "/")) + "\n"
+		if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	write("/a")
+
+	reloader := NewReloader(configPath, config.NewConfigManager(), server, nil, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 20*time.Millisecond)
+
+	// The watcher picks up the change; the modification time must differ
+	time.Sleep(50 * time.Millisecond)
+	write("/b")
+	os.Chtimes(configPath, time.Now().Add(time.Second), time.Now().Add(time.Second))
+	deadline := time.Now().Add(5 * time.Second)
+	for status(t, base+"/b/file.txt") != http.StatusOK && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if code := status(t, base+"/b/file.txt"); code != http.StatusOK {
+		t.Fatalf("Expected the watcher to apply the new route, got %d", code)
+	}
+
+	// Invalid YAML is rejected and logged
+	os.WriteFile(configPath, []byte("routes: [\n"), 0644)
+	if err := reloader.Reload(); err == nil {
+		t.Error("Expected invalid YAML to be rejected")
+	}
+	if code := status(t, base+"/b/file.txt"); code != http.StatusOK {
+		t.Errorf("Expected the running configuration to keep serving, got %d", code)
+	}
+	if !strings.Contains(logBuffer.String(), "Configuration reload failed") {
+		t.Errorf("Expected the failure in the log, got: %s", logBuffer.String())
+	}
+}
+
+// safeBuffer is a strings.Builder that may be written from several goroutines
+type safeBuffer struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (sb *safeBuffer) Write(p []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.b.Write(p)
+}
+
+func (sb *safeBuffer) String() string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.b.String()
+}
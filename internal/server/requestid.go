@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+
+	"otterserve/internal/requestid"
+)
+
+// requestIDMiddleware gives every request an ID, stored in its context and
+// echoed in header. An ID in header is kept if a trusted proxy sent it, so
+// the load balancer's logs and ours can be matched; otherwise a new one is
+// made.
+func (s *HTTPServer) requestIDMiddleware(header string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(header)
+		if id == "" || !requestid.Valid(id) || !s.clientIP.Load().FromTrustedProxy(r) {
+			id = requestid.New()
+		}
+		w.Header().Set(header, id)
+		next.ServeHTTP(w, requestid.With(r, id))
+	})
+}
+
+// instrument wraps a handler in the middleware every request passes
+// through: request ID, tracing and logging under the route path
+func (s *HTTPServer) instrument(header, route string, next http.Handler) http.Handler {
+	return s.requestIDMiddleware(header, s.tracingMiddleware(route, s.loggingMiddleware(route, next)))
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/fileserver"
+	"otterserve/internal/logger"
+)
+
+// startRequestIDServer starts a server trusting the given proxies and
+// returns it with its log
+func startRequestIDServer(t *testing.T, server config.ServerConfig) (*HTTPServer, *safeBuffer) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "file.txt"), []byte("hello"), 0644)
+
+	server.Host, server.Port = "127.0.0.1", 0
+	cfg := &config.Config{
+		Server: server,
+		Routes: []config.RouteConfig{{Path: "/static", Directory: tempDir}},
+	}
+	buf := &safeBuffer{}
+	log := logger.NewLogger(logger.InfoLevel, buf)
+	s := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.Stop(ctx)
+	})
+	return s, buf
+}
+
+// getWithHeader requests url with one header set and returns the response
+// and its body
+func getWithHeader(t *testing.T, url, name, value string) (*http.Response, string) {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if name != "" {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	return resp, string(body)
+}
+
+func TestHTTPServer_RequestID(t *testing.T) {
+	server, buf := startRequestIDServer(t, config.ServerConfig{})
+	base := "http://" + server.GetAddr()
+
+	resp1, _ := getWithHeader(t, base+"/static/file.txt", "", "")
+	resp2, _ := getWithHeader(t, base+"/static/file.txt", "", "")
+	id1, id2 := resp1.Header.Get("X-Request-ID"), resp2.Header.Get("X-Request-ID")
+	if len(id1) != 26 || id1 == id2 {
+		t.Errorf("Expected distinct ULIDs, got %q and %q", id1, id2)
+	}
+	if !strings.Contains(buf.String(), "request_id="+id1) {
+		t.Error("Expected the ID in the request log")
+	}
+
+	// Error bodies quote the ID
+	resp, body := getWithHeader(t, base+"/static/missing.txt", "", "")
+	if id := resp.Header.Get("X-Request-ID"); !strings.Contains(body, "Request ID: "+id) {
+		t.Errorf("Expected the request ID %s in the error body, got %q", id, body)
+	}
+	resp, body = getWithHeader(t, base+"/unknown", "", "")
+	if id := resp.Header.Get("X-Request-ID"); resp.StatusCode != http.StatusNotFound || !strings.Contains(body, "Request ID: "+id) {
+		t.Errorf("Expected the request ID %s in the 404 body, got %q", id, body)
+	}
+
+	// Clients that are not trusted proxies cannot choose the ID
+	resp, _ = getWithHeader(t, base+"/static/file.txt", "X-Request-ID", "lb-1234")
+	if resp.Header.Get("X-Request-ID") == "lb-1234" {
+		t.Error("Expected an ID from an untrusted client to be replaced")
+	}
+}
+
+func TestHTTPServer_RequestIDFromTrustedProxy(t *testing.T) {
+	server, buf := startRequestIDServer(t, config.ServerConfig{
+		TrustedProxies:  []string{"127.0.0.1/32"},
+		RequestIDHeader: "X-Correlation-ID",
+	})
+	url := "http://" + server.GetAddr() + "/static/file.txt"
+
+	resp, _ := getWithHeader(t, url, "X-Correlation-ID", "lb-1234")
+	if got := resp.Header.Get("X-Correlation-ID"); got != "lb-1234" {
+		t.Errorf("Expected the proxy's ID to be echoed, got %q", got)
+	}
+	if resp.Header.Get("X-Request-ID") != "" {
+		t.Error("Expected only the configured header to be set")
+	}
+	if !strings.Contains(buf.String(), "request_id=lb-1234") {
+		t.Error("Expected the proxy's ID in the request log")
+	}
+
+	// IDs that could break log lines are replaced
+	resp, _ = getWithHeader(t, url, "X-Correlation-ID", strings.Repeat("x", 200))
+	if got := resp.Header.Get("X-Correlation-ID"); len(got) != 26 {
+		t.Errorf("Expected an overlong ID to be replaced, got %q", got)
+	}
+}
@@ -2,17 +2,33 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"otterserve/internal/accesslog"
+	"otterserve/internal/acme"
+	"otterserve/internal/audit"
 	"otterserve/internal/auth"
 	"otterserve/internal/config"
 	"otterserve/internal/fileserver"
 	"otterserve/internal/logger"
+	"otterserve/internal/metrics"
+	"otterserve/internal/netutil"
+	"otterserve/internal/requestid"
+	"otterserve/internal/rotate"
+	"otterserve/internal/systemd"
+	"otterserve/internal/tlsutil"
+	"otterserve/internal/tracing"
+	"otterserve/internal/upgrade"
 )
 
 // Server interface defines HTTP server operations
@@ -21,140 +37,498 @@ type Server interface {
 	Stop(ctx context.Context) error
 	RegisterRoutes(routes []config.RouteConfig) error
 	GetAddr() string
+	GetAddrs() []net.Addr
+}
+
+// ListenerProvider is implemented by servers whose sockets can be handed to
+// a new process on upgrade
+type ListenerProvider interface {
+	Listeners() []net.Listener
 }
 
 // HTTPServer implements the Server interface
 type HTTPServer struct {
 	config        *config.Config
-	server        *http.Server
-	mux           *http.ServeMux
+	listeners     []*listener
+	admin         *listener      // nil unless the admin API is enabled
+	mux           *handlerSwitch // every route, whatever the listener
 	logger        logger.Logger
 	authenticator auth.Authenticator
 	fileServer    fileserver.FileServer
-	actualAddr    string
+	clientIP      atomic.Pointer[netutil.ClientIPResolver]
+	reloadMu      sync.Mutex
+	configMu      sync.RWMutex
+	auditor       *audit.Logger
+	accessLog     *accesslog.Logger // nil unless the access log is enabled
+	acme          *acme.Manager
+	stopACME      context.CancelFunc
+	stopWatchdog  context.CancelFunc
+	inherited     []net.Listener
 	addrMu        sync.RWMutex
+	conns         connTracker
+	certStores    []*tlsutil.CertificateStore // guarded by addrMu
+	started       time.Time
+	tracer        *tracing.Tracer // nil unless tracing is enabled
 }
 
 // NewHTTPServer creates a new HTTP server instance
 func NewHTTPServer(cfg *config.Config, log logger.Logger, authenticator auth.Authenticator, fileServer fileserver.FileServer) Server {
-	mux := http.NewServeMux()
-
-	server := &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
-		Handler:      mux,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  120 * time.Second,
+	var listeners []*listener
+	for _, lc := range cfg.Server.ListenerConfigs() {
+		listeners = append(listeners, newListener(lc))
 	}
 
-	return &HTTPServer{
+	s := &HTTPServer{
 		config:        cfg,
-		server:        server,
-		mux:           mux,
+		listeners:     listeners,
+		mux:           newHandlerSwitch(),
 		logger:        log,
 		authenticator: authenticator,
 		fileServer:    fileServer,
 	}
+	if cfg.Admin.Enabled {
+		s.admin = newListener(cfg.Admin.Listener())
+	}
+	return s
 }
 
 // Start starts the HTTP server
 func (s *HTTPServer) Start() error {
+	// Open the audit log before routes are registered so they can record to it
+	if s.config.Logging.Audit.Enabled {
+		auditor, err := audit.NewLogger(s.config.Logging.Audit)
+		if err != nil {
+			return err
+		}
+		s.auditor = auditor
+	}
+
+	if s.config.Logging.Access.Enabled {
+		rc := s.config.Logging.Redact
+		redactor, err := logger.NewRedactor(rc.Fields, rc.Patterns, rc.QueryParams)
+		if err != nil {
+			return err
+		}
+		accessLog, err := accesslog.New(s.config.Logging.Access, redactor)
+		if err != nil {
+			return err
+		}
+		s.accessLog = accessLog
+	}
+
+	if s.config.Tracing.Enabled {
+		tracer, err := tracing.New(s.config.Tracing, s.logger)
+		if err != nil {
+			return fmt.Errorf("failed to set up tracing: %w", err)
+		}
+		s.tracer = tracer
+	}
+
+	s.started = time.Now()
+
 	// Register routes from configuration
 	if err := s.RegisterRoutes(s.config.Routes); err != nil {
 		return fmt.Errorf("failed to register routes: %w", err)
 	}
 
-	s.logger.Info("Starting HTTP server", logger.Fields{
-		"address":      s.server.Addr,
-		"routes":       len(s.config.Routes),
-		"auth_enabled": s.authenticator.IsEnabled(),
+	// Sockets passed by systemd are used for the listeners they match
+	inherited, err := inheritListeners()
+	if err != nil {
+		return fmt.Errorf("failed to use inherited sockets: %w", err)
+	}
+	s.inherited = inherited
+
+	all := s.allListeners()
+	for i, l := range all {
+		if err := s.startListener(l); err != nil {
+			for _, started := range all[:i] {
+				started.close()
+			}
+			s.closeInherited()
+			return err
+		}
+	}
+	s.closeInherited()
+
+	// Challenges are answered by the listeners, so certificates can only be
+	// requested once they are up
+	if s.acme != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopACME = cancel
+		go s.acme.Run(ctx)
+	}
+
+	addrs := make([]string, 0, len(s.listeners))
+	for _, addr := range s.GetAddrs() {
+		addrs = append(addrs, addr.String())
+	}
+	s.logger.Info("HTTP server started successfully", logger.Fields{
+		"address": strings.Join(addrs, ","),
 	})
 
-	// Create listener to get actual address
-	listener, err := net.Listen("tcp", s.server.Addr)
-	if err != nil {
-		return fmt.Errorf("failed to create listener: %w", err)
+	// Written before reporting ready, so the process handing over to this
+	// one finds the file no longer names it
+	if s.config.Server.PIDFile != "" {
+		if err := upgrade.WritePIDFile(s.config.Server.PIDFile); err != nil {
+			s.logger.Error("Failed to write PID file", logger.Fields{
+				"path":  s.config.Server.PIDFile,
+				"error": err.Error(),
+			})
+		}
 	}
 
-	// Store the actual address
+	// The listeners are bound, so connections are accepted from here on
+	if err := upgrade.Ready(); err != nil {
+		s.logger.Warn("Failed to notify the previous process", logger.Fields{
+			"error": err.Error(),
+		})
+	}
+	if _, err := systemd.Notify("READY=1"); err != nil {
+		s.logger.Warn("Failed to notify systemd", logger.Fields{
+			"error": err.Error(),
+		})
+	}
+	if interval, ok := systemd.WatchdogInterval(); ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopWatchdog = cancel
+		go systemd.Watchdog(ctx, interval)
+	}
+
+	return nil
+}
+
+// startRedirect starts the plain HTTP listener that redirects to the HTTPS
+// listener l bound at httpsAddr
+func (s *HTTPServer) startRedirect(l *listener, httpsAddr *net.TCPAddr) error {
+	host, _, _ := net.SplitHostPort(l.cfg.Address)
+	redirectCfg := config.ListenerConfig{
+		Network: l.cfg.NetworkName(),
+		Address: net.JoinHostPort(host, strconv.Itoa(l.cfg.TLS.RedirectPort)),
+	}
+	listener := s.takeInherited(redirectCfg)
+	if listener == nil {
+		var err error
+		if listener, err = listen(redirectCfg); err != nil {
+			return fmt.Errorf("failed to create redirect listener: %w", err)
+		}
+	}
 	s.addrMu.Lock()
-	s.actualAddr = listener.Addr().String()
+	l.sockets = append(l.sockets, listener)
 	s.addrMu.Unlock()
 
-	// Start server in a goroutine
+	handler := redirectToHTTPS(httpsAddr.Port)
+	if l.cfg.TLS.ACME.Enabled {
+		handler = s.acme.HTTPHandler(handler)
+	}
+	l.redirect = &http.Server{
+		Handler:      handler,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
 	go func() {
-		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
-			s.logger.Error("Server failed to start", logger.Fields{
+		if err := l.redirect.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Redirect listener failed", logger.Fields{
 				"error": err.Error(),
 			})
 		}
 	}()
 
-	// Give the server a moment to start
-	time.Sleep(100 * time.Millisecond)
-
-	s.logger.Info("HTTP server started successfully", logger.Fields{
-		"address": s.actualAddr,
+	s.logger.Info("Redirecting HTTP to HTTPS", logger.Fields{
+		"address": listener.Addr().String(),
 	})
-
 	return nil
 }
 
+// certificateSource returns where server certificates come from: the ACME
+// manager when acme is enabled, otherwise the configured files
+func (s *HTTPServer) certificateSource(cfg config.TLSConfig) (func(*tls.ClientHelloInfo) (*tls.Certificate, error), error) {
+	if cfg.ACME.Enabled {
+		manager, err := acme.NewManager(cfg.ACME, s.logger)
+		if err != nil {
+			return nil, err
+		}
+		s.acme = manager
+		return manager.GetCertificate, nil
+	}
+
+	store, err := newCertificateStore(cfg, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	s.addrMu.Lock()
+	s.certStores = append(s.certStores, store)
+	s.addrMu.Unlock()
+	return store.GetCertificate, nil
+}
+
 // Stop gracefully stops the HTTP server
 func (s *HTTPServer) Stop(ctx context.Context) error {
 	s.logger.Info("Stopping HTTP server")
+	systemd.Notify("STOPPING=1")
+
+	if s.stopWatchdog != nil {
+		s.stopWatchdog()
+	}
+
+	if s.stopACME != nil {
+		s.stopACME()
+	}
+
+	var shutdownErr error
+	for _, l := range s.allListeners() {
+		if l.redirect != nil {
+			l.redirect.Shutdown(ctx)
+		}
+		if err := l.server.Shutdown(ctx); err != nil {
+			s.logger.Error("Failed to gracefully shutdown server", logger.Fields{
+				"address": l.cfg.Address,
+				"error":   err.Error(),
+			})
+			if shutdownErr == nil {
+				shutdownErr = err
+			}
+		}
+	}
+
+	// Clean up even when connections did not finish in time; the error is
+	// returned afterwards
+	if pidFile := s.currentConfig().Server.PIDFile; pidFile != "" {
+		if err := upgrade.RemovePIDFile(pidFile); err != nil {
+			s.logger.Warn("Failed to remove PID file", logger.Fields{
+				"error": err.Error(),
+			})
+		}
+	}
 
-	if err := s.server.Shutdown(ctx); err != nil {
-		s.logger.Error("Failed to gracefully shutdown server", logger.Fields{
+	if err := s.auditor.Close(); err != nil {
+		s.logger.Error("Failed to close audit log", logger.Fields{
+			"error": err.Error(),
+		})
+	}
+
+	if err := s.accessLog.Close(); err != nil {
+		s.logger.Error("Failed to close access log", logger.Fields{
+			"error": err.Error(),
+		})
+	}
+
+	// Remaining spans still get a chance when draining used up ctx
+	traceCtx := ctx
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		traceCtx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+	}
+	if err := s.tracer.Shutdown(traceCtx); err != nil {
+		s.logger.Error("Failed to export remaining spans", logger.Fields{
 			"error": err.Error(),
 		})
-		return err
 	}
 
+	if shutdownErr != nil {
+		return shutdownErr
+	}
 	s.logger.Info("HTTP server stopped")
 	return nil
 }
 
-// RegisterRoutes registers file serving routes from configuration
+// RegisterRoutes registers file serving routes, replacing the current ones,
+// and makes them the routes of the current configuration
 func (s *HTTPServer) RegisterRoutes(routes []config.RouteConfig) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	s.configMu.RLock()
+	cfg, authenticator := s.config, s.authenticator
+	s.configMu.RUnlock()
+
+	set, err := s.buildRoutes(cfg, routes, authenticator)
+	if err != nil {
+		return err
+	}
+
+	updated := *cfg
+	updated.Routes = append([]config.RouteConfig(nil), routes...)
+	s.configMu.Lock()
+	s.config = &updated
+	s.configMu.Unlock()
+	s.swapRoutes(set)
+	return nil
+}
+
+// routeSet is one generation of request handlers. A reload builds a new set
+// and swaps it in whole, so a request sees either the old or the new routes.
+type routeSet struct {
+	clientIP  *netutil.ClientIPResolver
+	all       *http.ServeMux
+	listeners []config.ListenerConfig
+	muxes     []*http.ServeMux // per listener; all where every route is exposed
+}
+
+// buildRoutes creates the handlers for routes under the server settings of
+// cfg, authenticating with authenticator
+func (s *HTTPServer) buildRoutes(cfg *config.Config, routes []config.RouteConfig, authenticator auth.Authenticator) (*routeSet, error) {
 	if len(routes) == 0 {
-		return fmt.Errorf("no routes configured")
+		return nil, fmt.Errorf("no routes configured")
+	}
+
+	clientIP, err := netutil.NewClientIPResolver(cfg.Server.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	ipFilter, err := netutil.NewIPFilter(cfg.Server.Allow, cfg.Server.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("server: %w", err)
+	}
+
+	// Route restrictions may change on reload, the listeners themselves not
+	listeners := cfg.Server.ListenerConfigs()
+	if len(listeners) != len(s.listeners) {
+		listeners = nil
+		for _, l := range s.listeners {
+			listeners = append(listeners, l.cfg)
+		}
+	}
+
+	header := cfg.Server.RequestIDHeaderName()
+	set := &routeSet{clientIP: clientIP, all: http.NewServeMux(), listeners: listeners}
+	for _, lc := range listeners {
+		mux := set.all
+		if len(lc.Routes) > 0 {
+			mux = http.NewServeMux()
+		}
+		set.muxes = append(set.muxes, mux)
 	}
 
 	for _, route := range routes {
-		if err := s.registerRoute(route); err != nil {
-			return fmt.Errorf("failed to register route %s: %w", route.Path, err)
+		path, handler, err := s.routeHandler(route, authenticator, ipFilter, header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register route %s: %w", route.Path, err)
 		}
+		set.handle(path, handler, &route)
 	}
 
-	// Register 404 handler for unmatched routes
-	s.mux.HandleFunc("/", s.notFoundHandler)
+	// Register endpoints required by the authenticator (login callbacks, logout)
+	if provider, ok := authenticator.(auth.HandlerProvider); ok {
+		for path, handler := range provider.Handlers() {
+			handler = s.ipFilterMiddleware("server", ipFilter, handler)
+			set.handle(path, s.instrument(header, path, s.auditMiddleware(path, handler)), nil)
+		}
+	}
 
-	return nil
+	// Register 404 handler for unmatched routes, unless a route serves the
+	// root already
+	servesRoot := false
+	for _, route := range routes {
+		if routePath(route.Path) == "/" {
+			servesRoot = true
+		}
+	}
+	if !servesRoot {
+		set.all.Handle("/", s.instrument(header, unmatchedRoute, s.notFoundHandler(config.ListenerConfig{}, routes)))
+	}
+	for i, mux := range set.muxes {
+		if mux != set.all && !(servesRoot && listeners[i].ExposesRoute("/")) {
+			mux.Handle("/", s.instrument(header, unmatchedRoute, s.notFoundHandler(listeners[i], routes)))
+		}
+	}
+
+	// Health checks are left out of the access log unless asked for
+	liveness, readiness := s.livenessHandler(), s.readinessHandler()
+	if cfg.Health.LogRequests {
+		liveness = s.loggingMiddleware(cfg.Health.Liveness(), liveness)
+		readiness = s.loggingMiddleware(cfg.Health.Readiness(), readiness)
+	}
+	set.handle(cfg.Health.Liveness(), liveness, nil)
+	set.handle(cfg.Health.Readiness(), readiness, nil)
+
+	// Metrics are served on every listener unless they go to the admin one
+	if mc := cfg.Metrics; mc.Enabled && !mc.Admin {
+		metricsFilter, err := netutil.NewIPFilter(mc.Allow, nil)
+		if err != nil {
+			return nil, fmt.Errorf("metrics: %w", err)
+		}
+		handler := s.ipFilterMiddleware("metrics", metricsFilter, metrics.Default.Handler())
+		handler = s.ipFilterMiddleware("server", ipFilter, handler)
+		set.handle(mc.MetricsPath(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, netutil.WithClientIP(r, clientIP.Resolve(r)))
+		}), nil)
+	}
+
+	return set, nil
 }
 
-// registerRoute registers a single route with middleware chain
-func (s *HTTPServer) registerRoute(route config.RouteConfig) error {
-	// Validate route configuration
-	if route.Path == "" {
-		return fmt.Errorf("route path cannot be empty")
+// handle registers handler on the mux of every listener; for a route, only
+// listeners exposing it serve the handler
+func (rs *routeSet) handle(path string, handler http.Handler, route *config.RouteConfig) {
+	rs.all.Handle(path, handler)
+	for i, mux := range rs.muxes {
+		if mux != rs.all && (route == nil || rs.listeners[i].ExposesRoute(route.Path)) {
+			mux.Handle(path, handler)
+		}
 	}
-	if route.Directory == "" {
-		return fmt.Errorf("route directory cannot be empty")
+}
+
+// swapRoutes makes set serve all following requests
+func (s *HTTPServer) swapRoutes(set *routeSet) {
+	s.clientIP.Store(set.clientIP)
+	s.mux.current.Store(set.all)
+	for i, l := range s.listeners {
+		l.routes.current.Store(set.muxes[i])
 	}
+}
 
-	// Ensure path starts with / and ends with /
-	path := route.Path
+// handlerSwitch serves whichever mux is current; swapping it leaves requests
+// in flight on the one they started with
+type handlerSwitch struct {
+	current atomic.Pointer[http.ServeMux]
+}
+
+// newHandlerSwitch creates a switch serving an empty mux
+func newHandlerSwitch() *handlerSwitch {
+	h := &handlerSwitch{}
+	h.current.Store(http.NewServeMux())
+	return h
+}
+
+// ServeHTTP passes the request to the current mux
+func (h *handlerSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.current.Load().ServeHTTP(w, r)
+}
+
+// routePath returns a route path with leading and trailing slashes, the form
+// routes are registered under
+func routePath(path string) string {
 	if !strings.HasPrefix(path, "/") {
 		path = "/" + path
 	}
 	if !strings.HasSuffix(path, "/") {
 		path = path + "/"
 	}
+	return path
+}
+
+// routeHandler builds the middleware chain of a single route and returns it
+// with the path it is registered under
+func (s *HTTPServer) routeHandler(route config.RouteConfig, authenticator auth.Authenticator, serverFilter *netutil.IPFilter, header string) (string, http.Handler, error) {
+	// Validate route configuration
+	if route.Path == "" {
+		return "", nil, fmt.Errorf("route path cannot be empty")
+	}
+	if route.Directory == "" {
+		return "", nil, fmt.Errorf("route directory cannot be empty")
+	}
+
+	// Ensure path starts with / and ends with /
+	path := routePath(route.Path)
 
 	s.logger.Info("Registering route", logger.Fields{
 		"path":      path,
 		"directory": route.Directory,
+		"groups":    route.Groups,
+		"auth":      route.Auth,
+		"allow":     route.Allow,
+		"deny":      route.Deny,
 	})
 
 	// Create file serving handler
@@ -162,23 +536,48 @@ func (s *HTTPServer) registerRoute(route config.RouteConfig) error {
 		s.fileServer.ServeFiles(w, r, path, route.Directory)
 	})
 
-	// Apply middleware chain: logging -> authentication -> file serving
-	handler := s.loggingMiddleware(s.authenticator.Middleware(fileHandler))
+	routeFilter, err := netutil.NewIPFilter(route.Allow, route.Deny)
+	if err != nil {
+		return "", nil, fmt.Errorf("route %s: %w", path, err)
+	}
+
+	// Select the authentication methods for this route
+	if ra, ok := authenticator.(auth.RouteAuthenticator); ok {
+		selected, err := ra.ForRoute(route.Auth)
+		if err != nil {
+			return "", nil, fmt.Errorf("route %s: %w", path, err)
+		}
+		authenticator = selected
+	}
 
-	// Register the handler
-	s.mux.Handle(path, handler)
+	// Apply middleware chain: request ID -> tracing -> logging -> audit -> IP filters -> authentication -> group check -> file serving
+	handler := s.authMiddleware(authenticator, auth.RequireGroups(route.Groups, fileHandler))
+	handler = s.ipFilterMiddleware("route "+path, routeFilter, handler)
+	handler = s.ipFilterMiddleware("server", serverFilter, handler)
+	handler = s.auditMiddleware(path, handler)
+	handler = s.instrument(header, route.Path, handler)
 
-	return nil
+	return path, handler, nil
 }
 
-// loggingMiddleware logs HTTP requests
-func (s *HTTPServer) loggingMiddleware(next http.Handler) http.Handler {
+// loggingMiddleware logs HTTP requests and records their metrics under the
+// route path
+func (s *HTTPServer) loggingMiddleware(route string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+		clientIP := s.clientIP.Load().Resolve(r)
+		r = netutil.WithClientIP(r, clientIP)
 
 		// Create request-specific logger
-		requestLogger := s.logger.(*logger.DefaultLogger).RequestLogger(
-			generateRequestID(),
+		id := requestid.FromRequest(r)
+		if id == "" {
+			id = requestid.New()
+		}
+		requestLogger := logger.WithRequest(
+			s.logger,
+			id,
 			r.Method,
 			r.URL.Path,
 			r.RemoteAddr,
@@ -188,63 +587,211 @@ func (s *HTTPServer) loggingMiddleware(next http.Handler) http.Handler {
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
 		// Log request start
-		requestLogger.Info("Request started", logger.Fields{
-			"user_agent": r.UserAgent(),
-		})
+		lines := s.currentConfig().Logging.RequestLines()
+		sc := tracing.SpanFromContext(r.Context()).Context()
+		if lines == config.RequestLinesAll {
+			startFields := logger.Fields{
+				"user_agent": r.UserAgent(),
+				"client_ip":  clientIP.String(),
+			}
+			if sc.IsValid() {
+				startFields["trace_id"], startFields["span_id"] = sc.TraceID.String(), sc.SpanID.String()
+			}
+			requestLogger.Info("Request started", startFields)
+		}
 
-		// Process request
+		// Process request, tracking the identity established by authentication
+		r, identity := auth.TrackIdentity(r)
 		next.ServeHTTP(wrapped, r)
 
 		// Log request completion
 		duration := time.Since(start)
-		requestLogger.Info("Request completed", logger.Fields{
-			"status_code": wrapped.statusCode,
-			"duration_ms": duration.Milliseconds(),
-			"bytes":       wrapped.bytesWritten,
-		})
+		user := identity()
+		if lines != config.RequestLinesNone {
+			fields := logger.Fields{
+				"status_code": wrapped.statusCode,
+				"duration_ms": duration.Milliseconds(),
+				"bytes":       wrapped.bytesWritten,
+			}
+			if user != nil {
+				fields["user"] = user.Username
+				fields["auth_method"] = user.Method
+			}
+			if sc.IsValid() {
+				fields["trace_id"], fields["span_id"] = sc.TraceID.String(), sc.SpanID.String()
+			}
+			requestLogger.Info("Request completed", fields)
+		}
+		if s.accessLog != nil {
+			entry := accesslog.Entry{
+				Time:       start,
+				ClientIP:   clientIP.String(),
+				RemoteAddr: r.RemoteAddr,
+				Method:     r.Method,
+				URI:        r.RequestURI,
+				Path:       r.URL.Path,
+				Protocol:   r.Proto,
+				Host:       r.Host,
+				Status:     wrapped.statusCode,
+				Bytes:      wrapped.bytesWritten,
+				Duration:   duration,
+				Referer:    r.Referer(),
+				UserAgent:  r.UserAgent(),
+				RequestID:  id,
+				Route:      route,
+			}
+			if user != nil {
+				entry.User = user.Username
+			}
+			if sc.IsValid() {
+				entry.TraceID = sc.TraceID.String()
+			}
+			s.accessLog.Log(entry)
+		}
+		observeRequest(route, r.Method, wrapped.statusCode, duration, wrapped.bytesWritten)
 	})
 }
 
-// notFoundHandler handles requests that don't match any registered routes
-func (s *HTTPServer) notFoundHandler(w http.ResponseWriter, r *http.Request) {
-	// Check if any route prefix matches
-	for _, route := range s.config.Routes {
-		routePath := route.Path
-		if !strings.HasPrefix(routePath, "/") {
-			routePath = "/" + routePath
-		}
-		if !strings.HasSuffix(routePath, "/") {
-			routePath = routePath + "/"
-		}
+// auditMiddleware makes the audit log available to the handlers of a route
+// and records every write operation (any method other than GET, HEAD,
+// OPTIONS and TRACE) with its outcome
+func (s *HTTPServer) auditMiddleware(route string, next http.Handler) http.Handler {
+	if s.auditor == nil {
+		return next
+	}
 
-		if strings.HasPrefix(r.URL.Path, routePath) {
-			// This should have been handled by the route handler
-			// If we're here, it means the file wasn't found
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = audit.WithLogger(r, s.auditor, route)
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, r)
 			return
 		}
+
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		r, identity := auth.TrackIdentity(r)
+		next.ServeHTTP(wrapped, r)
+
+		event := audit.Event{
+			Type:    audit.EventWrite,
+			Outcome: audit.OutcomeSuccess,
+			Status:  wrapped.statusCode,
+		}
+		switch {
+		case wrapped.statusCode == http.StatusUnauthorized,
+			wrapped.statusCode == http.StatusForbidden,
+			wrapped.statusCode == http.StatusTooManyRequests:
+			event.Outcome = audit.OutcomeDenied
+		case wrapped.statusCode >= 400:
+			event.Outcome = audit.OutcomeFailure
+		}
+		if id := identity(); id != nil {
+			event.User, event.AuthMethod = id.Username, id.Method
+		}
+		audit.Record(r, event)
+	})
+}
+
+// ipFilterMiddleware rejects requests whose client address is refused by the
+// filter; scope names the configuration the filter came from
+func (s *HTTPServer) ipFilterMiddleware(scope string, filter *netutil.IPFilter, next http.Handler) http.Handler {
+	if filter.Empty() {
+		return next
 	}
 
-	// No matching route found
-	s.logger.Info("Route not found", logger.Fields{
-		"path":        r.URL.Path,
-		"method":      r.Method,
-		"remote_addr": r.RemoteAddr,
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP := netutil.ClientIP(r)
+		if allowed, rule := filter.Check(clientIP); !allowed {
+			s.logger.Warn("Request denied by IP filter", logger.Fields{
+				"request_id": requestid.FromRequest(r),
+				"client_ip":  clientIP.String(),
+				"path":       r.URL.Path,
+				"scope":      scope,
+				"rule":       rule,
+			})
+			audit.Record(r, audit.Event{
+				Type:    audit.EventAccessDenied,
+				Outcome: audit.OutcomeDenied,
+				Status:  http.StatusForbidden,
+				Reason:  scope + ": " + rule,
+			})
+			requestid.Error(w, r, "403 Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
 	})
+}
 
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusNotFound)
-	fmt.Fprintf(w, "404 Not Found\n\nThe requested path '%s' was not found on this server.\n", r.URL.Path)
+// notFoundHandler answers requests that don't match any registered route;
+// lc limits the routes considered to those the listener exposes
+func (s *HTTPServer) notFoundHandler(lc config.ListenerConfig, routes []config.RouteConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Check if any route prefix matches
+		for _, route := range routes {
+			if lc.ExposesRoute(route.Path) && strings.HasPrefix(r.URL.Path, routePath(route.Path)) {
+				// This should have been handled by the route handler
+				// If we're here, it means the file wasn't found
+				return
+			}
+		}
+
+		// No matching route found
+		s.logger.Info("Route not found", logger.Fields{
+			"request_id":  requestid.FromRequest(r),
+			"path":        r.URL.Path,
+			"method":      r.Method,
+			"remote_addr": r.RemoteAddr,
+		})
+
+		requestid.Error(w, r, fmt.Sprintf("404 Not Found\n\nThe requested path '%s' was not found on this server.", r.URL.Path), http.StatusNotFound)
+	}
 }
 
-// GetAddr returns the server address
+// GetAddr returns the address of the first listener: the bound address once
+// started, the configured one before
 func (s *HTTPServer) GetAddr() string {
 	s.addrMu.RLock()
-	addr := s.actualAddr
+	addr := s.listeners[0].addr
 	s.addrMu.RUnlock()
-	if addr != "" {
-		return addr
+	if addr != nil {
+		return addr.String()
+	}
+	return s.listeners[0].cfg.Address
+}
+
+// Listeners returns every bound socket, including redirect listeners, for
+// handing them to a new process
+func (s *HTTPServer) Listeners() []net.Listener {
+	s.addrMu.RLock()
+	defer s.addrMu.RUnlock()
+	var sockets []net.Listener
+	for _, l := range s.allListeners() {
+		sockets = append(sockets, l.sockets...)
+	}
+	return sockets
+}
+
+// allListeners returns the listeners serving routes followed by the admin
+// listener, if any
+func (s *HTTPServer) allListeners() []*listener {
+	if s.admin == nil {
+		return s.listeners
+	}
+	return append(s.listeners[:len(s.listeners):len(s.listeners)], s.admin)
+}
+
+// GetAddrs returns the bound addresses of all started listeners in
+// configuration order; for unix sockets the address is the socket path
+func (s *HTTPServer) GetAddrs() []net.Addr {
+	s.addrMu.RLock()
+	defer s.addrMu.RUnlock()
+	var addrs []net.Addr
+	for _, l := range s.listeners {
+		if l.addr != nil {
+			addrs = append(addrs, l.addr)
+		}
 	}
-	return s.server.Addr
+	return addrs
 }
 
 // responseWriter wraps http.ResponseWriter to capture response details
@@ -267,15 +814,16 @@ func (rw *responseWriter) Write(data []byte) (int, error) {
 	return n, err
 }
 
-// generateRequestID generates a simple request ID
-func generateRequestID() string {
-	return fmt.Sprintf("req-%d", time.Now().UnixNano())
-}
+// upgradeTimeout bounds how long a new process may take to become ready
+const upgradeTimeout = time.Minute
 
 // LifecycleManager manages server lifecycle including graceful shutdown
+// and handing the listeners to a new process on upgrade
 type LifecycleManager struct {
-	server Server
-	logger logger.Logger
+	server     Server
+	logger     logger.Logger
+	reloader   *Reloader
+	handedOver bool
 }
 
 // NewLifecycleManager creates a new lifecycle manager
@@ -288,6 +836,9 @@ func NewLifecycleManager(server Server, logger logger.Logger) *LifecycleManager
 
 // Run starts the server and handles graceful shutdown
 func (lm *LifecycleManager) Run(ctx context.Context) error {
+	// Write buffered log messages, the last ones included, before returning
+	defer logger.FlushAll()
+
 	// Start the server
 	if err := lm.server.Start(); err != nil {
 		return fmt.Errorf("failed to start server: %w", err)
@@ -295,10 +846,44 @@ func (lm *LifecycleManager) Run(ctx context.Context) error {
 
 	lm.logger.Info("Server lifecycle manager started")
 
-	// Wait for context cancellation (shutdown signal)
-	<-ctx.Done()
-
-	lm.logger.Info("Shutdown signal received, starting graceful shutdown")
+	upgrades := make(chan os.Signal, 1)
+	upgrade.Notify(upgrades)
+	defer signal.Stop(upgrades)
+	reloads := make(chan os.Signal, 1)
+	notifyReload(reloads)
+	defer signal.Stop(reloads)
+	reopens := make(chan os.Signal, 1)
+	notifyReopen(reopens)
+	defer signal.Stop(reopens)
+
+	// Wait for a shutdown signal, or for a new process to take over
+wait:
+	for {
+		select {
+		case <-ctx.Done():
+			lm.logger.Info("Shutdown signal received, starting graceful shutdown")
+			break wait
+		case <-upgrades:
+			if lm.upgrade() {
+				break wait
+			}
+		case <-reloads:
+			if lm.reloader == nil {
+				lm.logger.Warn("Reload requested, but no configuration file is known")
+				continue
+			}
+			lm.reloader.Reload()
+		case <-reopens:
+			// Log files were renamed, as by logrotate; start new ones
+			if err := rotate.ReopenAll(); err != nil {
+				lm.logger.Error("Failed to reopen log files", logger.Fields{
+					"error": err.Error(),
+				})
+				continue
+			}
+			lm.logger.Info("Log files reopened")
+		}
+	}
 
 	// Create shutdown context with timeout
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -316,7 +901,51 @@ func (lm *LifecycleManager) Run(ctx context.Context) error {
 	return nil
 }
 
+// upgrade starts a new process of the executable with the server's sockets
+// and reports whether it took over
+func (lm *LifecycleManager) upgrade() bool {
+	provider, ok := lm.server.(ListenerProvider)
+	if !ok {
+		lm.logger.Warn("Upgrade requested, but the server cannot hand over its listeners")
+		return false
+	}
+
+	lm.logger.Info("Upgrade requested, starting new process")
+	ctx, cancel := context.WithTimeout(context.Background(), upgradeTimeout)
+	defer cancel()
+	process, err := upgrade.Start(ctx, provider.Listeners())
+	if err != nil {
+		lm.logger.Error("Upgrade failed, continuing to serve", logger.Fields{
+			"error": err.Error(),
+		})
+		return false
+	}
+
+	// systemd follows the new process from here on
+	systemd.Notify("MAINPID=" + strconv.Itoa(process.Pid))
+	lm.handedOver = true
+	lm.logger.Info("New process is serving, draining connections", logger.Fields{
+		"pid": process.Pid,
+	})
+	return true
+}
+
+// SetReloader sets the reloader used when SIGHUP is received
+func (lm *LifecycleManager) SetReloader(r *Reloader) {
+	lm.reloader = r
+}
+
+// HandedOver reports whether Run returned because a new process took over
+func (lm *LifecycleManager) HandedOver() bool {
+	return lm.handedOver
+}
+
 // GetServerAddr returns the server address
 func (lm *LifecycleManager) GetServerAddr() string {
 	return lm.server.GetAddr()
 }
+
+// GetServerAddrs returns the addresses of all server listeners
+func (lm *LifecycleManager) GetServerAddrs() []net.Addr {
+	return lm.server.GetAddrs()
+}
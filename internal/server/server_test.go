@@ -1,7 +1,10 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -10,10 +13,13 @@ import (
 	"testing"
 	"time"
 
+	"otterserve/internal/accesslog"
+	"otterserve/internal/audit"
 	"otterserve/internal/auth"
 	"otterserve/internal/config"
 	"otterserve/internal/fileserver"
 	"otterserve/internal/logger"
+	"otterserve/internal/requestid"
 )
 
 func TestNewHTTPServer(t *testing.T) {
@@ -119,7 +125,7 @@ func TestHTTPServer_NotFoundHandler(t *testing.T) {
 	req := httptest.NewRequest("GET", "/nonexistent", nil)
 	rr := httptest.NewRecorder()
 
-	server.notFoundHandler(rr, req)
+	server.notFoundHandler(config.ListenerConfig{}, cfg.Routes)(rr, req)
 
 	if rr.Code != http.StatusNotFound {
 		t.Errorf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
@@ -154,7 +160,7 @@ func TestHTTPServer_LoggingMiddleware(t *testing.T) {
 	})
 
 	// Wrap with logging middleware
-	wrappedHandler := server.loggingMiddleware(testHandler)
+	wrappedHandler := server.loggingMiddleware("/test", testHandler)
 
 	req := httptest.NewRequest("GET", "/test", nil)
 	req.Header.Set("User-Agent", "test-agent")
@@ -180,6 +186,104 @@ func TestHTTPServer_LoggingMiddleware(t *testing.T) {
 	}
 }
 
+func TestHTTPServer_LoggingMiddleware_Identity(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Host: "localhost", Port: 1124},
+	}
+
+	var logBuffer strings.Builder
+	log := logger.NewLogger(logger.InfoLevel, &logBuffer)
+	authenticator := auth.NewBasicAuthenticator(true, "admin", "secret")
+	server := NewHTTPServer(cfg, log, authenticator, fileserver.NewFileServer()).(*HTTPServer)
+
+	handler := server.loggingMiddleware("/", authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.SetBasicAuth("admin", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	logOutput := logBuffer.String()
+	if !strings.Contains(logOutput, "user=admin") {
+		t.Errorf("Expected user in log output, got: %s", logOutput)
+	}
+	if !strings.Contains(logOutput, "auth_method=basic") {
+		t.Errorf("Expected auth method in log output, got: %s", logOutput)
+	}
+}
+
+func TestHTTPServer_LoggingMiddleware_RequestLines(t *testing.T) {
+	tests := []struct {
+		lines         string
+		wantStarted   bool
+		wantCompleted bool
+	}{
+		{"", true, true},
+		{config.RequestLinesCompleted, false, true},
+		{config.RequestLinesNone, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lines, func(t *testing.T) {
+			cfg := &config.Config{
+				Server:  config.ServerConfig{Host: "localhost", Port: 1124},
+				Logging: config.LoggingConfig{Requests: tt.lines},
+			}
+			var logBuffer strings.Builder
+			log := logger.NewLogger(logger.InfoLevel, &logBuffer)
+			server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+
+			handler := server.loggingMiddleware("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+			logOutput := logBuffer.String()
+			if got := strings.Contains(logOutput, "Request started"); got != tt.wantStarted {
+				t.Errorf("Expected 'Request started' logged %v, got: %s", tt.wantStarted, logOutput)
+			}
+			if got := strings.Contains(logOutput, "Request completed"); got != tt.wantCompleted {
+				t.Errorf("Expected 'Request completed' logged %v, got: %s", tt.wantCompleted, logOutput)
+			}
+		})
+	}
+}
+
+func TestHTTPServer_LoggingMiddleware_AccessLog(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Host: "localhost", Port: 1124},
+	}
+	authenticator := auth.NewBasicAuthenticator(true, "admin", "secret")
+	server := NewHTTPServer(cfg, logger.NewLogger(logger.InfoLevel, io.Discard), authenticator, fileserver.NewFileServer()).(*HTTPServer)
+
+	var accessBuffer bytes.Buffer
+	accessLog, err := accesslog.NewWriterLogger(&accessBuffer, config.AccessLogCombined, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.accessLog = accessLog
+
+	handler := server.requestIDMiddleware(requestid.DefaultHeader, server.loggingMiddleware("/", authenticator.Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		}))))
+
+	req := httptest.NewRequest("GET", "/docs/a.txt?v=1", nil)
+	req.SetBasicAuth("admin", "secret")
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Referer", "http://example.com/")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := accessBuffer.String()
+	for _, want := range []string{
+		"192.0.2.1 - admin [",
+		`] "GET /docs/a.txt?v=1 HTTP/1.1" 200 5 "http://example.com/" "test-agent"` + "\n",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %q in access log, got %q", want, line)
+		}
+	}
+}
+
 func TestResponseWriter_WriteHeader(t *testing.T) {
 	rr := httptest.NewRecorder()
 	rw := &responseWriter{ResponseWriter: rr, statusCode: http.StatusOK}
@@ -209,20 +313,6 @@ func TestResponseWriter_Write(t *testing.T) {
 	}
 }
 
-func TestGenerateRequestID(t *testing.T) {
-	id1 := generateRequestID()
-	time.Sleep(1 * time.Millisecond) // Ensure different timestamp
-	id2 := generateRequestID()
-
-	if id1 == id2 {
-		t.Error("Expected different request IDs")
-	}
-
-	if !strings.HasPrefix(id1, "req-") {
-		t.Errorf("Expected request ID to start with 'req-', got '%s'", id1)
-	}
-}
-
 func TestHTTPServer_Integration(t *testing.T) {
 	// Create temporary directory with test file
 	tempDir := t.TempDir()
@@ -267,6 +357,70 @@ func TestHTTPServer_Integration(t *testing.T) {
 	}
 }
 
+func TestHTTPServer_IPFilter(t *testing.T) {
+	tempDir := t.TempDir()
+	lanDir := filepath.Join(tempDir, "lan")
+	os.MkdirAll(lanDir, 0755)
+	os.WriteFile(filepath.Join(lanDir, "test.txt"), []byte("lan only"), 0644)
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Host:           "localhost",
+			Port:           1124,
+			Deny:           []string{"192.168.1.66"},
+			TrustedProxies: []string{"127.0.0.1"},
+		},
+		Routes: []config.RouteConfig{
+			{Path: "/lan", Directory: lanDir, Allow: []string{"192.168.1.0/24"}},
+		},
+	}
+
+	var logBuffer strings.Builder
+	log := logger.NewLogger(logger.InfoLevel, &logBuffer)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.RegisterRoutes(cfg.Routes); err != nil {
+		t.Fatalf("Failed to register routes: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		expected   int
+	}{
+		{"lan client", "192.168.1.10:5000", "", http.StatusOK},
+		{"vpn client", "10.8.0.5:5000", "", http.StatusForbidden},
+		{"globally denied", "192.168.1.66:5000", "", http.StatusForbidden},
+		{"lan client via trusted proxy", "127.0.0.1:5000", "192.168.1.10", http.StatusOK},
+		{"vpn client via trusted proxy", "127.0.0.1:5000", "10.8.0.5", http.StatusForbidden},
+		{"forged header via trusted proxy", "127.0.0.1:5000", "192.168.1.10, 10.8.0.5", http.StatusForbidden},
+		{"forged header from untrusted peer", "10.8.0.5:5000", "192.168.1.10", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/lan/test.txt", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			rr := httptest.NewRecorder()
+			server.mux.ServeHTTP(rr, req)
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+		})
+	}
+
+	logOutput := logBuffer.String()
+	if !strings.Contains(logOutput, "rule=deny 192.168.1.66/32") {
+		t.Errorf("Expected matched deny rule in log output, got: %s", logOutput)
+	}
+	if !strings.Contains(logOutput, "rule=not in allow list") || !strings.Contains(logOutput, "scope=route /lan/") {
+		t.Errorf("Expected route allow list denial in log output, got: %s", logOutput)
+	}
+}
+
 func TestHTTPServer_StartStop(t *testing.T) {
 	cfg := &config.Config{
 		Server: config.ServerConfig{Host: "localhost", Port: 0}, // Use port 0 for testing
@@ -423,4 +577,85 @@ func TestHTTPServer_GracefulShutdownTimeout(t *testing.T) {
 	err = server.Stop(ctx)
 	// We don't check for error here as the timeout might or might not occur
 	// depending on timing, but the important thing is that it doesn't hang
-}
\ No newline at end of file
+}
+func TestHTTPServer_AuditLog(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("secret"), 0644)
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Host: "localhost",
+			Port: 1125,
+			Deny: []string{"203.0.113.0/24"},
+		},
+		Routes: []config.RouteConfig{
+			{Path: "/files", Directory: tempDir},
+		},
+	}
+
+	var auditBuffer strings.Builder
+	log := logger.NewLogger(logger.InfoLevel, &strings.Builder{})
+	authenticator := auth.NewBasicAuthenticator(true, "admin", "secret")
+	server := NewHTTPServer(cfg, log, authenticator, fileserver.NewFileServer()).(*HTTPServer)
+	server.auditor = audit.NewWriterLogger(&auditBuffer, true, "")
+	if err := server.RegisterRoutes(cfg.Routes); err != nil {
+		t.Fatalf("Failed to register routes: %v", err)
+	}
+
+	requests := []struct {
+		method     string
+		remoteAddr string
+		password   string
+	}{
+		{"GET", "192.0.2.1:5000", "wrong"},
+		{"GET", "192.0.2.1:5000", "secret"},
+		{"GET", "203.0.113.9:5000", "secret"},
+		{"PUT", "192.0.2.1:5000", "secret"},
+	}
+	for _, tt := range requests {
+		req := httptest.NewRequest(tt.method, "/files/test.txt", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.SetBasicAuth("admin", tt.password)
+		server.mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var events []audit.Event
+	for _, line := range strings.Split(strings.TrimSpace(auditBuffer.String()), "\n") {
+		var e audit.Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Expected JSON audit entry, got %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+
+	expected := []struct {
+		event   string
+		outcome string
+		ip      string
+	}{
+		{audit.EventLogin, audit.OutcomeFailure, "192.0.2.1"},
+		{audit.EventLogin, audit.OutcomeSuccess, "192.0.2.1"},
+		{audit.EventAccessDenied, audit.OutcomeDenied, "203.0.113.9"},
+		{audit.EventLogin, audit.OutcomeSuccess, "192.0.2.1"},
+		{audit.EventWrite, audit.OutcomeSuccess, "192.0.2.1"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d audit entries, got %d: %s", len(expected), len(events), auditBuffer.String())
+	}
+	for i, want := range expected {
+		e := events[i]
+		if e.Type != want.event || e.Outcome != want.outcome || e.IP != want.ip {
+			t.Errorf("Entry %d: expected %s/%s from %s, got %+v", i, want.event, want.outcome, want.ip, e)
+		}
+		if e.Route != "/files/" || e.Path != "/files/test.txt" {
+			t.Errorf("Entry %d: expected route and path, got %+v", i, e)
+		}
+	}
+	if events[4].User != "admin" || events[4].Status != http.StatusOK {
+		t.Errorf("Expected write entry with user and status, got %+v", events[4])
+	}
+
+	if _, err := audit.Verify(strings.NewReader(auditBuffer.String())); err != nil {
+		t.Errorf("Expected intact hash chain: %v", err)
+	}
+}
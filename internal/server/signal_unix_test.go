@@ -0,0 +1,108 @@
+//go:build !windows
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/fileserver"
+	"otterserve/internal/logger"
+	"otterserve/internal/rotate"
+)
+
+func TestLifecycleManager_ReloadOnSIGHUP(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "b"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "b", "file.txt"), []byte("b"), 0644)
+	configPath := filepath.Join(tempDir, "config.yaml")
+	os.WriteFile(configPath, []byte("routes:\n  - path: /b\n    directory: "+filepath.Join(tempDir, "b")+"\n"), 0644)
+
+	cfg := &config.Config{
+		Server:  config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Routes:  []config.RouteConfig{{Path: "/a", Directory: tempDir}},
+		Logging: config.LoggingConfig{Level: "info"},
+	}
+	log := logger.NewLogger(logger.InfoLevel, nil)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	lm := NewLifecycleManager(server, log)
+	lm.SetReloader(NewReloader(configPath, config.NewConfigManager(), server, nil, log))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- lm.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(server.GetAddrs()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+
+	for time.Now().Before(deadline) {
+		if resp, err := http.Get("http://" + server.GetAddr() + "/b/file.txt"); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Error("Expected SIGHUP to apply the new configuration")
+}
+
+func TestLifecycleManager_ReopenOnSIGUSR1(t *testing.T) {
+	tempDir := t.TempDir()
+	logPath := filepath.Join(tempDir, "access.log")
+	logFile, err := rotate.Open(logPath, 0644, rotate.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logFile.Close()
+
+	cfg := &config.Config{
+		Server:  config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Routes:  []config.RouteConfig{{Path: "/a", Directory: tempDir}},
+		Logging: config.LoggingConfig{Level: "info"},
+	}
+	log := logger.NewLogger(logger.InfoLevel, nil)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	lm := NewLifecycleManager(server, log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- lm.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(server.GetAddrs()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Rename the file as logrotate would, then ask for it to be reopened
+	if err := os.Rename(logPath, logPath+".1"); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(logPath); err == nil {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Error("Expected SIGUSR1 to reopen the log file")
+}
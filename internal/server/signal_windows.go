@@ -0,0 +1,11 @@
+//go:build windows
+
+package server
+
+import "os"
+
+// notifyReload does nothing on Windows, which has no SIGHUP
+func notifyReload(c chan<- os.Signal) {}
+
+// notifyReopen does nothing on Windows, which has no SIGUSR1
+func notifyReopen(c chan<- os.Signal) {}
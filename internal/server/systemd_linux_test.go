@@ -0,0 +1,125 @@
+//go:build linux
+
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/fileserver"
+	"otterserve/internal/logger"
+)
+
+func TestHTTPServer_SystemdActivation(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("activated"), 0644)
+
+	notifySocket := filepath.Join(t.TempDir(), "notify.sock")
+	notifications, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: notifySocket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to create notify socket: %v", err)
+	}
+	defer notifications.Close()
+	t.Setenv("NOTIFY_SOCKET", notifySocket)
+	t.Setenv("WATCHDOG_USEC", "100000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	activated, _ := net.Listen("tcp", "127.0.0.1:0")
+	unmatched, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer func() { activated.Close(); unmatched.Close() }()
+	original := inheritListeners
+	inheritListeners = func() ([]net.Listener, error) {
+		return []net.Listener{unmatched, activated}, nil
+	}
+	defer func() { inheritListeners = original }()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Listeners: []config.ListenerConfig{
+				{Address: activated.Addr().String()},
+				{Address: "127.0.0.1:0"},
+			},
+		},
+		Routes: []config.RouteConfig{{Path: "/static", Directory: tempDir}},
+	}
+	log := logger.NewLogger(logger.InfoLevel, nil)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	next := func() string {
+		notifications.SetReadDeadline(time.Now().Add(2 * time.Second))
+		buf := make([]byte, 64)
+		n, err := notifications.Read(buf)
+		if err != nil {
+			t.Fatalf("Expected a notification: %v", err)
+		}
+		return string(buf[:n])
+	}
+	if state := next(); state != "READY=1" {
+		t.Errorf("Expected READY=1 first, got %q", state)
+	}
+	if state := next(); state != "WATCHDOG=1" {
+		t.Errorf("Expected watchdog keepalive, got %q", state)
+	}
+
+	if server.GetAddr() != activated.Addr().String() {
+		t.Errorf("Expected the inherited socket to be used, got %s", server.GetAddr())
+	}
+	resp, err := http.Get("http://" + activated.Addr().String() + "/static/test.txt")
+	if err != nil {
+		t.Fatalf("Request over inherited socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+	if conn, err := net.Dial("tcp", unmatched.Addr().String()); err == nil {
+		conn.Close()
+		t.Error("Expected the unmatched inherited socket to be closed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Stop(ctx)
+	for {
+		state := next()
+		if state == "STOPPING=1" {
+			break
+		}
+		if state != "WATCHDOG=1" {
+			t.Fatalf("Expected STOPPING=1, got %q", state)
+		}
+	}
+}
+
+func TestBoundTo(t *testing.T) {
+	tests := []struct {
+		cfg      config.ListenerConfig
+		addr     net.Addr
+		expected bool
+	}{
+		{config.ListenerConfig{Address: "127.0.0.1:8080"}, &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8080}, true},
+		{config.ListenerConfig{Address: "127.0.0.1:8080"}, &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8081}, false},
+		{config.ListenerConfig{Address: "0.0.0.0:443"}, &net.TCPAddr{IP: net.IPv6unspecified, Port: 443}, true},
+		{config.ListenerConfig{Address: ":443"}, &net.TCPAddr{IP: net.IPv6unspecified, Port: 443}, true},
+		{config.ListenerConfig{Address: "localhost:80"}, &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 80}, true},
+		{config.ListenerConfig{Network: "unix", Address: "/run/a.sock"}, &net.UnixAddr{Name: "/run/a.sock", Net: "unix"}, true},
+		{config.ListenerConfig{Network: "unix", Address: "/run/a.sock"}, &net.UnixAddr{Name: "/run/b.sock", Net: "unix"}, false},
+		{config.ListenerConfig{Address: "127.0.0.1:80"}, &net.UnixAddr{Name: "/run/a.sock", Net: "unix"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := boundTo(tt.cfg, tt.addr); got != tt.expected {
+			t.Errorf("%s vs %s: expected %v, got %v", tt.cfg.Address, tt.addr, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+	"otterserve/internal/tlsutil"
+)
+
+// newTLSConfig builds the server TLS configuration around a certificate
+// source; the authenticator may add client certificate settings
+func newTLSConfig(cfg config.TLSConfig, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error), authenticator auth.Authenticator) (*tls.Config, error) {
+	minVersion, err := tlsutil.ParseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := tlsutil.ParseCipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	alpn := cfg.ALPN
+	if len(alpn) == 0 {
+		alpn = tlsutil.DefaultALPN
+	}
+
+	tlsConfig := &tls.Config{
+		GetCertificate: getCertificate,
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		NextProtos:     append([]string(nil), alpn...),
+	}
+	if configurer, ok := authenticator.(auth.TLSConfigurer); ok {
+		configurer.ConfigureTLS(tlsConfig)
+	}
+	return tlsConfig, nil
+}
+
+// newCertificateStore loads the configured certificate files
+func newCertificateStore(cfg config.TLSConfig, log logger.Logger) (*tlsutil.CertificateStore, error) {
+	pairs := []tlsutil.CertificatePair{{CertFile: cfg.CertFile, KeyFile: cfg.KeyFile}}
+	for _, cert := range cfg.Certificates {
+		pairs = append(pairs, tlsutil.CertificatePair{CertFile: cert.CertFile, KeyFile: cert.KeyFile})
+	}
+	return tlsutil.NewCertificateStore(pairs, log)
+}
+
+// offersHTTP2 reports whether h2 is among the ALPN protocols
+func offersHTTP2(tlsConfig *tls.Config) bool {
+	for _, proto := range tlsConfig.NextProtos {
+		if proto == "h2" {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectToHTTPS answers plain HTTP requests with a permanent redirect to
+// the same URL on the HTTPS port
+func redirectToHTTPS(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		if host == "" {
+			http.Error(w, "400 Bad Request: missing Host header", http.StatusBadRequest)
+			return
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
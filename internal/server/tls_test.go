@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/fileserver"
+	"otterserve/internal/logger"
+)
+
+// writeServerCertificate writes a self-signed certificate for localhost and
+// returns the file names and a pool trusting it
+func writeServerCertificate(t *testing.T, dir string) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	cert, _ := x509.ParseCertificate(der)
+
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+// freePort returns a TCP port that was free a moment ago
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestHTTPServer_TLS(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("over tls"), 0644)
+	certFile, keyFile, pool := writeServerCertificate(t, tempDir)
+
+	tests := []struct {
+		name          string
+		alpn          []string
+		expectedProto string
+	}{
+		{"default alpn", nil, "HTTP/2.0"},
+		{"http/1.1 only", []string{"http/1.1"}, "HTTP/1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Server: config.ServerConfig{
+					Host: "127.0.0.1",
+					Port: 0,
+					TLS: config.TLSConfig{
+						Enabled:      true,
+						CertFile:     certFile,
+						KeyFile:      keyFile,
+						MinVersion:   "1.3",
+						ALPN:         tt.alpn,
+						RedirectPort: freePort(t),
+					},
+				},
+				Routes: []config.RouteConfig{{Path: "/static", Directory: tempDir}},
+			}
+			log := logger.NewLogger(logger.InfoLevel, nil)
+			server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+			if err := server.Start(); err != nil {
+				t.Fatalf("Failed to start server: %v", err)
+			}
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				server.Stop(ctx)
+			}()
+
+			client := &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig:   &tls.Config{RootCAs: pool},
+					ForceAttemptHTTP2: true,
+				},
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+
+			resp, err := client.Get("https://" + server.GetAddr() + "/static/test.txt")
+			if err != nil {
+				t.Fatalf("HTTPS request failed: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "over tls" {
+				t.Errorf("Expected file content, got %q", body)
+			}
+			if resp.Proto != tt.expectedProto {
+				t.Errorf("Expected protocol %s, got %s", tt.expectedProto, resp.Proto)
+			}
+			if resp.TLS.Version != tls.VersionTLS13 {
+				t.Errorf("Expected TLS 1.3, got %x", resp.TLS.Version)
+			}
+
+			redirectURL := "http://127.0.0.1:" + strconv.Itoa(cfg.Server.TLS.RedirectPort) + "/static/test.txt?x=1"
+			resp, err = client.Get(redirectURL)
+			if err != nil {
+				t.Fatalf("HTTP request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusPermanentRedirect {
+				t.Errorf("Expected redirect, got %d", resp.StatusCode)
+			}
+			if want := "https://" + server.GetAddr() + "/static/test.txt?x=1"; resp.Header.Get("Location") != want {
+				t.Errorf("Expected Location %s, got %s", want, resp.Header.Get("Location"))
+			}
+		})
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		port     int
+		host     string
+		expected string
+	}{
+		{443, "files.example.com", "https://files.example.com/docs/a.txt?v=2"},
+		{443, "files.example.com:80", "https://files.example.com/docs/a.txt?v=2"},
+		{8443, "files.example.com:8080", "https://files.example.com:8443/docs/a.txt?v=2"},
+		{443, "[2001:db8::1]:80", "https://[2001:db8::1]/docs/a.txt?v=2"},
+		{8443, "[2001:db8::1]", "https://[2001:db8::1]:8443/docs/a.txt?v=2"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/docs/a.txt?v=2", nil)
+		req.Host = tt.host
+		rr := httptest.NewRecorder()
+		redirectToHTTPS(tt.port).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: expected status %d, got %d", tt.host, http.StatusPermanentRedirect, rr.Code)
+		}
+		if got := rr.Header().Get("Location"); got != tt.expected {
+			t.Errorf("%s: expected Location %s, got %s", tt.host, tt.expected, got)
+		}
+	}
+}
+
+func TestHTTPServer_ACME(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Host: "127.0.0.1",
+			Port: 0,
+			TLS: config.TLSConfig{
+				Enabled:      true,
+				RedirectPort: freePort(t),
+				ACME: config.ACMEConfig{
+					Enabled:      true,
+					DirectoryURL: "https://127.0.0.1:1/directory",
+					Domains:      []string{"files.example.com"},
+					DataDir:      filepath.Join(tempDir, "acme"),
+					Challenges:   []string{"http-01", "tls-alpn-01"},
+				},
+			},
+		},
+		Routes: []config.RouteConfig{{Path: "/static", Directory: tempDir}},
+	}
+	log := logger.NewLogger(logger.InfoLevel, nil)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+
+	// The listeners must come up without a certificate, since they answer
+	// the challenges that obtain it
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	}()
+
+	alpn := server.listeners[0].server.TLSConfig.NextProtos
+	if alpn[len(alpn)-1] != "acme-tls/1" {
+		t.Errorf("Expected acme-tls/1 to be offered, got %v", alpn)
+	}
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	base := "http://127.0.0.1:" + strconv.Itoa(cfg.Server.TLS.RedirectPort)
+
+	resp, err := client.Get(base + "/.well-known/acme-challenge/unknown")
+	if err != nil {
+		t.Fatalf("Challenge request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown challenge token, got %d", resp.StatusCode)
+	}
+
+	resp, err = client.Get(base + "/static/")
+	if err != nil {
+		t.Fatalf("Redirect request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPermanentRedirect {
+		t.Errorf("Expected other paths to redirect, got %d", resp.StatusCode)
+	}
+}
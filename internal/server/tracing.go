@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/tracing"
+)
+
+// tracingMiddleware starts the server span of a request, continuing the
+// trace of a caller that sent a W3C traceparent header. Spans are named
+// after the route, not the request path, as with metrics.
+func (s *HTTPServer) tracingMiddleware(route string, next http.Handler) http.Handler {
+	if s.tracer == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if remote, ok := tracing.Extract(r.Header); ok {
+			ctx = tracing.ContextWithRemote(ctx, remote)
+		}
+
+		method := methodLabel(r.Method)
+		if method == "other" {
+			method = "HTTP"
+		}
+		name := method
+		attrs := []tracing.Attribute{
+			tracing.String("http.request.method", r.Method),
+			tracing.String("url.path", r.URL.Path),
+			tracing.String("client.address", s.clientIP.Load().Resolve(r).String()),
+			tracing.String("network.protocol.version", strconv.Itoa(r.ProtoMajor)+"."+strconv.Itoa(r.ProtoMinor)),
+		}
+		if route != unmatchedRoute {
+			name += " " + route
+			attrs = append(attrs, tracing.String("http.route", route))
+		}
+		if ua := r.UserAgent(); ua != "" {
+			attrs = append(attrs, tracing.String("user_agent.original", ua))
+		}
+
+		ctx, span := s.tracer.Start(ctx, name, tracing.KindServer, attrs...)
+		defer span.End()
+
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		span.SetAttributes(
+			tracing.Int("http.response.status_code", int64(wrapped.statusCode)),
+			tracing.Int("http.response.body.size", wrapped.bytesWritten),
+		)
+		if wrapped.statusCode >= 500 {
+			span.SetStatus(tracing.StatusError, http.StatusText(wrapped.statusCode))
+		}
+	})
+}
+
+// authParentKey carries the span that encloses the authentication span
+type authParentKey struct{}
+
+// authMiddleware runs authenticator in a span of its own, which ends when
+// the request is let through or turned away
+func (s *HTTPServer) authMiddleware(authenticator auth.Authenticator, next http.Handler) http.Handler {
+	if s.tracer == nil {
+		return authenticator.Middleware(next)
+	}
+
+	// Handlers after authentication are traced under the enclosing span
+	inner := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if parent, ok := r.Context().Value(authParentKey{}).(*tracing.Span); ok {
+			span := tracing.SpanFromContext(r.Context())
+			span.SetStatus(tracing.StatusOK, "")
+			span.End()
+			r = r.WithContext(tracing.ContextWithSpan(r.Context(), parent))
+		}
+		next.ServeHTTP(w, r)
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parent := tracing.SpanFromContext(r.Context())
+		ctx, span := tracing.Start(r.Context(), "authenticate")
+		if span == nil {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		inner.ServeHTTP(w, r.WithContext(context.WithValue(ctx, authParentKey{}, parent)))
+		span.SetStatus(tracing.StatusError, "request refused")
+		span.End()
+	})
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/auth"
+	"otterserve/internal/config"
+	"otterserve/internal/fileserver"
+	"otterserve/internal/logger"
+)
+
+// exportedSpan is the part of an OTLP JSON span the tests look at
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+}
+
+// readSpans reads the spans written by the file exporter, by name
+func readSpans(t *testing.T, path string) map[string]exportedSpan {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open trace file: %v", err)
+	}
+	defer file.Close()
+
+	spans := map[string]exportedSpan{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			t.Fatalf("Invalid trace line: %v", err)
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					spans[span.Name] = span
+				}
+			}
+		}
+	}
+	return spans
+}
+
+func TestHTTPServer_Tracing(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "file.txt"), []byte("traced"), 0644)
+	traceFile := filepath.Join(t.TempDir(), "traces.jsonl")
+
+	cfg := &config.Config{
+		Server:  config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Routes:  []config.RouteConfig{{Path: "/static", Directory: tempDir}},
+		Tracing: config.TracingConfig{Enabled: true, Exporter: config.TracingExporterFile, File: traceFile},
+	}
+	buf := &safeBuffer{}
+	log := logger.NewLogger(logger.InfoLevel, buf)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	const traceID, callerSpan = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	req, _ := http.NewRequest(http.MethodGet, "http://"+server.GetAddr()+"/static/file.txt", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+callerSpan+"-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	// Stopping exports the queued spans
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Stop(ctx)
+
+	spans := readSpans(t, traceFile)
+	root, ok := spans["GET /static"]
+	if !ok {
+		t.Fatalf("Expected a server span named after the route, got %v", spans)
+	}
+	if root.TraceID != traceID || root.ParentSpanID != callerSpan {
+		t.Errorf("Expected the caller's trace to be continued, got %+v", root)
+	}
+	for name, parent := range map[string]string{
+		"authenticate":     root.SpanID,
+		"fileserver.serve": root.SpanID,
+		"fileserver.read":  spans["fileserver.serve"].SpanID,
+	} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("Expected a %s span", name)
+			continue
+		}
+		if span.TraceID != traceID || span.ParentSpanID != parent {
+			t.Errorf("Expected %s under %s, got %+v", name, parent, span)
+		}
+	}
+
+	if !strings.Contains(buf.String(), "trace_id="+traceID) || !strings.Contains(buf.String(), "span_id="+root.SpanID) {
+		t.Error("Expected trace and span IDs in the request log")
+	}
+}
+
+func TestHTTPServer_TracingDisabled(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "file.txt"), []byte("untraced"), 0644)
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Host: "127.0.0.1", Port: 0},
+		Routes: []config.RouteConfig{{Path: "/static", Directory: tempDir}},
+	}
+	buf := &safeBuffer{}
+	log := logger.NewLogger(logger.InfoLevel, buf)
+	server := NewHTTPServer(cfg, log, auth.NewNoOpAuthenticator(), fileserver.NewFileServer()).(*HTTPServer)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	}()
+
+	if code := status(t, "http://"+server.GetAddr()+"/static/file.txt"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if strings.Contains(buf.String(), "trace_id") {
+		t.Error("Expected no trace IDs in the log without tracing")
+	}
+}
@@ -14,10 +14,13 @@ import (
 	"time"
 
 	"github.com/kardianos/service"
+	"otterserve/internal/admin"
 	"otterserve/internal/auth"
 	"otterserve/internal/config"
 	"otterserve/internal/fileserver"
 	"otterserve/internal/logger"
+	"otterserve/internal/metrics"
+	"otterserve/internal/rotate"
 	"otterserve/internal/server"
 )
 
@@ -45,10 +48,14 @@ type ServiceProgram struct {
 	server     *server.LifecycleManager
 	ctx        context.Context
 	cancel     context.CancelFunc
-	startupLogFile *os.File
-	serviceLogFile *os.File
+	startupLogFile *rotate.Writer
+	serviceLogFile *rotate.Writer
 }
 
+// serviceLogRotation limits the service's own log files until the
+// configuration, which may set other limits, has been loaded
+var serviceLogRotation = rotate.Options{MaxSize: 10 << 20, MaxBackups: 5, Compress: true}
+
 // NewServiceManager creates a new service manager
 func NewServiceManager(serviceName, displayName, description, configPath string, log logger.Logger) (ServiceManager, error) {
 	program := &ServiceProgram{
@@ -96,6 +103,8 @@ func NewServiceManager(serviceName, displayName, description, configPath string,
 		},
 	}
 
+	platformOptions(svcConfig.Option)
+
 	// Create service
 	svc, err := service.New(program, svcConfig)
 	if err != nil {
@@ -288,11 +297,16 @@ func (sp *ServiceProgram) Start(s service.Service) error {
 func (sp *ServiceProgram) startAsync() {
   // Create a log file in the system temp directory first
   logPath := filepath.Join(os.TempDir(), "otterserve_service_startup.log")
-  lf, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+  lf, err := rotate.Open(logPath, 0666, serviceLogRotation)
   if err == nil {
+    // Every start gets a new file; earlier ones are kept as rotated files
+    if info, statErr := os.Stat(logPath); statErr == nil && info.Size() > 0 {
+      lf.Rotate()
+    }
     sp.startupLogFile = lf
     // In service context, avoid Stdout/Stderr to prevent redirection to system directories
-    sp.logger = logger.NewLogger(logger.DebugLevel, lf)
+    // Arguments and environment variables are logged below; mask secrets in them
+    sp.logger = logger.NewRedactingLogger(logger.NewLogger(logger.DebugLevel, lf), logger.DefaultRedactor())
     sp.logger.Info("=== Service Starting - Initial Log ===")
   } else {
     // Last resort: still avoid Stdout/Stderr; use an in-memory pipe fallback to drop messages
@@ -336,14 +350,14 @@ func (sp *ServiceProgram) startAsync() {
 
   // Set up the main service log file beside the executable (not system32)
   serviceLogPath := filepath.Join(exeDir, "otterserve_service.log")
-  slf, slfErr := os.OpenFile(serviceLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+  slf, slfErr := rotate.Open(serviceLogPath, 0666, serviceLogRotation)
   if slfErr != nil {
     sp.logger.Warn("Could not create main log file, using startup logger only",
       logger.Fields{"path": serviceLogPath, "error": slfErr})
   } else {
     sp.serviceLogFile = slf
     // If we can create the log file, update the logger to use file only (no Stdout)
-    sp.logger = logger.NewLogger(logger.DebugLevel, slf)
+    sp.logger = logger.NewRedactingLogger(logger.NewLogger(logger.DebugLevel, slf), logger.DefaultRedactor())
     sp.logger.Info("Created main service log file", logger.Fields{"path": serviceLogPath})
   }
 
@@ -380,6 +394,11 @@ func (sp *ServiceProgram) startAsync() {
     return
   }
 
+  // The service log follows the configured rotation limits, if any
+  if sp.serviceLogFile != nil && cfg.Logging.Rotation != (config.RotationConfig{}) {
+    sp.serviceLogFile.SetOptions(cfg.Logging.Rotation.Options())
+  }
+
   // Normalize configured log file: ensure absolute under exeDir when empty or relative
   logFile := cfg.Logging.File
   if logFile == "" {
@@ -387,8 +406,9 @@ func (sp *ServiceProgram) startAsync() {
   } else if !filepath.IsAbs(logFile) {
     logFile = filepath.Join(exeDir, logFile)
   }
+  absolutePaths(cfg, exeDir)
   // Create logger from configuration (file-backed only in service context)
-  appLogger, logErr := logger.NewLoggerFromConfig(cfg.Logging.Level, logFile)
+  appLogger, logErr := newAppLogger(cfg.Logging, logFile)
   if logErr != nil {
     sp.logger.Error("Failed to create application logger", logger.Fields{
       "error": logErr.Error(),
@@ -397,11 +417,13 @@ func (sp *ServiceProgram) startAsync() {
   }
 
   // Create authenticator
-  authenticator := auth.NewBasicAuthenticator(
-    cfg.Auth.Enabled,
-    cfg.Auth.Username,
-    cfg.Auth.Password,
-  )
+  authenticator, authErr := auth.NewAuthenticatorFromConfig(cfg.Auth, appLogger)
+  if authErr != nil {
+    sp.logger.Error("Failed to create authenticator", logger.Fields{
+      "error": authErr.Error(),
+    })
+    return
+  }
 
   // Create file server
   fileServer := fileserver.NewFileServer()
@@ -412,6 +434,21 @@ func (sp *ServiceProgram) startAsync() {
   // Create lifecycle manager
   sp.server = server.NewLifecycleManager(httpServer, appLogger)
 
+  // Reload the configuration on SIGHUP and, if enabled, on file changes
+  reloader := server.NewReloader(sp.configPath, configManager, httpServer.(server.Reconfigurable),
+    func(c *config.Config) { absolutePaths(c, exeDir) }, appLogger)
+  sp.server.SetReloader(reloader)
+  if cfg.Server.Reload.Watch {
+    go reloader.Watch(sp.ctx, cfg.Server.Reload.Interval)
+  }
+
+  if err := setupAdmin(cfg, httpServer, configManager, reloader, appLogger); err != nil {
+    sp.logger.Error("Failed to set up admin API", logger.Fields{
+      "error": err.Error(),
+    })
+    return
+  }
+
   // Start server in goroutine
   go func() {
     if err := sp.server.Run(sp.ctx); err != nil {
@@ -419,6 +456,11 @@ func (sp *ServiceProgram) startAsync() {
         "error": err.Error(),
       })
     }
+    // After an upgrade the new process runs the service; this one is done
+    if sp.server.HandedOver() {
+      sp.logger.Info("Service handed over to new process, exiting")
+      os.Exit(0)
+    }
   }()
 
   sp.logger.Info("Service program started successfully")
@@ -445,6 +487,101 @@ func (sp *ServiceProgram) Stop(s service.Service) error {
   return nil
 }
 
+// logOptions returns the output format, rotation and buffering of the
+// application log
+func logOptions(lc config.LoggingConfig) logger.Options {
+	return logger.Options{
+		Format:        lc.Format,
+		TimeZone:      lc.TimeZone,
+		TimePrecision: lc.TimePrecision,
+		Rotation:      lc.Rotation.Options(),
+		Async: logger.AsyncOptions{
+			Enabled:    lc.Async.Enabled,
+			BufferSize: lc.Async.BufferSize,
+			Overflow:   lc.Async.Overflow,
+		},
+	}
+}
+
+// newAppLogger creates the application logger writing to file, standard
+// output when empty, and to the configured sinks, with secrets masked in
+// all of them
+func newAppLogger(lc config.LoggingConfig, file string) (logger.Logger, error) {
+	redactor, err := logger.NewRedactor(lc.Redact.Fields, lc.Redact.Patterns, lc.Redact.QueryParams)
+	if err != nil {
+		return nil, err
+	}
+	primary, err := logger.NewLoggerFromConfig(lc.Level, file, logOptions(lc))
+	if err != nil {
+		return nil, err
+	}
+	var sinks []logger.Logger
+	for i, sc := range lc.Sinks {
+		sink, err := logger.NewSinkLogger(sinkOptions(lc, sc))
+		if err != nil {
+			return nil, fmt.Errorf("log sink %d: %w", i, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return logger.NewRedactingLogger(logger.NewMultiLogger(primary, sinks...), redactor), nil
+}
+
+// sinkOptions returns the settings of a log sink. File and stdout sinks use
+// the time and buffer settings of the application log.
+func sinkOptions(lc config.LoggingConfig, sc config.LogSinkConfig) logger.SinkOptions {
+	opts := logOptions(lc)
+	opts.Format, opts.Rotation = sc.Format, sc.Rotation.Options()
+	return logger.SinkOptions{
+		Type:     sc.Type,
+		Level:    sc.LevelName(lc.Level),
+		Options:  opts,
+		File:     sc.File,
+		Network:  sc.NetworkName(),
+		Address:  sc.Address,
+		Facility: sc.FacilityName(),
+		Tag:      sc.TagName(),
+	}
+}
+
+// absolutePaths makes file paths the service writes to relative to the
+// executable's directory instead of the working directory
+func absolutePaths(cfg *config.Config, exeDir string) {
+	if cfg.Logging.Audit.File != "" && !filepath.IsAbs(cfg.Logging.Audit.File) {
+		cfg.Logging.Audit.File = filepath.Join(exeDir, cfg.Logging.Audit.File)
+	}
+	if cfg.Logging.Access.File != "" && !filepath.IsAbs(cfg.Logging.Access.File) {
+		cfg.Logging.Access.File = filepath.Join(exeDir, cfg.Logging.Access.File)
+	}
+	for i, sink := range cfg.Logging.Sinks {
+		if sink.File != "" && !filepath.IsAbs(sink.File) {
+			cfg.Logging.Sinks[i].File = filepath.Join(exeDir, sink.File)
+		}
+	}
+	if cfg.Server.PIDFile != "" && !filepath.IsAbs(cfg.Server.PIDFile) {
+		cfg.Server.PIDFile = filepath.Join(exeDir, cfg.Server.PIDFile)
+	}
+	if cfg.Tracing.File != "" && !filepath.IsAbs(cfg.Tracing.File) {
+		cfg.Tracing.File = filepath.Join(exeDir, cfg.Tracing.File)
+	}
+}
+
+// setupAdmin serves the admin API, and metrics if they go there, on the
+// admin listener of httpServer when it is enabled
+func setupAdmin(cfg *config.Config, httpServer server.Server, manager config.ConfigManager, reloader *server.Reloader, log logger.Logger) error {
+	if !cfg.Admin.Enabled {
+		return nil
+	}
+	api, err := admin.New(cfg.Admin, httpServer.(admin.Server), manager, reloader, log)
+	if err != nil {
+		return fmt.Errorf("failed to create admin API: %w", err)
+	}
+	if cfg.Metrics.Enabled && cfg.Metrics.Admin {
+		api.ServeMetrics(cfg.Metrics.MetricsPath(), metrics.Default.Handler())
+	}
+	httpServer.(server.AdminHost).SetAdminHandler(api)
+	return nil
+}
+
 // ConsoleRunner runs the service in console mode (not as a system service)
 type ConsoleRunner struct {
 	configPath string
@@ -483,7 +620,7 @@ func (cr *ConsoleRunner) Run() error {
 	}
 
 	// Create logger from configuration
-	appLogger, err := logger.NewLoggerFromConfig(cfg.Logging.Level, cfg.Logging.File)
+	appLogger, err := newAppLogger(cfg.Logging, cfg.Logging.File)
 	if err != nil {
 		cr.logger.Error("Failed to create application logger", logger.Fields{
 			"error": err.Error(),
@@ -492,11 +629,13 @@ func (cr *ConsoleRunner) Run() error {
 	}
 
 	// Create authenticator
-	authenticator := auth.NewBasicAuthenticator(
-		cfg.Auth.Enabled,
-		cfg.Auth.Username,
-		cfg.Auth.Password,
-	)
+	authenticator, err := auth.NewAuthenticatorFromConfig(cfg.Auth, appLogger)
+	if err != nil {
+		cr.logger.Error("Failed to create authenticator", logger.Fields{
+			"error": err.Error(),
+		})
+		return fmt.Errorf("failed to create authenticator: %w", err)
+	}
 
 	// Create file server
 	fileServer := fileserver.NewFileServer()
@@ -511,6 +650,20 @@ func (cr *ConsoleRunner) Run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Reload the configuration on SIGHUP and, if enabled, on file changes
+	reloader := server.NewReloader(cr.configPath, configManager, httpServer.(server.Reconfigurable), nil, appLogger)
+	lifecycleManager.SetReloader(reloader)
+	if cfg.Server.Reload.Watch {
+		go reloader.Watch(ctx, cfg.Server.Reload.Interval)
+	}
+
+	if err := setupAdmin(cfg, httpServer, configManager, reloader, appLogger); err != nil {
+		cr.logger.Error("Failed to set up admin API", logger.Fields{
+			"error": err.Error(),
+		})
+		return err
+	}
+
 	// Handle shutdown signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,37 @@
+//go:build linux
+
+package service
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestSystemdUnit(t *testing.T) {
+	// kardianos/service renders the unit with these functions
+	funcs := template.FuncMap{
+		"cmd":       func(s string) string { return s },
+		"cmdEscape": func(s string) string { return s },
+	}
+	tmpl, err := template.New("unit").Funcs(funcs).Parse(systemdUnit)
+	if err != nil {
+		t.Fatalf("Failed to parse unit template: %v", err)
+	}
+
+	var out strings.Builder
+	err = tmpl.Execute(&out, map[string]interface{}{
+		"Description": "Otter Serve", "Path": "/opt/otterserve/otterserve", "Arguments": []string{"-config", "config.yaml"},
+		"Dependencies": nil, "ChRoot": "", "WorkingDirectory": "/opt/otterserve", "UserName": "", "ReloadSignal": "",
+		"PIDFile": "", "LogOutput": false, "HasOutputFileSupport": true, "LimitNOFILE": -1, "Restart": "always",
+		"SuccessExitStatus": "", "Name": "otterserve", "EnvVars": map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to render unit: %v", err)
+	}
+	for _, line := range []string{"Type=notify", "WatchdogSec=30", "ExecStart=/opt/otterserve/otterserve -config config.yaml"} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Expected %q in unit:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package service
+
+import "github.com/kardianos/service"
+
+// platformOptions leaves the service options unchanged outside Linux
+func platformOptions(options service.KeyValue) {}
@@ -0,0 +1,30 @@
+//go:build linux
+
+package systemd
+
+import (
+	"net"
+	"os"
+)
+
+// Notify sends a state change such as "READY=1" to the service manager over
+// NOTIFY_SOCKET. It reports false without error when the process was not
+// started with a notify socket.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// A leading @ names an abstract socket, which net maps itself
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,107 @@
+//go:build linux
+
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// fakeNotifySocket listens where NOTIFY_SOCKET points and returns the
+// connection to read notifications from
+func fakeNotifySocket(t *testing.T) *net.UnixConn {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to create notify socket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", socket)
+	return conn
+}
+
+// fdOpen reports whether the process has the file descriptor open
+func fdOpen(fd int) bool {
+	_, err := os.Lstat("/proc/self/fd/" + strconv.Itoa(fd))
+	return err == nil
+}
+
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := Notify("READY=1"); sent || err != nil {
+		t.Errorf("Expected no notification without NOTIFY_SOCKET, got %v %v", sent, err)
+	}
+
+	conn := fakeNotifySocket(t)
+	if sent, err := Notify("READY=1"); !sent || err != nil {
+		t.Fatalf("Expected notification to be sent, got %v %v", sent, err)
+	}
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read notification: %v", err)
+	}
+	if string(buf[:n]) != "READY=1" {
+		t.Errorf("Expected READY=1, got %q", buf[:n])
+	}
+}
+
+func TestListeners(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+	if listeners, err := Listeners(); listeners != nil || err != nil {
+		t.Errorf("Expected no listeners without LISTEN_FDS, got %v %v", listeners, err)
+	}
+
+	// Place two listening sockets at consecutive descriptors, as systemd does
+	first, _ := net.Listen("tcp", "127.0.0.1:0")
+	second, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer first.Close()
+	defer second.Close()
+	f1, _ := first.(*net.TCPListener).File()
+	f2, _ := second.(*net.TCPListener).File()
+	defer f1.Close()
+	defer f2.Close()
+
+	start := 100
+	for fdOpen(start) || fdOpen(start+1) {
+		start += 2
+	}
+	if err := syscall.Dup2(int(f1.Fd()), start); err != nil {
+		t.Fatalf("Failed to place descriptor: %v", err)
+	}
+	if err := syscall.Dup2(int(f2.Fd()), start+1); err != nil {
+		t.Fatalf("Failed to place descriptor: %v", err)
+	}
+	listenFDsStart = start
+	defer func() { listenFDsStart = 3 }()
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "2")
+	if listeners, _ := Listeners(); listeners != nil {
+		t.Error("Expected sockets meant for another process to be ignored")
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDNAMES", "http:https")
+	listeners, err := Listeners()
+	if err != nil {
+		t.Fatalf("Listeners failed: %v", err)
+	}
+	if len(listeners) != 2 {
+		t.Fatalf("Expected 2 listeners, got %d", len(listeners))
+	}
+	for i, want := range []net.Listener{first, second} {
+		if listeners[i].Addr().String() != want.Addr().String() {
+			t.Errorf("Listener %d: expected %s, got %s", i, want.Addr(), listeners[i].Addr())
+		}
+		listeners[i].Close()
+	}
+	if os.Getenv("LISTEN_FDS") != "" || os.Getenv("LISTEN_PID") != "" {
+		t.Error("Expected LISTEN_* variables to be cleared")
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package systemd
+
+import "net"
+
+// Notify does nothing outside Linux
+func Notify(state string) (bool, error) {
+	return false, nil
+}
+
+// Listeners returns no sockets outside Linux
+func Listeners() ([]net.Listener, error) {
+	return nil, nil
+}
@@ -0,0 +1,39 @@
+package systemd
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+)
+
+// WatchdogInterval returns how often keepalives must be sent when the service
+// manager expects them (WatchdogSec= in the unit). Keepalives are due at half
+// the configured timeout.
+func WatchdogInterval() (time.Duration, bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	if pidStr := os.Getenv("WATCHDOG_PID"); pidStr != "" {
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil || pid != os.Getpid() {
+			return 0, false
+		}
+	}
+	return time.Duration(usec) * time.Microsecond / 2, true
+}
+
+// Watchdog sends WATCHDOG=1 every interval until ctx is done
+func Watchdog(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			Notify("WATCHDOG=1")
+		}
+	}
+}
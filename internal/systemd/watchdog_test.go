@@ -0,0 +1,33 @@
+package systemd
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		usec     string
+		pid      string
+		expected time.Duration
+		enabled  bool
+	}{
+		{"", "", 0, false},
+		{"30000000", "", 15 * time.Second, true},
+		{"30000000", strconv.Itoa(os.Getpid()), 15 * time.Second, true},
+		{"30000000", strconv.Itoa(os.Getpid() + 1), 0, false},
+		{"garbage", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("WATCHDOG_USEC", tt.usec)
+		t.Setenv("WATCHDOG_PID", tt.pid)
+		interval, enabled := WatchdogInterval()
+		if interval != tt.expected || enabled != tt.enabled {
+			t.Errorf("WATCHDOG_USEC=%q WATCHDOG_PID=%q: expected %v %v, got %v %v",
+				tt.usec, tt.pid, tt.expected, tt.enabled, interval, enabled)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"otterserve/internal/logger"
+)
+
+// reloadInterval limits how often certificate files are checked for changes
+const reloadInterval = time.Second
+
+// CertificatePair names a certificate chain and its private key
+type CertificatePair struct {
+	CertFile string
+	KeyFile  string
+}
+
+// CertificateStore serves certificates for TLS handshakes. The files are
+// checked for changes at most once per second and reloaded, so renewed
+// certificates are used for new connections while established ones are left
+// alone.
+type CertificateStore struct {
+	pairs  []CertificatePair
+	logger logger.Logger
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	certs     []*tls.Certificate
+	modTimes  [][2]time.Time
+	lastCheck time.Time
+}
+
+// NewCertificateStore loads the certificate pairs. The first pair is served
+// to clients whose server name matches none of the certificates.
+func NewCertificateStore(pairs []CertificatePair, log logger.Logger) (*CertificateStore, error) {
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no certificates configured")
+	}
+	if log == nil {
+		log = logger.NewLogger(logger.ErrorLevel, nil)
+	}
+
+	cs := &CertificateStore{
+		pairs:  pairs,
+		logger: log,
+		now:    time.Now,
+	}
+	if err := cs.Reload(); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// GetCertificate selects the certificate for a handshake by server name
+// (SNI); it is meant for tls.Config.GetCertificate
+func (cs *CertificateStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cs.reloadIfChanged()
+
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	for _, cert := range cs.certs {
+		if hello.ServerName != "" && hello.SupportsCertificate(cert) == nil {
+			return cert, nil
+		}
+	}
+	return cs.certs[0], nil
+}
+
+// Leaves returns the parsed certificates being served, after picking up
+// changed files, so their names and expiry can be inspected
+func (cs *CertificateStore) Leaves() []*x509.Certificate {
+	cs.reloadIfChanged()
+
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	leaves := make([]*x509.Certificate, len(cs.certs))
+	for i, cert := range cs.certs {
+		leaves[i] = cert.Leaf
+	}
+	return leaves
+}
+
+// Reload re-reads all certificate pairs. The previous certificates stay in
+// use if any pair fails to load.
+func (cs *CertificateStore) Reload() error {
+	certs := make([]*tls.Certificate, len(cs.pairs))
+	modTimes := make([][2]time.Time, len(cs.pairs))
+	for i, pair := range cs.pairs {
+		modTimes[i] = pairModTime(pair)
+		cert, err := loadPair(pair)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.certs = certs
+	cs.modTimes = modTimes
+	cs.lastCheck = cs.now()
+	return nil
+}
+
+// reloadIfChanged reloads the certificates when a file was modified
+func (cs *CertificateStore) reloadIfChanged() {
+	cs.mu.Lock()
+	now := cs.now()
+	if now.Sub(cs.lastCheck) < reloadInterval {
+		cs.mu.Unlock()
+		return
+	}
+	cs.lastCheck = now
+
+	changed := false
+	for i, pair := range cs.pairs {
+		if mod := pairModTime(pair); mod != cs.modTimes[i] {
+			// Remember the attempt so a broken pair is reported once per change
+			cs.modTimes[i] = mod
+			changed = true
+		}
+	}
+	cs.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err := cs.Reload(); err != nil {
+		cs.logger.Error("Failed to reload TLS certificates, keeping previous ones", logger.Fields{
+			"error": err.Error(),
+		})
+		return
+	}
+	cs.logger.Info("Reloaded TLS certificates", logger.Fields{
+		"certificates": len(cs.pairs),
+	})
+}
+
+// loadPair reads a certificate pair and parses its leaf for SNI matching
+func loadPair(pair CertificatePair) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(pair.CertFile, pair.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate %s: %w", pair.CertFile, err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate %s: %w", pair.CertFile, err)
+	}
+	cert.Leaf = leaf
+	return &cert, nil
+}
+
+// pairModTime returns the modification times of the certificate and key files
+func pairModTime(pair CertificatePair) [2]time.Time {
+	var mod [2]time.Time
+	for i, path := range []string{pair.CertFile, pair.KeyFile} {
+		if info, err := os.Stat(path); err == nil {
+			mod[i] = info.ModTime()
+		}
+	}
+	return mod
+}
@@ -0,0 +1,167 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate for the DNS names and
+// returns its file pair
+func writeCertificate(t *testing.T, dir, name string, serial int64, dnsNames ...string) CertificatePair {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	pair := CertificatePair{
+		CertFile: filepath.Join(dir, name+".pem"),
+		KeyFile:  filepath.Join(dir, name+".key"),
+	}
+	os.WriteFile(pair.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(pair.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return pair
+}
+
+// touch moves the modification time of the pair forward so the change is
+// noticed even on file systems with coarse timestamps
+func touch(t *testing.T, pair CertificatePair, at time.Time) {
+	for _, path := range []string{pair.CertFile, pair.KeyFile} {
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatalf("Failed to touch %s: %v", path, err)
+		}
+	}
+}
+
+func hello(serverName string) *tls.ClientHelloInfo {
+	return &tls.ClientHelloInfo{
+		ServerName:        serverName,
+		SupportedVersions: []uint16{tls.VersionTLS13, tls.VersionTLS12},
+		SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		SupportedCurves:   []tls.CurveID{tls.CurveP256},
+		CipherSuites:      []uint16{tls.TLS_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}
+}
+
+func TestCertificateStore_SNI(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewCertificateStore([]CertificatePair{
+		writeCertificate(t, dir, "files", 1, "files.example.com"),
+		writeCertificate(t, dir, "wildcard", 2, "*.example.org"),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	tests := []struct {
+		serverName string
+		serial     int64
+	}{
+		{"files.example.com", 1},
+		{"docs.example.org", 2},
+		{"unknown.example.net", 1},
+		{"", 1},
+	}
+	for _, tt := range tests {
+		cert, err := store.GetCertificate(hello(tt.serverName))
+		if err != nil {
+			t.Fatalf("GetCertificate(%q) failed: %v", tt.serverName, err)
+		}
+		if got := cert.Leaf.SerialNumber.Int64(); got != tt.serial {
+			t.Errorf("GetCertificate(%q): expected certificate %d, got %d", tt.serverName, tt.serial, got)
+		}
+	}
+}
+
+func TestCertificateStore_Leaves(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewCertificateStore([]CertificatePair{
+		writeCertificate(t, dir, "files", 1, "files.example.com"),
+		writeCertificate(t, dir, "wildcard", 2, "*.example.org"),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	leaves := store.Leaves()
+	if len(leaves) != 2 || leaves[0].DNSNames[0] != "files.example.com" || leaves[1].SerialNumber.Int64() != 2 {
+		t.Errorf("Expected the configured certificates in order, got %v", leaves)
+	}
+	if leaves[0].NotAfter.IsZero() {
+		t.Error("Expected the expiry to be available")
+	}
+}
+
+func TestCertificateStore_Reload(t *testing.T) {
+	dir := t.TempDir()
+	pair := writeCertificate(t, dir, "server", 1, "files.example.com")
+	store, err := NewCertificateStore([]CertificatePair{pair}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	serial := func() int64 {
+		cert, err := store.GetCertificate(hello("files.example.com"))
+		if err != nil {
+			t.Fatalf("GetCertificate failed: %v", err)
+		}
+		return cert.Leaf.SerialNumber.Int64()
+	}
+
+	// A renewed certificate is picked up once the check interval has passed
+	writeCertificate(t, dir, "server", 2, "files.example.com")
+	touch(t, pair, now.Add(time.Minute))
+	if got := serial(); got != 1 {
+		t.Errorf("Expected previous certificate within the check interval, got %d", got)
+	}
+	now = now.Add(2 * reloadInterval)
+	if got := serial(); got != 2 {
+		t.Errorf("Expected renewed certificate, got %d", got)
+	}
+
+	// A broken file keeps the previous certificate in use
+	os.WriteFile(pair.CertFile, []byte("not a certificate"), 0644)
+	touch(t, pair, now.Add(2*time.Minute))
+	now = now.Add(2 * reloadInterval)
+	if got := serial(); got != 2 {
+		t.Errorf("Expected previous certificate after failed reload, got %d", got)
+	}
+}
+
+func TestNewCertificateStore_Invalid(t *testing.T) {
+	if _, err := NewCertificateStore(nil, nil); err == nil {
+		t.Error("Expected error without certificates")
+	}
+	missing := CertificatePair{CertFile: filepath.Join(t.TempDir(), "missing.pem"), KeyFile: "missing.key"}
+	if _, err := NewCertificateStore([]CertificatePair{missing}, nil); err == nil {
+		t.Error("Expected error for missing files")
+	}
+}
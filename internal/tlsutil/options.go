@@ -0,0 +1,71 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// DefaultALPN is offered when no protocols are configured
+var DefaultALPN = []string{"h2", "http/1.1"}
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseVersion converts a version such as "1.2" to its crypto/tls constant;
+// an empty string selects TLS 1.2
+func ParseVersion(version string) (uint16, error) {
+	if version == "" {
+		return tls.VersionTLS12, nil
+	}
+	v, ok := versions[version]
+	if !ok {
+		return 0, fmt.Errorf("invalid tls min_version %q, must be one of: 1.0, 1.1, 1.2, 1.3", version)
+	}
+	return v, nil
+}
+
+// ParseCipherSuites converts IANA cipher suite names (as listed by
+// tls.CipherSuites) to their IDs. Suites with known weaknesses are refused.
+// The list only applies to TLS 1.2 and earlier; TLS 1.3 suites are not
+// configurable. An empty list selects the Go defaults.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	secure := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+	insecure := make(map[string]bool)
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := secure[name]
+		if !ok {
+			if insecure[name] {
+				return nil, fmt.Errorf("tls cipher suite %s is insecure", name)
+			}
+			return nil, fmt.Errorf("unknown tls cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ValidateALPN checks that only protocols served by net/http are offered
+func ValidateALPN(protocols []string) error {
+	for _, proto := range protocols {
+		if proto != "h2" && proto != "http/1.1" {
+			return fmt.Errorf("unsupported alpn protocol %q, must be h2 or http/1.1", proto)
+		}
+	}
+	return nil
+}
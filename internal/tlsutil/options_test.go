@@ -0,0 +1,62 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected uint16
+		wantErr  bool
+	}{
+		{"", tls.VersionTLS12, false},
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"1.0", tls.VersionTLS10, false},
+		{"TLS1.3", 0, true},
+		{"1.4", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersion(%q): unexpected error state: %v", tt.version, err)
+		}
+		if got != tt.expected {
+			t.Errorf("ParseVersion(%q): expected %x, got %x", tt.version, tt.expected, got)
+		}
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	ids, err := ParseCipherSuites([]string{
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+	})
+	if err != nil {
+		t.Fatalf("ParseCipherSuites failed: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+		t.Errorf("Expected suites in configured order, got %v", ids)
+	}
+
+	if ids, err := ParseCipherSuites(nil); err != nil || ids != nil {
+		t.Errorf("Expected Go defaults for an empty list, got %v, %v", ids, err)
+	}
+	if _, err := ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"}); err == nil {
+		t.Error("Expected error for insecure suite")
+	}
+	if _, err := ParseCipherSuites([]string{"TLS_FANCY"}); err == nil {
+		t.Error("Expected error for unknown suite")
+	}
+}
+
+func TestValidateALPN(t *testing.T) {
+	if err := ValidateALPN([]string{"http/1.1"}); err != nil {
+		t.Errorf("Expected http/1.1 to be accepted: %v", err)
+	}
+	if err := ValidateALPN([]string{"h2", "spdy/3"}); err == nil {
+		t.Error("Expected error for unsupported protocol")
+	}
+}
@@ -0,0 +1,312 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+	"otterserve/internal/metrics"
+)
+
+// Batching limits: spans are exported once maxBatch are queued or
+// batchInterval passed, and dropped while maxQueue wait for export
+const (
+	maxBatch      = 512
+	maxQueue      = 2048
+	batchInterval = 5 * time.Second
+)
+
+var spansDropped = metrics.Default.NewCounterVec("otterserve_tracing_spans_dropped_total",
+	"Spans not exported, by reason.", "reason")
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Resource describes the process that produced the spans
+type Resource struct {
+	ServiceName string
+	HostName    string
+}
+
+// attributes returns the resource as OTLP attributes
+func (r Resource) attributes() []Attribute {
+	attrs := []Attribute{String("service.name", r.ServiceName)}
+	if r.HostName != "" {
+		attrs = append(attrs, String("host.name", r.HostName))
+	}
+	return attrs
+}
+
+// hostName returns the host name reported with spans
+func hostName() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// scopeName is the instrumentation scope reported with every span
+const scopeName = "otterserve"
+
+// newExporter creates the exporter selected by cfg
+func newExporter(cfg config.TracingConfig) (Exporter, error) {
+	resource := Resource{ServiceName: cfg.Service(), HostName: hostName()}
+	switch cfg.ExporterName() {
+	case config.TracingExporterFile:
+		return NewFileExporter(cfg.File, resource)
+	case config.TracingExporterOTLP:
+		return NewOTLPExporter(cfg.ProtocolName(), cfg.EndpointURL(), cfg.Headers, resource)
+	}
+	return nil, fmt.Errorf("unknown tracing exporter %s", cfg.Exporter)
+}
+
+// processor queues finished spans and exports them in batches from a
+// single goroutine, so a slow backend never holds up requests
+type processor struct {
+	exporter Exporter
+	logger   logger.Logger
+	queue    chan SpanData
+	flush    chan chan struct{}
+	once     sync.Once
+}
+
+// newProcessor creates a processor and starts its goroutine
+func newProcessor(exporter Exporter, log logger.Logger) *processor {
+	p := &processor{
+		exporter: exporter,
+		logger:   log,
+		queue:    make(chan SpanData, maxQueue),
+		flush:    make(chan chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// enqueue queues a span, dropping it if the queue is full
+func (p *processor) enqueue(span SpanData) {
+	select {
+	case p.queue <- span:
+	default:
+		spansDropped.WithLabelValues("queue_full").Inc()
+	}
+}
+
+// run collects batches until shutdown
+func (p *processor) run() {
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	var batch []SpanData
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+		if err := p.exporter.Export(ctx, batch); err != nil {
+			spansDropped.WithLabelValues("export_failed").Add(float64(len(batch)))
+			p.logger.Warn("Failed to export spans", logger.Fields{
+				"spans": len(batch),
+				"error": err.Error(),
+			})
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= maxBatch {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case reply := <-p.flush:
+			for drained := false; !drained; {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+					if len(batch) >= maxBatch {
+						export()
+					}
+				default:
+					drained = true
+				}
+			}
+			export()
+			close(reply)
+			return
+		}
+	}
+}
+
+// shutdown exports what is queued, waiting until ctx is done at most
+func (p *processor) shutdown(ctx context.Context) error {
+	var err error
+	p.once.Do(func() {
+		reply := make(chan struct{})
+		select {
+		case p.flush <- reply:
+			select {
+			case <-reply:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if shutdownErr := p.exporter.Shutdown(ctx); err == nil {
+			err = shutdownErr
+		}
+	})
+	return err
+}
+
+// FileExporter appends each batch to a file as one line of OTLP JSON, the
+// format of the OpenTelemetry Collector's file exporter
+type FileExporter struct {
+	mu       sync.Mutex
+	file     *os.File
+	resource Resource
+}
+
+// NewFileExporter opens path for appending
+func NewFileExporter(path string, resource Resource) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file %s: %w", path, err)
+	}
+	return &FileExporter{file: file, resource: resource}, nil
+}
+
+// Export writes spans as one line
+func (e *FileExporter) Export(ctx context.Context, spans []SpanData) error {
+	line, err := json.Marshal(jsonRequest(e.resource, spans))
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.file.Write(append(line, '\n'))
+	return err
+}
+
+// Shutdown closes the file
+func (e *FileExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+// OTLP JSON encoding, which differs from plain JSON of the protobuf
+// messages in writing IDs as hex and 64-bit integers as strings
+
+type jsonTraceRequest struct {
+	ResourceSpans []jsonResourceSpans `json:"resourceSpans"`
+}
+
+type jsonResourceSpans struct {
+	Resource   jsonResource     `json:"resource"`
+	ScopeSpans []jsonScopeSpans `json:"scopeSpans"`
+}
+
+type jsonResource struct {
+	Attributes []jsonKeyValue `json:"attributes"`
+}
+
+type jsonScopeSpans struct {
+	Scope jsonScope  `json:"scope"`
+	Spans []jsonSpan `json:"spans"`
+}
+
+type jsonScope struct {
+	Name string `json:"name"`
+}
+
+type jsonSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	TraceState        string         `json:"traceState,omitempty"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              Kind           `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []jsonKeyValue `json:"attributes,omitempty"`
+	Status            *jsonStatus    `json:"status,omitempty"`
+}
+
+type jsonStatus struct {
+	Message string     `json:"message,omitempty"`
+	Code    StatusCode `json:"code,omitempty"`
+}
+
+type jsonKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// jsonRequest builds an ExportTraceServiceRequest in OTLP JSON
+func jsonRequest(resource Resource, spans []SpanData) jsonTraceRequest {
+	out := make([]jsonSpan, 0, len(spans))
+	for _, span := range spans {
+		js := jsonSpan{
+			TraceID:           span.Context.TraceID.String(),
+			SpanID:            span.Context.SpanID.String(),
+			TraceState:        span.Context.TraceState,
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        jsonAttributes(span.Attributes),
+		}
+		if span.Parent.IsValid() {
+			js.ParentSpanID = span.Parent.String()
+		}
+		if span.Status != StatusUnset {
+			js.Status = &jsonStatus{Message: span.StatusMessage, Code: span.Status}
+		}
+		out = append(out, js)
+	}
+	return jsonTraceRequest{ResourceSpans: []jsonResourceSpans{{
+		Resource:   jsonResource{Attributes: jsonAttributes(resource.attributes())},
+		ScopeSpans: []jsonScopeSpans{{Scope: jsonScope{Name: scopeName}, Spans: out}},
+	}}}
+}
+
+// jsonAttributes converts attributes to OTLP JSON key-values
+func jsonAttributes(attrs []Attribute) []jsonKeyValue {
+	var out []jsonKeyValue
+	for _, attr := range attrs {
+		var value map[string]interface{}
+		switch v := attr.Value.(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, jsonKeyValue{Key: attr.Key, Value: value})
+	}
+	return out
+}
@@ -0,0 +1,14 @@
+//go:build go1.24
+
+package tracing
+
+import "net/http"
+
+// cleartextHTTP2Transport returns a transport speaking HTTP/2 without TLS,
+// as gRPC collectors on plain http endpoints expect
+func cleartextHTTP2Transport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Protocols = new(http.Protocols)
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	return transport, nil
+}
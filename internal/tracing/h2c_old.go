@@ -0,0 +1,14 @@
+//go:build !go1.24
+
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// cleartextHTTP2Transport is not available before Go 1.24, whose net/http
+// first speaks HTTP/2 without TLS
+func cleartextHTTP2Transport() (*http.Transport, error) {
+	return nil, fmt.Errorf("gRPC over plain http needs a build with Go 1.24 or later, use an https endpoint or the http/protobuf protocol")
+}
@@ -0,0 +1,32 @@
+//go:build go1.24
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"otterserve/internal/config"
+)
+
+func TestOTLPExporter_GRPCCleartext(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewUnstartedServer(c)
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	defer srv.Close()
+
+	exporter, err := NewOTLPExporter(config.OTLPProtocolGRPC, srv.URL, nil, Resource{ServiceName: "files"})
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	if err := exporter.Export(context.Background(), testSpans()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if c.requests[0].ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2, got HTTP/%d", c.requests[0].ProtoMajor)
+	}
+}
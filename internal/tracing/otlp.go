@@ -0,0 +1,256 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"otterserve/internal/config"
+)
+
+// exportTimeout bounds a single export request
+const exportTimeout = 10 * time.Second
+
+// grpcExportPath is the gRPC method of the OTLP trace service
+const grpcExportPath = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+
+// OTLPExporter sends spans to an OpenTelemetry collector or any other
+// backend speaking OTLP, over HTTP with protobuf or JSON bodies or over gRPC
+type OTLPExporter struct {
+	protocol string
+	endpoint string
+	headers  map[string]string
+	resource Resource
+	client   *http.Client
+}
+
+// NewOTLPExporter creates an exporter for endpoint. For HTTP it is the full
+// URL spans are posted to; for gRPC the collector's base URL, where https
+// uses TLS and http unencrypted HTTP/2.
+func NewOTLPExporter(protocol, endpoint string, headers map[string]string, resource Resource) (*OTLPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %s", endpoint)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	switch protocol {
+	case config.OTLPProtocolHTTPProtobuf, config.OTLPProtocolHTTPJSON:
+	case config.OTLPProtocolGRPC:
+		if u.Scheme == "http" {
+			if transport, err = cleartextHTTP2Transport(); err != nil {
+				return nil, err
+			}
+		} else {
+			transport.ForceAttemptHTTP2 = true
+		}
+		endpoint = strings.TrimSuffix(endpoint, "/") + grpcExportPath
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %s", protocol)
+	}
+
+	return &OTLPExporter{
+		protocol: protocol,
+		endpoint: endpoint,
+		headers:  headers,
+		resource: resource,
+		client:   &http.Client{Transport: transport, Timeout: exportTimeout},
+	}, nil
+}
+
+// Export sends one batch of spans
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	var body []byte
+	var contentType string
+	switch e.protocol {
+	case config.OTLPProtocolHTTPJSON:
+		data, err := json.Marshal(jsonRequest(e.resource, spans))
+		if err != nil {
+			return err
+		}
+		body, contentType = data, "application/json"
+	case config.OTLPProtocolGRPC:
+		message := protoRequest(e.resource, spans)
+		body = make([]byte, 5, 5+len(message))
+		binary.BigEndian.PutUint32(body[1:], uint32(len(message)))
+		body, contentType = append(body, message...), "application/grpc"
+	default:
+		body, contentType = protoRequest(e.resource, spans), "application/x-protobuf"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.protocol == config.OTLPProtocolGRPC {
+		req.Header.Set("TE", "trailers")
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if e.protocol == config.OTLPProtocolGRPC {
+		return grpcStatus(resp)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// grpcStatus returns the error a gRPC response reports, read from its
+// trailers or, for responses without a body, its headers
+func grpcStatus(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	if resp.ProtoMajor != 2 {
+		return fmt.Errorf("collector does not speak HTTP/2")
+	}
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		if message, err := url.PathUnescape(message); err == nil && message != "" {
+			return fmt.Errorf("collector returned gRPC status %s: %s", status, message)
+		}
+		return fmt.Errorf("collector returned gRPC status %q", status)
+	}
+	return nil
+}
+
+// Shutdown closes idle connections
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// Protobuf encoding of opentelemetry.proto.collector.trace.v1
+// ExportTraceServiceRequest. The messages are small and fixed, so they are
+// written by hand rather than generated.
+
+// Wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// protoRequest encodes spans as an ExportTraceServiceRequest
+func protoRequest(resource Resource, spans []SpanData) []byte {
+	var resourceMsg []byte
+	for _, attr := range resource.attributes() {
+		resourceMsg = appendMessage(resourceMsg, 1, protoKeyValue(attr))
+	}
+
+	var scopeSpans []byte
+	scopeSpans = appendMessage(scopeSpans, 1, appendString(nil, 1, scopeName))
+	for _, span := range spans {
+		scopeSpans = appendMessage(scopeSpans, 2, protoSpan(span))
+	}
+
+	var resourceSpans []byte
+	resourceSpans = appendMessage(resourceSpans, 1, resourceMsg)
+	resourceSpans = appendMessage(resourceSpans, 2, scopeSpans)
+	return appendMessage(nil, 1, resourceSpans)
+}
+
+// protoSpan encodes a Span message
+func protoSpan(span SpanData) []byte {
+	var b []byte
+	b = appendBytes(b, 1, span.Context.TraceID[:])
+	b = appendBytes(b, 2, span.Context.SpanID[:])
+	if span.Context.TraceState != "" {
+		b = appendString(b, 3, span.Context.TraceState)
+	}
+	if span.Parent.IsValid() {
+		b = appendBytes(b, 4, span.Parent[:])
+	}
+	b = appendString(b, 5, span.Name)
+	b = appendVarint(b, 6, uint64(span.Kind))
+	b = appendFixed64(b, 7, uint64(span.Start.UnixNano()))
+	b = appendFixed64(b, 8, uint64(span.End.UnixNano()))
+	for _, attr := range span.Attributes {
+		b = appendMessage(b, 9, protoKeyValue(attr))
+	}
+	if span.Status != StatusUnset {
+		var status []byte
+		if span.StatusMessage != "" {
+			status = appendString(status, 2, span.StatusMessage)
+		}
+		status = appendVarint(status, 3, uint64(span.Status))
+		b = appendMessage(b, 15, status)
+	}
+	return b
+}
+
+// protoKeyValue encodes a KeyValue message
+func protoKeyValue(attr Attribute) []byte {
+	var value []byte
+	switch v := attr.Value.(type) {
+	case string:
+		value = appendString(value, 1, v)
+	case bool:
+		n := uint64(0)
+		if v {
+			n = 1
+		}
+		value = appendVarint(value, 2, n)
+	case int64:
+		value = appendVarint(value, 3, uint64(v))
+	case float64:
+		value = appendFixed64(value, 4, math.Float64bits(v))
+	default:
+		value = appendString(value, 1, fmt.Sprint(v))
+	}
+	b := appendString(nil, 1, attr.Key)
+	return appendMessage(b, 2, value)
+}
+
+// appendTag appends a field number and wire type
+func appendTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// appendVarint appends a varint field
+func appendVarint(b []byte, field int, v uint64) []byte {
+	return binary.AppendUvarint(appendTag(b, field, wireVarint), v)
+}
+
+// appendFixed64 appends a fixed64 or double field
+func appendFixed64(b []byte, field int, v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(appendTag(b, field, wireFixed64), v)
+}
+
+// appendBytes appends a length-delimited field
+func appendBytes(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, field, wireBytes), uint64(len(data)))
+	return append(b, data...)
+}
+
+// appendString appends a string field
+func appendString(b []byte, field int, s string) []byte {
+	return appendBytes(b, field, []byte(s))
+}
+
+// appendMessage appends an embedded message field
+func appendMessage(b []byte, field int, message []byte) []byte {
+	return appendBytes(b, field, message)
+}
@@ -0,0 +1,249 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"otterserve/internal/config"
+)
+
+// protoFields splits a protobuf message into its fields, keyed by number
+func protoFields(t *testing.T, b []byte) map[int][][]byte {
+	fields := map[int][][]byte{}
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatal("Malformed tag")
+		}
+		b = b[n:]
+		field := int(tag >> 3)
+		switch tag & 7 {
+		case wireVarint:
+			_, n := binary.Uvarint(b)
+			fields[field] = append(fields[field], b[:n])
+			b = b[n:]
+		case wireFixed64:
+			fields[field] = append(fields[field], b[:8])
+			b = b[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			fields[field] = append(fields[field], b[n:n+int(size)])
+			b = b[n+int(size):]
+		default:
+			t.Fatalf("Unexpected wire type %d", tag&7)
+		}
+	}
+	return fields
+}
+
+// protoSpanNames returns the span names in an ExportTraceServiceRequest and
+// the service.name of its resource
+func protoSpanNames(t *testing.T, body []byte) ([]string, string) {
+	resourceSpans := protoFields(t, protoFields(t, body)[1][0])
+	service := ""
+	for _, kv := range protoFields(t, resourceSpans[1][0])[1] {
+		f := protoFields(t, kv)
+		if string(f[1][0]) == "service.name" {
+			service = string(protoFields(t, f[2][0])[1][0])
+		}
+	}
+	var names []string
+	for _, span := range protoFields(t, resourceSpans[2][0])[2] {
+		names = append(names, string(protoFields(t, span)[5][0]))
+	}
+	return names, service
+}
+
+// collector is a stand-in for an OpenTelemetry collector
+type collector struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	c.requests = append(c.requests, r)
+	c.bodies = append(c.bodies, body)
+	c.mu.Unlock()
+	if r.Header.Get("Content-Type") == "application/grpc" {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", "0")
+		return
+	}
+	w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+	w.Write([]byte("{}"))
+}
+
+func testSpans() []SpanData {
+	now := time.Now()
+	parent := SpanData{
+		Name:       "GET /static",
+		Kind:       KindServer,
+		Context:    SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true},
+		Start:      now,
+		End:        now.Add(time.Millisecond),
+		Attributes: []Attribute{String("http.route", "/static"), Int("http.response.status_code", 200), Bool("ok", true)},
+		Status:     StatusError,
+	}
+	child := parent
+	child.Name, child.Kind, child.Parent = "fileserver.read", KindInternal, parent.Context.SpanID
+	child.Context.SpanID = newSpanID()
+	return []SpanData{child, parent}
+}
+
+func TestOTLPExporter_HTTP(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	cfg := config.TracingConfig{Endpoint: srv.URL, Headers: map[string]string{"X-Api-Key": "secret"}}
+	exporter, err := NewOTLPExporter(cfg.ProtocolName(), cfg.EndpointURL(), cfg.Headers, Resource{ServiceName: "files"})
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	if err := exporter.Export(context.Background(), testSpans()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	req := c.requests[0]
+	if req.URL.Path != "/v1/traces" || req.Header.Get("Content-Type") != "application/x-protobuf" {
+		t.Errorf("Unexpected request %s %s", req.URL.Path, req.Header.Get("Content-Type"))
+	}
+	if req.Header.Get("X-Api-Key") != "secret" {
+		t.Error("Expected configured headers to be sent")
+	}
+	names, service := protoSpanNames(t, c.bodies[0])
+	if strings.Join(names, ",") != "fileserver.read,GET /static" || service != "files" {
+		t.Errorf("Unexpected spans %v from %q", names, service)
+	}
+}
+
+func TestOTLPExporter_HTTPJSON(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	exporter, err := NewOTLPExporter(config.OTLPProtocolHTTPJSON, srv.URL+"/otlp/v1/traces", nil, Resource{ServiceName: "files"})
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	spans := testSpans()
+	if err := exporter.Export(context.Background(), spans); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	var req jsonTraceRequest
+	if err := json.Unmarshal(c.bodies[0], &req); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	got := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(got) != 2 || got[0].TraceID != spans[0].Context.TraceID.String() || got[0].ParentSpanID != spans[1].Context.SpanID.String() {
+		t.Errorf("Unexpected spans %+v", got)
+	}
+	if got[1].Attributes[1].Value["intValue"] != "200" {
+		t.Errorf("Expected integers as strings, got %v", got[1].Attributes[1].Value)
+	}
+	if c.requests[0].URL.Path != "/otlp/v1/traces" {
+		t.Errorf("Expected an endpoint with a path to be used as is, got %s", c.requests[0].URL.Path)
+	}
+}
+
+func TestOTLPExporter_HTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	exporter, _ := NewOTLPExporter(config.OTLPProtocolHTTPProtobuf, srv.URL, nil, Resource{})
+	err := exporter.Export(context.Background(), testSpans())
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Expected the collector's error, got %v", err)
+	}
+}
+
+func TestOTLPExporter_GRPC(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewUnstartedServer(c)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	exporter, err := NewOTLPExporter(config.OTLPProtocolGRPC, srv.URL, nil, Resource{ServiceName: "files"})
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	exporter.client.Transport.(*http.Transport).TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+	if err := exporter.Export(context.Background(), testSpans()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	req, body := c.requests[0], c.bodies[0]
+	if req.URL.Path != grpcExportPath || req.ProtoMajor != 2 {
+		t.Errorf("Unexpected request %s over HTTP/%d", req.URL.Path, req.ProtoMajor)
+	}
+	if body[0] != 0 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		t.Fatal("Expected a length-prefixed gRPC message")
+	}
+	if names, _ := protoSpanNames(t, body[5:]); len(names) != 2 {
+		t.Errorf("Unexpected spans %v", names)
+	}
+}
+
+func TestOTLPExporter_GRPCStatus(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", "16")
+		w.Header().Set("Grpc-Message", "missing%20credentials")
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	exporter, _ := NewOTLPExporter(config.OTLPProtocolGRPC, srv.URL, nil, Resource{})
+	exporter.client.Transport.(*http.Transport).TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+	err := exporter.Export(context.Background(), testSpans())
+	if err == nil || !strings.Contains(err.Error(), "missing credentials") {
+		t.Errorf("Expected the gRPC status message, got %v", err)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	exporter, err := NewFileExporter(path, Resource{ServiceName: "files"})
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	exporter.Export(context.Background(), testSpans())
+	exporter.Export(context.Background(), testSpans()[:1])
+	exporter.Shutdown(context.Background())
+
+	file, _ := os.Open(path)
+	defer file.Close()
+	var counts []int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var req jsonTraceRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			t.Fatalf("Invalid line: %v", err)
+		}
+		counts = append(counts, len(req.ResourceSpans[0].ScopeSpans[0].Spans))
+	}
+	if len(counts) != 2 || counts[0] != 2 || counts[1] != 1 {
+		t.Errorf("Expected one line per batch, got %v", counts)
+	}
+}
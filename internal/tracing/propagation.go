@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// W3C Trace Context headers
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// flagSampled is the sampled bit of the traceparent trace flags
+const flagSampled = 0x01
+
+// Extract returns the span context a caller sent in the W3C traceparent and
+// tracestate headers. ok is false if there is none or it is malformed, in
+// which case the request starts a new trace.
+func Extract(h http.Header) (sc SpanContext, ok bool) {
+	values := h.Values(TraceparentHeader)
+	if len(values) != 1 {
+		return SpanContext{}, false
+	}
+	sc, ok = ParseTraceparent(values[0])
+	if !ok {
+		return SpanContext{}, false
+	}
+	sc.TraceState = strings.Join(h.Values(TracestateHeader), ",")
+	return sc, true
+}
+
+// Inject sets the traceparent and tracestate headers for sc
+func Inject(sc SpanContext, h http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, FormatTraceparent(sc))
+	if sc.TraceState != "" {
+		h.Set(TracestateHeader, sc.TraceState)
+	}
+}
+
+// ParseTraceparent parses a traceparent header value such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01. Versions after
+// 00 are accepted as long as they start with the version 00 fields.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return SpanContext{}, false
+	}
+	version, ok := decodeHex(value[0:2])
+	if !ok || version[0] == 0xff || (version[0] == 0 && len(value) != 55) {
+		return SpanContext{}, false
+	}
+	if len(value) > 55 && value[55] != '-' {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	traceID, ok1 := decodeHex(value[3:35])
+	spanID, ok2 := decodeHex(value[36:52])
+	flags, ok3 := decodeHex(value[53:55])
+	if !ok1 || !ok2 || !ok3 {
+		return SpanContext{}, false
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&flagSampled != 0
+	sc.Remote = true
+	return sc, sc.IsValid()
+}
+
+// FormatTraceparent returns the traceparent header value for sc
+func FormatTraceparent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// decodeHex decodes lowercase hex, which is all traceparent allows
+func decodeHex(s string) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
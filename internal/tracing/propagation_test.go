@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"future version with extra fields", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"version 00 with extra fields", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"zero span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"short", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, false},
+		{"not hex", "00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(tt.value)
+			if ok != tt.valid {
+				t.Fatalf("Expected valid=%v, got %v", tt.valid, ok)
+			}
+			if ok && sc.Sampled != tt.sampled {
+				t.Errorf("Expected sampled=%v", tt.sampled)
+			}
+		})
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	in := http.Header{}
+	in.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	in.Add("Tracestate", "a=1")
+	in.Add("Tracestate", "b=2")
+
+	sc, ok := Extract(in)
+	if !ok || !sc.Remote {
+		t.Fatal("Expected a remote span context")
+	}
+	if sc.TraceState != "a=1,b=2" {
+		t.Errorf("Expected tracestate values joined, got %q", sc.TraceState)
+	}
+
+	out := http.Header{}
+	Inject(sc, out)
+	if out.Get("Traceparent") != in.Get("Traceparent") || out.Get("Tracestate") != "a=1,b=2" {
+		t.Errorf("Expected headers to round trip, got %v", out)
+	}
+
+	// Several traceparent headers are ambiguous and ignored
+	in.Add("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b8-01")
+	if _, ok := Extract(in); ok {
+		t.Error("Expected duplicate traceparent headers to be ignored")
+	}
+}
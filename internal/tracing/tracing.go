@@ -0,0 +1,294 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"otterserve/internal/config"
+	"otterserve/internal/logger"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the trace ID as lowercase hex
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether the ID is not all zeros
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the span ID as lowercase hex
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether the ID is not all zeros
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext is the part of a span that is propagated to other services
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+	Remote     bool
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Kind is the role of a span in a trace
+type Kind int
+
+// Span kinds, numbered as in OTLP
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+)
+
+// StatusCode is the outcome of a span, numbered as in OTLP
+type StatusCode int
+
+// Span status codes
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attribute is a key and a string, bool, int64 or float64 value
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute { return Attribute{key, value} }
+
+// Int returns an integer attribute
+func Int(key string, value int64) Attribute { return Attribute{key, value} }
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attribute { return Attribute{key, value} }
+
+// SpanData is a finished span as handed to exporters
+type SpanData struct {
+	Name          string
+	Kind          Kind
+	Context       SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Span is an operation being traced. All methods are safe on a nil span,
+// which is what Start returns when tracing is disabled.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// Context returns the span's identity
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil || !s.data.Context.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Attributes = append(s.data.Attributes, attrs...)
+	}
+}
+
+// SetStatus sets the outcome of the span
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Status, s.data.StatusMessage = code, message
+	}
+}
+
+// SetError marks the span as failed with err
+func (s *Span) SetError(err error) {
+	if err != nil {
+		s.SetStatus(StatusError, err.Error())
+	}
+}
+
+// End finishes the span and queues it for export if it is sampled. Later
+// calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.Context.Sampled {
+		s.tracer.processor.enqueue(data)
+	}
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithSpan returns a context carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemote returns a context carrying the span context of a caller,
+// which becomes the parent of the next span started from it
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Start starts a child of the span carried by ctx. Without one, such as
+// when tracing is disabled, it returns ctx and a nil span.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, KindInternal, attrs...)
+}
+
+// Tracer creates spans and exports the sampled ones in batches. A nil
+// Tracer creates no spans.
+type Tracer struct {
+	processor *processor
+	ratio     float64
+}
+
+// New creates a tracer exporting as configured
+func New(cfg config.TracingConfig, log logger.Logger) (*Tracer, error) {
+	if log == nil {
+		log = logger.NewLogger(logger.ErrorLevel, nil)
+	}
+	exporter, err := newExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newTracer(exporter, cfg.Ratio(), log), nil
+}
+
+// newTracer creates a tracer sampling ratio of new traces
+func newTracer(exporter Exporter, ratio float64, log logger.Logger) *Tracer {
+	return &Tracer{
+		processor: newProcessor(exporter, log),
+		ratio:     ratio,
+	}
+}
+
+// Start starts a span. Its parent is the span carried by ctx or, failing
+// that, a remote caller's span context; without either it starts a new
+// trace. New traces are sampled at the configured ratio, others follow
+// their parent's decision.
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind, attrs ...Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	var parent SpanContext
+	if span := SpanFromContext(ctx); span != nil {
+		parent = span.Context()
+	} else if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		parent = remote
+	}
+
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID, sc.Sampled, sc.TraceState = parent.TraceID, parent.Sampled, parent.TraceState
+	} else {
+		sc.TraceID = newTraceID()
+		sc.Sampled = t.sample(sc.TraceID)
+	}
+
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:    name,
+			Kind:    kind,
+			Context: sc,
+			Parent:  parent.SpanID,
+			Start:   time.Now(),
+		},
+	}
+	if sc.Sampled {
+		span.data.Attributes = attrs
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// sample decides from the trace ID whether a new trace is recorded, so that
+// every service using the same ratio makes the same decision
+func (t *Tracer) sample(id TraceID) bool {
+	if t.ratio >= 1 {
+		return true
+	}
+	bound := uint64(t.ratio * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:])>>1 < bound
+}
+
+// Shutdown exports the spans still queued and releases the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.processor.shutdown(ctx)
+}
+
+// newTraceID returns a random trace ID
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// newSpanID returns a random span ID
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
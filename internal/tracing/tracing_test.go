@@ -0,0 +1,152 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"otterserve/internal/logger"
+)
+
+// recordingExporter keeps exported spans for inspection
+type recordingExporter struct {
+	mu       sync.Mutex
+	spans    []SpanData
+	shutdown bool
+}
+
+func (e *recordingExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+func (e *recordingExporter) exported() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+func TestTracer_ParentAndChild(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := newTracer(exporter, 1, logger.NewLogger(logger.ErrorLevel, nil))
+
+	ctx, server := tracer.Start(context.Background(), "GET /static", KindServer, String("http.route", "/static"))
+	_, child := Start(ctx, "fileserver.stat", Int("size", 42))
+	child.SetStatus(StatusError, "missing")
+	child.End()
+	server.End()
+	server.End() // ended spans are exported once
+
+	ctx2, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx2); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	spans := exporter.exported()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	c, s := spans[0], spans[1]
+	if c.Name != "fileserver.stat" || s.Name != "GET /static" {
+		t.Fatalf("Unexpected span order: %s, %s", c.Name, s.Name)
+	}
+	if c.Context.TraceID != s.Context.TraceID {
+		t.Error("Expected the child to share the trace ID")
+	}
+	if c.Parent != s.Context.SpanID || s.Parent.IsValid() {
+		t.Error("Expected the server span to be the child's parent and have none itself")
+	}
+	if c.Kind != KindInternal || s.Kind != KindServer {
+		t.Errorf("Unexpected kinds %d and %d", c.Kind, s.Kind)
+	}
+	if c.Status != StatusError || c.StatusMessage != "missing" {
+		t.Errorf("Expected error status, got %d %q", c.Status, c.StatusMessage)
+	}
+	if len(s.Attributes) != 1 || s.Attributes[0].Value != "/static" {
+		t.Errorf("Unexpected attributes %v", s.Attributes)
+	}
+	if s.End.Before(s.Start) {
+		t.Error("Expected end after start")
+	}
+	if !exporter.shutdown {
+		t.Error("Expected the exporter to be shut down")
+	}
+}
+
+func TestTracer_RemoteParent(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := newTracer(exporter, 1, logger.NewLogger(logger.ErrorLevel, nil))
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	remote.TraceState = "vendor=1"
+	ctx := ContextWithRemote(context.Background(), remote)
+	_, span := tracer.Start(ctx, "request", KindServer)
+	span.End()
+	tracer.Shutdown(context.Background())
+
+	sc := span.Context()
+	if sc.TraceID != remote.TraceID || sc.TraceState != "vendor=1" {
+		t.Error("Expected the span to continue the remote trace")
+	}
+	if sc.Sampled {
+		t.Error("Expected the caller's decision not to sample to be followed")
+	}
+	if len(exporter.exported()) != 0 {
+		t.Error("Expected unsampled spans not to be exported")
+	}
+}
+
+func TestTracer_SampleRatio(t *testing.T) {
+	tests := []struct {
+		ratio    float64
+		min, max int
+	}{
+		{1, 1000, 1000},
+		{0.5, 400, 600},
+		{0.0001, 0, 10},
+	}
+	for _, tt := range tests {
+		tracer := newTracer(&recordingExporter{}, tt.ratio, nil)
+		sampled := 0
+		for i := 0; i < 1000; i++ {
+			if tracer.sample(newTraceID()) {
+				sampled++
+			}
+		}
+		if sampled < tt.min || sampled > tt.max {
+			t.Errorf("Ratio %v sampled %d of 1000", tt.ratio, sampled)
+		}
+		tracer.Shutdown(context.Background())
+	}
+}
+
+func TestNilTracerAndSpan(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "request", KindServer)
+	if span != nil {
+		t.Fatal("Expected a nil tracer to return a nil span")
+	}
+	span.SetAttributes(String("key", "value"))
+	span.SetError(context.Canceled)
+	span.End()
+	if span.Context().IsValid() {
+		t.Error("Expected an invalid span context")
+	}
+	if _, child := Start(ctx, "child"); child != nil {
+		t.Error("Expected no child without a parent span")
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Errorf("Expected nil tracer shutdown to succeed, got %v", err)
+	}
+}
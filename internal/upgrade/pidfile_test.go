@@ -0,0 +1,41 @@
+package upgrade
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otterserve.pid")
+
+	if err := WritePIDFile(path); err != nil {
+		t.Fatalf("WritePIDFile failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected own PID in file, got %q", data)
+	}
+
+	// A new process took over and rewrote the file
+	os.WriteFile(path, []byte("999999\n"), 0644)
+	if err := RemovePIDFile(path); err != nil {
+		t.Fatalf("RemovePIDFile failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("Expected the file of another process to be kept")
+	}
+
+	WritePIDFile(path)
+	if err := RemovePIDFile(path); err != nil {
+		t.Fatalf("RemovePIDFile failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected own PID file to be removed")
+	}
+	if err := RemovePIDFile(path); err != nil {
+		t.Errorf("Expected a missing PID file to be ignored, got %v", err)
+	}
+}
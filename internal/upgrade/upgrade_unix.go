@@ -0,0 +1,170 @@
+//go:build !windows
+
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	fdsEnv   = "OTTERSERVE_UPGRADE_FDS"
+	readyEnv = "OTTERSERVE_UPGRADE_READY"
+)
+
+// firstFD is where exec.Cmd places the first of ExtraFiles
+const firstFD = 3
+
+// Notify relays upgrade requests (SIGUSR2) to c
+func Notify(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR2)
+}
+
+// Listeners returns the listening sockets handed over by the previous
+// process, in the order it passed them
+func Listeners() ([]net.Listener, error) {
+	count, err := strconv.Atoi(os.Getenv(fdsEnv))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+	os.Unsetenv(fdsEnv)
+
+	listeners := make([]net.Listener, 0, count)
+	for i := 0; i < count; i++ {
+		f := os.NewFile(uintptr(firstFD+i), "upgrade-listener")
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("inherited socket %d: %w", i, err)
+		}
+		// This process owns the socket file now and removes it on shutdown
+		if unixListener, ok := l.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(true)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// Ready tells the previous process that this one is serving, so it can
+// drain its connections and exit. It does nothing when the process was not
+// started by Start.
+func Ready() error {
+	fd, err := strconv.Atoi(os.Getenv(readyEnv))
+	if err != nil {
+		return nil
+	}
+	os.Unsetenv(readyEnv)
+
+	f := os.NewFile(uintptr(fd), "upgrade-ready")
+	defer f.Close()
+	_, err = f.Write([]byte{1})
+	return err
+}
+
+// Start runs the current executable again with the same arguments, hands it
+// the listeners and waits until it reports ready. If it fails or ctx ends
+// first, the new process is killed and the caller keeps serving.
+func Start(ctx context.Context, listeners []net.Listener) (*os.Process, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*os.File
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+		files = nil
+	}
+	defer closeFiles()
+	for _, l := range listeners {
+		filer, ok := l.(interface{ File() (*os.File, error) })
+		if !ok {
+			return nil, fmt.Errorf("listener %s cannot be handed over", l.Addr())
+		}
+		f, err := filer.File()
+		if err != nil {
+			return nil, fmt.Errorf("listener %s: %w", l.Addr(), err)
+		}
+		files = append(files, f)
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer readyR.Close()
+	files = append(files, readyW)
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(environ(),
+		fdsEnv+"="+strconv.Itoa(len(listeners)),
+		readyEnv+"="+strconv.Itoa(firstFD+len(listeners)),
+	)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start new process: %w", err)
+	}
+	// Only the child holds the write end now, so its exit ends the read
+	closeFiles()
+
+	ready := make(chan error, 1)
+	go func() {
+		_, err := readyR.Read(make([]byte, 1))
+		ready <- err
+	}()
+	select {
+	case err := <-ready:
+		if err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return nil, fmt.Errorf("new process exited before it was ready")
+		}
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("new process not ready: %w", ctx.Err())
+	}
+
+	// The sockets live on in the new process; closing them here must not
+	// remove their files
+	for _, l := range listeners {
+		if unixListener, ok := l.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(false)
+		}
+	}
+
+	// Reap the new process should it exit while this one drains
+	go cmd.Wait()
+	return cmd.Process, nil
+}
+
+// environ returns the environment without handoff variables of our own.
+// WATCHDOG_PID names this process, so it is dropped too: the new process
+// takes over as main process and must send the watchdog keepalives, which
+// it only does when WATCHDOG_PID is unset or its own.
+func environ() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, fdsEnv+"=") || strings.HasPrefix(kv, readyEnv+"=") ||
+			strings.HasPrefix(kv, "WATCHDOG_PID=") {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
@@ -0,0 +1,162 @@
+//go:build !windows
+
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"otterserve/internal/systemd"
+)
+
+// TestMain runs the process started by Start: the test binary is
+// re-executed with the handoff variables set
+func TestMain(m *testing.M) {
+	if os.Getenv(fdsEnv) != "" {
+		os.Exit(runChild())
+	}
+	os.Exit(m.Run())
+}
+
+// runChild serves "new" on the inherited listener until asked to exit
+func runChild() int {
+	if os.Getenv("UPGRADE_TEST_FAIL") == "1" {
+		return 1
+	}
+	listeners, err := Listeners()
+	if err != nil || len(listeners) != 1 {
+		fmt.Fprintf(os.Stderr, "unexpected listeners %v: %v\n", listeners, err)
+		return 1
+	}
+
+	done := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "new")
+	})
+	mux.HandleFunc("/exit", func(w http.ResponseWriter, r *http.Request) {
+		close(done)
+	})
+	go http.Serve(listeners[0], mux)
+
+	if err := Ready(); err != nil {
+		return 1
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+	}
+	return 0
+}
+
+func get(t *testing.T, url string) string {
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestStart(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "old")
+	})}
+	go server.Serve(l)
+	url := "http://" + l.Addr().String()
+
+	if body := get(t, url); body != "old" {
+		t.Fatalf("Expected the old process to answer, got %q", body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	process, err := Start(ctx, []net.Listener{l})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if process.Pid == os.Getpid() {
+		t.Error("Expected a new process")
+	}
+
+	// Once the old process stops accepting, the new one answers on the
+	// same socket
+	server.Close()
+	if body := get(t, url); body != "new" {
+		t.Errorf("Expected the new process to answer, got %q", body)
+	}
+	get(t, url+"/exit")
+}
+
+func TestStart_ChildFails(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	t.Setenv("UPGRADE_TEST_FAIL", "1")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := Start(ctx, []net.Listener{l}); err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("Expected an error for a process that never became ready, got %v", err)
+	}
+
+	// The old process keeps its socket
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Errorf("Expected the listener to stay open: %v", err)
+	} else {
+		f.Close()
+	}
+}
+
+func TestReady_NotUpgrading(t *testing.T) {
+	t.Setenv(readyEnv, "")
+	if err := Ready(); err != nil {
+		t.Errorf("Expected Ready to do nothing outside an upgrade, got %v", err)
+	}
+	t.Setenv(fdsEnv, "")
+	if listeners, err := Listeners(); listeners != nil || err != nil {
+		t.Errorf("Expected no listeners outside an upgrade, got %v %v", listeners, err)
+	}
+}
+
+func TestEnviron_Watchdog(t *testing.T) {
+	// The service manager addressed the watchdog to this process
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getppid()))
+	if _, ok := systemd.WatchdogInterval(); ok {
+		t.Fatal("Expected no watchdog for a process WATCHDOG_PID does not name")
+	}
+
+	// The new process gets the environment environ returns
+	env := environ()
+	os.Unsetenv("WATCHDOG_PID")
+	os.Unsetenv("WATCHDOG_USEC")
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		switch name {
+		case "WATCHDOG_PID", "WATCHDOG_USEC":
+			os.Setenv(name, value)
+		}
+	}
+	interval, ok := systemd.WatchdogInterval()
+	if !ok || interval != 15*time.Second {
+		t.Errorf("Expected the new process to send keepalives every 15s, got %v, %v", interval, ok)
+	}
+}
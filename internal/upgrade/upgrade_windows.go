@@ -0,0 +1,28 @@
+//go:build windows
+
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+)
+
+// Notify does nothing on Windows, which has no SIGUSR2
+func Notify(c chan<- os.Signal) {}
+
+// Listeners returns no sockets on Windows
+func Listeners() ([]net.Listener, error) {
+	return nil, nil
+}
+
+// Ready does nothing on Windows
+func Ready() error {
+	return nil
+}
+
+// Start is not supported on Windows
+func Start(ctx context.Context, listeners []net.Listener) (*os.Process, error) {
+	return nil, errors.New("binary upgrades are not supported on Windows")
+}